@@ -0,0 +1,258 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidLinkCode is returned when redeeming a link code that doesn't exist or is expired.
+	ErrInvalidLinkCode = errors.New("invalid or expired link code")
+	// ErrTwitchLinked is returned when the Twitch user is already linked to another Discord user.
+	ErrTwitchLinked = errors.New("twitch user is linked to another discord user")
+)
+
+const (
+	// linkCodeAlphabet leaves out characters that are easily confused, like 0 and O
+	linkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	// linkCodeLength is the number of characters of a link code
+	linkCodeLength = 8
+)
+
+// AccountLink is a Discord user linked to a Twitch user.
+type AccountLink struct {
+	DiscordID string
+	// TwitchID is the stable user ID of the Twitch user
+	TwitchID string
+	// TwitchLogin is the login the Twitch user was last seen with, see SeenTwitchUser. It is empty
+	// if the user was never seen in the chat.
+	TwitchLogin string
+	LinkedAt    time.Time
+}
+
+// SeenTwitchUser stores login as the current login of the Twitch user with the given ID. If the
+// user had another login before, their giveaway entries, cooldowns and native points are moved to
+// the new login, see renameTwitchLogin, and the old login is returned. The caller has to rename
+// the winners in the prizes files, see GiveawayPrize.RenameWinner. Ledgers keep the old login.
+func SeenTwitchUser(id, login string, now time.Time) (oldLogin string, err error) {
+	now = now.UTC().Truncate(time.Second)
+	tx, err := Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("SELECT login FROM twitch_users WHERE id=?", id).Scan(&oldLogin)
+	if err == sql.ErrNoRows {
+		if _, err = tx.Exec("INSERT INTO twitch_users (id,login,updated_at) VALUES (?,?,?)", id, login, now); err != nil {
+			return "", err
+		}
+		return "", tx.Commit()
+	} else if err != nil {
+		return "", err
+	}
+	if oldLogin == login {
+		return "", nil
+	}
+
+	if _, err = tx.Exec("UPDATE twitch_users SET login=?, updated_at=? WHERE id=?", login, now, id); err != nil {
+		return "", err
+	}
+	if err = renameTwitchLogin(tx, oldLogin, login); err != nil {
+		return "", err
+	}
+	return oldLogin, tx.Commit()
+}
+
+// twitchLoginTables are the tables with rows keyed by a Twitch login, see renameTwitchLogin.
+var twitchLoginTables = []struct {
+	// table stores the login in the column user. Its other key columns are keys.
+	table, user string
+	keys        []string
+	// filter limits the rows of table to the ones of Twitch users, if set.
+	filter string
+	// merge are the assignments merging the row of the old login (o) into the row of the new login
+	// (n). If it is empty, the row of the new login is kept as is.
+	merge []string
+}{
+	{"giveaway_entries", "user_id", []string{"giveaway_id", "platform"}, fmt.Sprintf("platform='%s'", GiveawayPlatformTwitch),
+		[]string{"weight=n.weight+o.weight", "last_entry=" + laterTime("last_entry")}},
+	{"twitch_cooldowns", "username", []string{"channel"}, "", []string{"last_used=" + laterTime("last_used")}},
+	{"twitch_points", "username", []string{"channel"}, "", []string{"points=n.points+o.points", "last_chat=" + laterTime("last_chat")}},
+	{"twitch_viewers", "username", []string{"channel"}, "", nil},
+}
+
+// laterTime returns an expression for the later time in column of the rows n and o, see
+// twitchLoginTables. NULL counts as earliest time.
+func laterTime(column string) string {
+	return fmt.Sprintf("CASE WHEN n.%[1]s IS NULL OR o.%[1]s>n.%[1]s THEN o.%[1]s ELSE n.%[1]s END", column)
+}
+
+// renameTwitchLogin moves all rows keyed by the Twitch login oldLogin to newLogin. The chat
+// commands may have added rows for newLogin before the rename was noticed. Those are merged with
+// the rows of oldLogin: weights and points are added up and the later times, like the cooldowns,
+// are kept.
+func renameTwitchLogin(q Querier, oldLogin, newLogin string) error {
+	for _, t := range twitchLoginTables {
+		on := fmt.Sprintf("n.%[1]s=? AND o.%[1]s=?", t.user)
+		for _, k := range t.keys {
+			on += fmt.Sprintf(" AND n.%[1]s=o.%[1]s", k)
+		}
+		rename := fmt.Sprintf("UPDATE %s SET %s=? WHERE %[2]s=?", t.table, t.user)
+		if t.filter != "" {
+			on += " AND o." + t.filter
+			rename += " AND " + t.filter
+		}
+
+		var merge, remove string
+		if db.Driver() == DriverSQLite {
+			merge = fmt.Sprintf("UPDATE %[1]s AS n SET %[2]s FROM %[1]s AS o WHERE %[3]s", t.table, strings.Join(t.merge, ","), on)
+			remove = fmt.Sprintf("DELETE FROM %[1]s AS o WHERE EXISTS (SELECT 1 FROM %[1]s AS n WHERE %[2]s)", t.table, on)
+		} else {
+			merge = fmt.Sprintf("UPDATE %[1]s n JOIN %[1]s o ON %[3]s SET n.%[2]s", t.table, strings.Join(t.merge, ",n."), on)
+			remove = fmt.Sprintf("DELETE o FROM %[1]s o JOIN %[1]s n ON %[2]s", t.table, on)
+		}
+		if len(t.merge) > 0 {
+			if _, err := q.Exec(merge, newLogin, oldLogin); err != nil {
+				return fmt.Errorf("merge %s: %v", t.table, err)
+			}
+		}
+		if _, err := q.Exec(remove, newLogin, oldLogin); err != nil {
+			return fmt.Errorf("remove merged %s: %v", t.table, err)
+		}
+		if _, err := q.Exec(rename, newLogin, oldLogin); err != nil {
+			return fmt.Errorf("rename %s: %v", t.table, err)
+		}
+	}
+	return nil
+}
+
+// CreateAccountLinkCode returns a new one-time code for the Discord user, which is valid for ttl.
+// Typing it in the Twitch chat links the Twitch user to the Discord user, see
+// RedeemAccountLinkCode. A previous code of the Discord user becomes invalid.
+func CreateAccountLinkCode(discordID string, now time.Time, ttl time.Duration) (string, error) {
+	now = now.UTC().Truncate(time.Second)
+	b := make([]byte, linkCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = linkCodeAlphabet[int(b[i])%len(linkCodeAlphabet)]
+	}
+	code := string(b)
+
+	tx, err := Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("DELETE FROM account_link_codes WHERE discord_id=? OR expires_at<=?", discordID, now); err != nil {
+		return "", err
+	}
+	if _, err = tx.Exec("INSERT INTO account_link_codes (code,discord_id,expires_at) VALUES (?,?,?)", code, discordID, now.Add(ttl)); err != nil {
+		return "", err
+	}
+	return code, tx.Commit()
+}
+
+// RedeemAccountLinkCode links the Twitch user with the given ID to the Discord user of code. A
+// previous link of the Discord user is replaced. It returns ErrInvalidLinkCode if code doesn't
+// exist or expired, and ErrTwitchLinked if the Twitch user is linked to another Discord user.
+func RedeemAccountLinkCode(code, twitchID string, now time.Time) (AccountLink, error) {
+	now = now.UTC().Truncate(time.Second)
+	code = strings.ToUpper(strings.TrimSpace(code))
+	tx, err := Begin()
+	if err != nil {
+		return AccountLink{}, err
+	}
+	defer tx.Rollback()
+
+	var discordID string
+	err = tx.QueryRow("SELECT discord_id FROM account_link_codes WHERE code=? AND expires_at>?", code, now).Scan(&discordID)
+	if err == sql.ErrNoRows {
+		return AccountLink{}, ErrInvalidLinkCode
+	} else if err != nil {
+		return AccountLink{}, err
+	}
+	if link, ok, err := getAccountLink(tx, "l.twitch_id=?", twitchID); err != nil {
+		return AccountLink{}, err
+	} else if ok && link.DiscordID != discordID {
+		return AccountLink{}, ErrTwitchLinked
+	}
+
+	if _, err = tx.Exec("DELETE FROM account_link_codes WHERE code=?", code); err != nil {
+		return AccountLink{}, err
+	}
+	if _, err = tx.Exec("DELETE FROM account_links WHERE discord_id=? OR twitch_id=?", discordID, twitchID); err != nil {
+		return AccountLink{}, err
+	}
+	if _, err = tx.Exec("INSERT INTO account_links (discord_id,twitch_id,linked_at) VALUES (?,?,?)", discordID, twitchID, now); err != nil {
+		return AccountLink{}, err
+	}
+	link, _, err := getAccountLink(tx, "l.discord_id=?", discordID)
+	if err != nil {
+		return AccountLink{}, err
+	}
+	return link, tx.Commit()
+}
+
+// GetAccountLinkByDiscord returns the link of the Discord user. ok is false if they aren't linked.
+func GetAccountLinkByDiscord(discordID string) (link AccountLink, ok bool, err error) {
+	return getAccountLink(db, "l.discord_id=?", discordID)
+}
+
+// GetAccountLinkByTwitch returns the link of the Twitch user with the given ID. ok is false if
+// they aren't linked.
+func GetAccountLinkByTwitch(twitchID string) (link AccountLink, ok bool, err error) {
+	return getAccountLink(db, "l.twitch_id=?", twitchID)
+}
+
+// GetAccountLinkByTwitchLogin returns the link of the Twitch user that was last seen with login.
+// ok is false if they aren't linked.
+func GetAccountLinkByTwitchLogin(login string) (link AccountLink, ok bool, err error) {
+	return getAccountLink(db, "u.login=?", strings.ToLower(login))
+}
+
+// getAccountLink returns the link matching where, which may use the account_links table as l and
+// the twitch_users table as u.
+func getAccountLink(q Querier, where string, args ...any) (link AccountLink, ok bool, err error) {
+	var login sql.NullString
+	err = q.QueryRow("SELECT l.discord_id,l.twitch_id,u.login,l.linked_at FROM account_links l LEFT JOIN twitch_users u ON u.id=l.twitch_id WHERE "+where+" ORDER BY u.updated_at DESC LIMIT 1",
+		args...).Scan(&link.DiscordID, &link.TwitchID, &login, &link.LinkedAt)
+	if err == sql.ErrNoRows {
+		return AccountLink{}, false, nil
+	} else if err != nil {
+		return AccountLink{}, false, err
+	}
+	link.TwitchLogin = login.String
+	return link, true, nil
+}
+
+// DeleteAccountLink removes the link of the Discord user. ok is false if they weren't linked.
+func DeleteAccountLink(discordID string) (ok bool, err error) {
+	res, err := Exec("DELETE FROM account_links WHERE discord_id=?", discordID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAccountLinks(t *testing.T) {
+	newTestDatabase(t)
+
+	now := time.Date(2024, 12, 24, 18, 0, 0, 0, time.UTC)
+	if _, err := SeenTwitchUser("100", "foo", now); err != nil {
+		t.Fatal(err)
+	}
+	code, err := CreateAccountLinkCode("1", now, 10*time.Minute)
+	if err != nil || len(code) != linkCodeLength {
+		t.Fatalf("CreateAccountLinkCode() = %q, %v", code, err)
+	}
+	if _, err = RedeemAccountLinkCode(code, "100", now.Add(11*time.Minute)); !errors.Is(err, ErrInvalidLinkCode) {
+		t.Errorf("RedeemAccountLinkCode() after expiry error = %v, want %v", err, ErrInvalidLinkCode)
+	}
+	link, err := RedeemAccountLinkCode(" "+code+" ", "100", now.Add(time.Minute))
+	if err != nil || link.DiscordID != "1" || link.TwitchID != "100" || link.TwitchLogin != "foo" {
+		t.Fatalf("RedeemAccountLinkCode() = %+v, %v, want 1 linked to foo", link, err)
+	}
+	if _, err = RedeemAccountLinkCode(code, "100", now.Add(time.Minute)); !errors.Is(err, ErrInvalidLinkCode) {
+		t.Errorf("RedeemAccountLinkCode() twice error = %v, want %v", err, ErrInvalidLinkCode)
+	}
+
+	// another Discord user can't take over the Twitch user
+	other, _ := CreateAccountLinkCode("2", now, 10*time.Minute)
+	if _, err = RedeemAccountLinkCode(other, "100", now); !errors.Is(err, ErrTwitchLinked) {
+		t.Errorf("RedeemAccountLinkCode() of linked Twitch user error = %v, want %v", err, ErrTwitchLinked)
+	}
+
+	// renaming moves the rows keyed by login
+	AddGiveawayWeight("tw11", GiveawayPlatformTwitch, "foo", 3)
+	if _, err = AddTwitchPoints("chan", "foo", 50, TwitchPointsAdjust); err != nil {
+		t.Fatal(err)
+	}
+	if oldLogin, err := SeenTwitchUser("100", "bar", now); err != nil || oldLogin != "foo" {
+		t.Fatalf("SeenTwitchUser() = %q, %v, want foo", oldLogin, err)
+	}
+	if link, ok, err := GetAccountLinkByTwitchLogin("BAR"); err != nil || !ok || link.DiscordID != "1" {
+		t.Errorf("GetAccountLinkByTwitchLogin() = %+v, %v, %v, want Discord user 1", link, ok, err)
+	}
+	if _, ok, _ := GetAccountLinkByTwitchLogin("foo"); ok {
+		t.Error("GetAccountLinkByTwitchLogin() of old login is linked")
+	}
+	if w := GetGiveawayEntry("tw11", GiveawayPlatformTwitch, "bar").Weight; w != 3 {
+		t.Errorf("got weight %d after rename, want 3", w)
+	}
+	if points, _ := GetTwitchPoints("chan", "bar"); points != 50 {
+		t.Errorf("got %d points after rename, want 50", points)
+	}
+
+	if ok, err := DeleteAccountLink("1"); err != nil || !ok {
+		t.Errorf("DeleteAccountLink() = %v, %v, want true, nil", ok, err)
+	}
+	if _, ok, _ := GetAccountLinkByDiscord("1"); ok {
+		t.Error("GetAccountLinkByDiscord() after delete is linked")
+	}
+}
+
+func TestRenameTwitchLoginMerge(t *testing.T) {
+	newTestDatabase(t)
+
+	now := time.Date(2024, 12, 24, 18, 0, 0, 0, time.UTC)
+	if _, err := SeenTwitchUser("100", "foo", now); err != nil {
+		t.Fatal(err)
+	}
+	AddGiveawayWeight("tw11", GiveawayPlatformTwitch, "foo", 3)
+	AddGiveawayWeight("tw11", GiveawayPlatformDiscord, "foo", 4)
+	if _, err := AddTwitchPoints("chan", "foo", 50, TwitchPointsAdjust); err != nil {
+		t.Fatal(err)
+	}
+	ClaimTwitchCooldown("chan", "foo", 0, now)
+	ClaimTwitchCooldown("other", "foo", 0, now)
+	JoinTwitchViewer("chan", "foo", now)
+
+	// a chat command of the new login was handled before the rename was noticed
+	AddGiveawayWeight("tw11", GiveawayPlatformTwitch, "bar", 2)
+	if _, err := AddTwitchPoints("chan", "bar", 20, TwitchPointsAdjust); err != nil {
+		t.Fatal(err)
+	}
+	ClaimTwitchCooldown("chan", "bar", 0, now.Add(5*time.Minute))
+	JoinTwitchViewer("chan", "bar", now.Add(5*time.Minute))
+
+	if oldLogin, err := SeenTwitchUser("100", "bar", now.Add(10*time.Minute)); err != nil || oldLogin != "foo" {
+		t.Fatalf("SeenTwitchUser() = %q, %v, want foo", oldLogin, err)
+	}
+
+	if w := GetGiveawayEntry("tw11", GiveawayPlatformTwitch, "bar").Weight; w != 5 {
+		t.Errorf("got weight %d after rename, want 5", w)
+	}
+	if w := GetGiveawayEntry("tw11", GiveawayPlatformDiscord, "foo").Weight; w != 4 {
+		t.Errorf("got Discord weight %d after rename, want 4", w)
+	}
+	if points, _ := GetTwitchPoints("chan", "bar"); points != 70 {
+		t.Errorf("got %d points after rename, want 70", points)
+	}
+	if points, _ := GetTwitchPoints("chan", "foo"); points != 0 {
+		t.Errorf("got %d points of old login after rename, want 0", points)
+	}
+	if lastUsed, err := GetTwitchCooldown("chan", "bar"); err != nil || !lastUsed.Equal(now.Add(5*time.Minute)) {
+		t.Errorf("GetTwitchCooldown() after rename = %v, %v, want the newer one", lastUsed, err)
+	}
+	if lastUsed, err := GetTwitchCooldown("other", "bar"); err != nil || !lastUsed.Equal(now) {
+		t.Errorf("GetTwitchCooldown() in other channel after rename = %v, %v, want %v", lastUsed, err, now)
+	}
+	var viewers int
+	if err := QueryRow("SELECT COUNT(*) FROM twitch_viewers WHERE channel=?", "chan").Scan(&viewers); err != nil || viewers != 1 {
+		t.Errorf("got %d viewers after rename, %v, want 1", viewers, err)
+	}
+}
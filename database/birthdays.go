@@ -0,0 +1,43 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"database/sql"
+	"strconv"
+)
+
+// Birthday is the birthday a Discord user set with the birthday command.
+type Birthday struct {
+	Day   int
+	Month int
+	// Year is 0 if the user didn't set it
+	Year int
+	// Visible is false if the user wants to keep their birthday private
+	Visible bool
+}
+
+// GetBirthday returns the birthday of the Discord user. ok is false if they didn't set one.
+func GetBirthday(discordID string) (b Birthday, ok bool, err error) {
+	id, err := strconv.ParseUint(discordID, 10, 64)
+	if err != nil {
+		return Birthday{}, false, err
+	}
+	err = QueryRow("SELECT day,month,year,visible FROM birthdays WHERE id=?", id).Scan(&b.Day, &b.Month, &b.Year, &b.Visible)
+	if err == sql.ErrNoRows {
+		return Birthday{}, false, nil
+	}
+	return b, err == nil, err
+}
@@ -18,57 +18,72 @@ import (
 	"database/sql"
 	"fmt"
 	logger "log"
-	"time"
 
-	// mysql driver used for database
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/spf13/viper"
 )
 
 var log = logger.New(logger.Writer(), "[Config] ", logger.LstdFlags|logger.Lmsgprefix)
-var db *sql.DB
-
-type connectionConfig struct {
-	User     string `mapstructure:"user"`
-	Password string `mapstructure:"password"`
-	Host     string `mapstructure:"host"`
-	Port     int    `mapstructure:"port"`
-	Database string `mapstructure:"database"`
-}
-
-func (c connectionConfig) String() string {
-	return fmt.Sprintf("user='%s', host:port='%s:%d', database='%s'", c.User, c.Host, c.Port, c.Database)
-}
+var db Store
 
-// Connect sets the connection to the configured database
+// Connect sets the connection to the configured database and brings its schema up to date (see
+// Migrate). The backend is selected by 'database.driver' in the config and defaults to mysql.
 func Connect() {
 	log.Println("Connecting to Database...")
 
-	// setting default values
-	config := connectionConfig{
-		Host: "localhost",
-		Port: 3306,
-	}
-	err := viper.UnmarshalKey("mysql", &config)
-	if err != nil {
-		log.Fatalf("Could not read msql connection data from config: %v", err)
+	var (
+		err    error
+		target string
+	)
+	switch driver := viper.GetString("database.driver"); driver {
+	case "", DriverMySQL:
+		// setting default values
+		config := MySQLConfig{
+			Host: "localhost",
+			Port: 3306,
+		}
+		err = viper.UnmarshalKey("mysql", &config)
+		if err != nil {
+			log.Fatalf("Could not read msql connection data from config: %v", err)
+		}
+
+		db, err = NewMySQL(config)
+		if err != nil {
+			log.Fatalf("Could not open database connection: %v", err)
+		}
+		target = fmt.Sprintf("%s@%s:%d/%s", config.User, config.Host, config.Port, config.Database)
+	case DriverSQLite:
+		path := viper.GetString("database.sqlite")
+		db, err = NewSQLite(path)
+		if err != nil {
+			log.Fatalf("Could not open database connection: %v", err)
+		}
+		target = fmt.Sprintf("sqlite:%s", path)
+	default:
+		log.Fatalf("Unknown database driver '%s'! Use one of '%s', '%s'", driver, DriverMySQL, DriverSQLite)
 	}
 
-	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", config.User, config.Password, config.Host, config.Port, config.Database)
-
-	db, err = sql.Open("mysql", dataSourceName)
+	err = db.Ping()
 	if err != nil {
-		log.Fatalf("Could not open database connection: %v", err)
+		log.Fatalf("Could not ping database: %v", err)
 	}
 
-	db.SetConnMaxLifetime(3 * time.Minute)
+	log.Printf("Connected to database %s\n", target)
 
-	err = db.Ping()
+	err = Migrate(db)
 	if err != nil {
-		log.Fatalf("Could not ping database: %v", err)
+		log.Fatalf("Could not migrate database: %v", err)
 	}
+}
 
-	log.Printf("Connected to database %s@%s:%d/%s\n", config.User, config.Host, config.Port, config.Database)
+// SetStore replaces the global store, e.g. to use an in-memory SQLite database in tests. Any
+// previously set store is not closed.
+func SetStore(s Store) {
+	db = s
+}
+
+// GetStore returns the global store.
+func GetStore() Store {
+	return db
 }
 
 // Close closes the database and prevents new queries from starting.
@@ -88,6 +103,11 @@ func Ping() (err error) {
 	return db.Ping()
 }
 
+// Begin starts a new transaction.
+func Begin() (*sql.Tx, error) {
+	return db.Begin()
+}
+
 // Exec executes a query without returning any rows.
 // The args are for any placeholder parameters in the query.
 func Exec(query string, args ...any) (result sql.Result, err error) {
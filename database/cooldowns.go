@@ -0,0 +1,101 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GetTwitchCooldown returns the time the given user last used the cooldown in channel. It is zero
+// if they never did.
+func GetTwitchCooldown(channel, user string) (lastUsed time.Time, err error) {
+	err = QueryRow("SELECT last_used FROM twitch_cooldowns WHERE channel=? AND username=?", channel, user).Scan(&lastUsed)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return lastUsed, err
+}
+
+// ClaimTwitchCooldown atomically checks and sets the cooldown of the given user in channel. If the
+// last use is at least cooldown before now (or there is none), it is set to now and ok is true.
+// Otherwise nothing is changed and ok is false.
+//
+// previous is the last use before the call, or zero if there was none. It can be used to calculate
+// the remaining cooldown or to undo the claim with RestoreTwitchCooldown.
+func ClaimTwitchCooldown(channel, user string, cooldown time.Duration, now time.Time) (previous time.Time, ok bool, err error) {
+	now = now.UTC().Truncate(time.Second)
+	res, err := Exec(insertIgnore()+" INTO twitch_cooldowns (channel,username,last_used) VALUES (?,?,?)", channel, user, now)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return time.Time{}, false, err
+	} else if n == 1 {
+		return time.Time{}, true, nil
+	}
+
+	previous, err = GetTwitchCooldown(channel, user)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	// only update if nobody else claimed it since, so two concurrent calls can't both succeed
+	res, err = Exec("UPDATE twitch_cooldowns SET last_used=? WHERE channel=? AND username=? AND last_used=? AND last_used<=?",
+		now, channel, user, previous, now.Add(-cooldown))
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return previous, false, err
+	}
+	return previous, true, nil
+}
+
+// RestoreTwitchCooldown undoes a successful ClaimTwitchCooldown at claimed by setting the last use
+// back to previous. If the cooldown was claimed again in the meantime, nothing is changed.
+func RestoreTwitchCooldown(channel, user string, claimed, previous time.Time) (err error) {
+	claimed = claimed.UTC().Truncate(time.Second)
+	if previous.IsZero() {
+		_, err = Exec("DELETE FROM twitch_cooldowns WHERE channel=? AND username=? AND last_used=?", channel, user, claimed)
+		return err
+	}
+	_, err = Exec("UPDATE twitch_cooldowns SET last_used=? WHERE channel=? AND username=? AND last_used=?",
+		previous.UTC().Truncate(time.Second), channel, user, claimed)
+	return err
+}
+
+// ImportTwitchCooldowns adds the given last uses mapped by user to channel. Users that already have
+// a cooldown in channel are skipped. It returns the number of imported cooldowns.
+func ImportTwitchCooldowns(channel string, times map[string]time.Time) (int, error) {
+	tx, err := Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var imported int
+	for user, lastUsed := range times {
+		res, err := tx.Exec(insertIgnore()+" INTO twitch_cooldowns (channel,username,last_used) VALUES (?,?,?)",
+			channel, user, lastUsed.UTC().Truncate(time.Second))
+		if err != nil {
+			return 0, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			imported++
+		}
+	}
+	return imported, tx.Commit()
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClaimTwitchCooldown(t *testing.T) {
+	newTestDatabase(t)
+
+	start := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	cooldown := 15 * time.Minute
+
+	tests := []struct {
+		name         string
+		now          time.Time
+		wantPrevious time.Time
+		wantOK       bool
+	}{
+		{"first use", start, time.Time{}, true},
+		{"during cooldown", start.Add(10 * time.Minute), start, false},
+		{"after cooldown", start.Add(15 * time.Minute), start, true},
+		{"during next cooldown", start.Add(20 * time.Minute), start.Add(15 * time.Minute), false},
+	}
+	for _, tt := range tests {
+		previous, ok, err := ClaimTwitchCooldown("channel", "user", cooldown, tt.now)
+		if err != nil || ok != tt.wantOK || !previous.Equal(tt.wantPrevious) {
+			t.Errorf("%s: ClaimTwitchCooldown() = %v, %v, %v, want %v, %v", tt.name, previous, ok, err, tt.wantPrevious, tt.wantOK)
+		}
+	}
+
+	// other channels are independent
+	if _, ok, err := ClaimTwitchCooldown("other", "user", cooldown, start.Add(20*time.Minute)); err != nil || !ok {
+		t.Errorf("ClaimTwitchCooldown() in other channel = %v, %v, want true", ok, err)
+	}
+
+	// undo the claim after the cooldown
+	if err := RestoreTwitchCooldown("channel", "user", start.Add(15*time.Minute), start); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := GetTwitchCooldown("channel", "user"); err != nil || !got.Equal(start) {
+		t.Errorf("GetTwitchCooldown() after restore = %v, %v, want %v", got, err, start)
+	}
+	if err := RestoreTwitchCooldown("other", "user", start.Add(20*time.Minute), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := GetTwitchCooldown("other", "user"); err != nil || !got.IsZero() {
+		t.Errorf("GetTwitchCooldown() after restore = %v, %v, want zero", got, err)
+	}
+}
+
+func TestClaimTwitchCooldownConcurrent(t *testing.T) {
+	newTestDatabase(t)
+
+	now := time.Now()
+	if _, _, err := ClaimTwitchCooldown("channel", "user", time.Minute, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		claimed int
+	)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok, err := ClaimTwitchCooldown("channel", "user", time.Minute, now)
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				mu.Lock()
+				claimed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if claimed != 1 {
+		t.Errorf("cooldown was claimed %d times, want 1", claimed)
+	}
+}
+
+func TestImportTwitchCooldowns(t *testing.T) {
+	newTestDatabase(t)
+
+	existing := time.Date(2024, 12, 1, 9, 0, 0, 0, time.UTC)
+	if _, _, err := ClaimTwitchCooldown("channel", "bar", time.Minute, existing); err != nil {
+		t.Fatal(err)
+	}
+
+	foo := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	n, err := ImportTwitchCooldowns("channel", map[string]time.Time{
+		"foo": foo,
+		"bar": foo,
+	})
+	if err != nil || n != 1 {
+		t.Fatalf("ImportTwitchCooldowns() = %d, %v, want 1, nil", n, err)
+	}
+	for user, want := range map[string]time.Time{"foo": foo, "bar": existing} {
+		if got, err := GetTwitchCooldown("channel", user); err != nil || !got.Equal(want) {
+			t.Errorf("GetTwitchCooldown(%s) = %v, %v, want %v", user, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DiscordGiveaway is a giveaway hosted in a Discord channel. Its entries are stored like the ones of
+// any other giveaway, see GetAllGiveawayEntries.
+type DiscordGiveaway struct {
+	// Name is the name of the giveaway holding the entries, see GetGiveaway.
+	Name      string
+	GuildID   string
+	ChannelID string
+	// MessageID is the message with the entry button.
+	MessageID string
+	HostID    string
+	Prize     string
+	// Winners is the number of winners to draw.
+	Winners int
+	// RoleID is the role users need to enter. It is empty if everyone can enter.
+	RoleID string
+	EndsAt time.Time
+	// Ended is true once the giveaway was closed and its winners are drawn.
+	Ended bool
+	// DrawID is the ID of the fair draw with the winners. It is 0 if the giveaway is not ended yet
+	// or had no entries.
+	DrawID int64
+	// Announced is true once the winners were announced in the channel of the giveaway.
+	Announced bool
+}
+
+// AddDiscordGiveaway stores g and creates its giveaway. It fails if a Discord giveaway with the
+// same name already exists.
+func AddDiscordGiveaway(g DiscordGiveaway) error {
+	ids, err := parseSnowflakes(g.GuildID, g.ChannelID, g.MessageID, g.HostID, g.RoleID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	giveawayID, err := getOrAddGiveaway(tx, g.Name)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO discord_giveaways (giveaway_id,guild_id,channel_id,message_id,host_id,prize,winners,role_id,ends_at) VALUES (?,?,?,?,?,?,?,?,?)",
+		giveawayID, ids[0], ids[1], ids[2], ids[3], g.Prize, g.Winners, ids[4], g.EndsAt.UTC().Truncate(time.Second))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const discordGiveawaySelect = `SELECT g.name,d.guild_id,d.channel_id,d.message_id,d.host_id,d.prize,d.winners,d.role_id,d.ends_at,d.ended,d.draw_id,d.announced
+FROM discord_giveaways d JOIN giveaways g ON g.id=d.giveaway_id `
+
+// GetDiscordGiveaway returns the Discord giveaway with the given name. ok is false if it doesn't
+// exist.
+func GetDiscordGiveaway(name string) (g DiscordGiveaway, ok bool, err error) {
+	g, err = scanDiscordGiveaway(QueryRow(discordGiveawaySelect+"WHERE g.name=?", name))
+	if err == sql.ErrNoRows {
+		return DiscordGiveaway{}, false, nil
+	} else if err != nil {
+		return DiscordGiveaway{}, false, err
+	}
+	return g, true, nil
+}
+
+// GetDueDiscordGiveaways returns all Discord giveaways whose end time is not after now and which
+// are not ended yet or whose winners are not announced yet.
+func GetDueDiscordGiveaways(now time.Time) ([]DiscordGiveaway, error) {
+	rows, err := Query(discordGiveawaySelect+"WHERE d.announced=? AND d.ends_at<=? ORDER BY d.ends_at", false, now.UTC().Truncate(time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var giveaways []DiscordGiveaway
+	for rows.Next() {
+		g, err := scanDiscordGiveaway(rows)
+		if err != nil {
+			return nil, err
+		}
+		giveaways = append(giveaways, g)
+	}
+	return giveaways, rows.Err()
+}
+
+// GetEndedDiscordGiveaways returns all ended Discord giveaways whose end time is not before since.
+func GetEndedDiscordGiveaways(since time.Time) ([]DiscordGiveaway, error) {
+	rows, err := Query(discordGiveawaySelect+"WHERE d.ended=? AND d.ends_at>=? ORDER BY d.ends_at", true, since.UTC().Truncate(time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var giveaways []DiscordGiveaway
+	for rows.Next() {
+		g, err := scanDiscordGiveaway(rows)
+		if err != nil {
+			return nil, err
+		}
+		giveaways = append(giveaways, g)
+	}
+	return giveaways, rows.Err()
+}
+
+// EndDiscordGiveaway marks the giveaway with the given name as ended and draws n winners, see
+// DrawGiveawayFair. Both happen in one transaction, so a failed draw leaves the giveaway open. Only
+// the first call for a giveaway returns ok, so the caller that ended it is the only one drawing the
+// winners.
+func EndDiscordGiveaway(name string, n int) (d FairDraw, ok bool, err error) {
+	tx, err := Begin()
+	if err != nil {
+		return FairDraw{}, false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE discord_giveaways SET ended=? WHERE ended=? AND giveaway_id=(SELECT id FROM giveaways WHERE name=?)", true, false, name)
+	if err != nil {
+		return FairDraw{}, false, err
+	}
+	if rows, err := res.RowsAffected(); err != nil || rows == 0 {
+		return FairDraw{}, false, err
+	}
+
+	d, err = drawGiveawayFair(tx, name, n)
+	if err != nil {
+		return FairDraw{}, false, fmt.Errorf("draw winners: %v", err)
+	}
+	if d.ID != 0 {
+		_, err = tx.Exec("UPDATE discord_giveaways SET draw_id=? WHERE giveaway_id=(SELECT id FROM giveaways WHERE name=?)", d.ID, name)
+		if err != nil {
+			return FairDraw{}, false, err
+		}
+	}
+	return d, true, tx.Commit()
+}
+
+// SetDiscordGiveawayAnnounced marks the winners of the giveaway with the given name as announced.
+func SetDiscordGiveawayAnnounced(name string) error {
+	_, err := Exec("UPDATE discord_giveaways SET announced=? WHERE giveaway_id=(SELECT id FROM giveaways WHERE name=?)", true, name)
+	return err
+}
+
+// scanDiscordGiveaway scans a row selected by discordGiveawaySelect.
+func scanDiscordGiveaway(row interface{ Scan(...any) error }) (g DiscordGiveaway, err error) {
+	var (
+		ids    [5]uint64
+		drawID sql.NullInt64
+	)
+	err = row.Scan(&g.Name, &ids[0], &ids[1], &ids[2], &ids[3], &g.Prize, &g.Winners, &ids[4], &g.EndsAt, &g.Ended, &drawID, &g.Announced)
+	if err != nil {
+		return DiscordGiveaway{}, err
+	}
+	for i, id := range []*string{&g.GuildID, &g.ChannelID, &g.MessageID, &g.HostID, &g.RoleID} {
+		if ids[i] != 0 {
+			*id = strconv.FormatUint(ids[i], 10)
+		}
+	}
+	g.EndsAt = g.EndsAt.UTC()
+	g.DrawID = drawID.Int64
+	return g, nil
+}
+
+// parseSnowflakes parses Discord IDs for storing them in the database. An empty ID results in 0.
+func parseSnowflakes(ids ...string) ([]uint64, error) {
+	values := make([]uint64, len(ids))
+	for i, id := range ids {
+		if id == "" {
+			continue
+		}
+		var err error
+		values[i], err = strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id '%s': %v", id, err)
+		}
+	}
+	return values, nil
+}
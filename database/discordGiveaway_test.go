@@ -0,0 +1,126 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiscordGiveaway(t *testing.T) {
+	newTestDatabase(t)
+
+	if _, ok, err := GetDiscordGiveaway("dc-1"); err != nil || ok {
+		t.Fatalf("GetDiscordGiveaway() = _, %v, %v, want false, nil", ok, err)
+	}
+
+	end := time.Date(2024, 12, 24, 18, 0, 0, 0, time.UTC)
+	want := DiscordGiveaway{
+		Name:      "dc-1",
+		GuildID:   "1000000000000000001",
+		ChannelID: "1000000000000000002",
+		MessageID: "1000000000000000004",
+		HostID:    "1000000000000000003",
+		Prize:     "Cake",
+		Winners:   2,
+		EndsAt:    end,
+	}
+	if err := AddDiscordGiveaway(want); err != nil {
+		t.Fatalf("AddDiscordGiveaway() error = %v", err)
+	}
+	if err := AddDiscordGiveaway(want); err == nil {
+		t.Error("AddDiscordGiveaway() with existing name succeeded")
+	}
+	if g, ok, err := GetDiscordGiveaway("dc-1"); err != nil || !ok || g != want {
+		t.Errorf("GetDiscordGiveaway() = %+v, %v, %v, want %+v", g, ok, err, want)
+	}
+
+	if due, err := GetDueDiscordGiveaways(end.Add(-time.Second)); err != nil || len(due) != 0 {
+		t.Errorf("GetDueDiscordGiveaways() before end = %v, %v, want none", due, err)
+	}
+	if due, err := GetDueDiscordGiveaways(end); err != nil || len(due) != 1 || due[0].Name != "dc-1" {
+		t.Errorf("GetDueDiscordGiveaways() at end = %v, %v, want dc-1", due, err)
+	}
+
+	if ended, err := GetEndedDiscordGiveaways(end); err != nil || len(ended) != 0 {
+		t.Errorf("GetEndedDiscordGiveaways() before end = %v, %v, want none", ended, err)
+	}
+	if ok, err := EnterGiveaway("dc-1", GiveawayPlatformDiscord, "1"); err != nil || !ok {
+		t.Fatalf("EnterGiveaway() = %v, %v, want true, nil", ok, err)
+	}
+	if ok, err := EnterGiveaway("dc-1", GiveawayPlatformDiscord, "1"); err != nil || ok {
+		t.Errorf("second EnterGiveaway() = %v, %v, want false, nil", ok, err)
+	}
+	if entry := GetGiveawayEntry("dc-1", GiveawayPlatformDiscord, "1"); entry.Weight != 1 {
+		t.Errorf("weight after entering twice = %d, want 1", entry.Weight)
+	}
+
+	d, ok, err := EndDiscordGiveaway("dc-1", 2)
+	if err != nil || !ok {
+		t.Fatalf("EndDiscordGiveaway() = _, %v, %v, want true, nil", ok, err)
+	}
+	if len(d.Winners) != 1 || d.Winners[0].UserID != "1" {
+		t.Errorf("EndDiscordGiveaway() winners = %v, want 1", d.Winners)
+	}
+	if _, ok, err := EndDiscordGiveaway("dc-1", 2); err != nil || ok {
+		t.Errorf("second EndDiscordGiveaway() = _, %v, %v, want false, nil", ok, err)
+	}
+	if due, err := GetDueDiscordGiveaways(end); err != nil || len(due) != 1 || !due[0].Ended || due[0].DrawID != d.ID {
+		t.Errorf("GetDueDiscordGiveaways() before announcement = %v, %v, want ended dc-1", due, err)
+	}
+	if err := SetDiscordGiveawayAnnounced("dc-1"); err != nil {
+		t.Fatal(err)
+	}
+	if due, err := GetDueDiscordGiveaways(end); err != nil || len(due) != 0 {
+		t.Errorf("GetDueDiscordGiveaways() after announcement = %v, %v, want none", due, err)
+	}
+	if ended, err := GetEndedDiscordGiveaways(end); err != nil || len(ended) != 1 || ended[0].Name != "dc-1" {
+		t.Errorf("GetEndedDiscordGiveaways() at end = %v, %v, want dc-1", ended, err)
+	}
+	if ended, err := GetEndedDiscordGiveaways(end.Add(time.Second)); err != nil || len(ended) != 0 {
+		t.Errorf("GetEndedDiscordGiveaways() after end = %v, %v, want none", ended, err)
+	}
+	if g, _, err := GetDiscordGiveaway("dc-1"); err != nil || !g.Ended || !g.Announced || g.DrawID != d.ID {
+		t.Errorf("GetDiscordGiveaway() after end = %+v, %v", g, err)
+	}
+}
+
+func TestCreateGiveawayPrize(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "prizes.json")
+	p, err := CreateGiveawayPrize(filename, GiveawayPrizeSingle{Name: "first"}, GiveawayPrizeSingle{Name: "second"})
+	if err != nil {
+		t.Fatalf("CreateGiveawayPrize() error = %v", err)
+	}
+	prize, ok := p.GetNextPrize()
+	if !ok || prize.Name != "first" {
+		t.Fatalf("GetNextPrize() = %v, %v, want first", prize, ok)
+	}
+	prize.Winner = "1"
+	if err = p.SaveFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err = NewGiveawayPrize(filename)
+	if err != nil {
+		t.Fatalf("NewGiveawayPrize() error = %v", err)
+	}
+	if !p.HasPrizeWon("1") {
+		t.Error("winner of first prize was not saved")
+	}
+	if prize, ok = p.GetNextPrize(); !ok || prize.Name != "second" {
+		t.Errorf("GetNextPrize() = %v, %v, want second", prize, ok)
+	}
+}
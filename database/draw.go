@@ -0,0 +1,87 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"sort"
+)
+
+// DrawGiveawayWinner takes one of the given entries and draw one winner of them. The probability
+// is based on their Weight value. A higher Weight means a higher probability.
+//
+// It uses the global random source, see DrawGiveawayWinners for reproducible draws.
+func DrawGiveawayWinner(e []GiveawayEntry) (winner GiveawayEntry, totalTickets int) {
+	winners, totalTickets := DrawGiveawayWinners(nil, e, 1)
+	if len(winners) == 0 {
+		return GiveawayEntry{}, 0
+	}
+	return winners[0], totalTickets
+}
+
+// DrawGiveawayWinners draws n distinct winners of the given entries. Each ticket has the same
+// chance, so the probability of an entry is its Weight divided by the weight of all entries that
+// didn't win yet. Entries without weight never win. If there are less than n entries, all of them
+// are returned in the drawn order.
+//
+// The entries are sorted by platform and user before drawing, so the result only depends on r and
+// not on the order of e. This way a draw with a seeded r can be reproduced. If r is nil, the
+// global random source is used.
+//
+// totalTickets is the sum of all weights of e.
+func DrawGiveawayWinners(r *rand.Rand, e []GiveawayEntry, n int) (winners []GiveawayEntry, totalTickets int) {
+	entries := make([]GiveawayEntry, 0, len(e))
+	for _, entry := range e {
+		if entry.Weight > 0 {
+			entries = append(entries, entry)
+			totalTickets += entry.Weight
+		}
+	}
+	sortGiveawayEntries(entries)
+
+	// cumulative[i] is the sum of the weights of entries[:i+1]
+	cumulative := make([]int, len(entries))
+	for len(winners) < n && len(entries) > 0 {
+		var sum int
+		for i, entry := range entries {
+			sum += entry.Weight
+			cumulative[i] = sum
+		}
+		ticket := intN(r, sum)
+		i := sort.Search(len(entries), func(i int) bool { return cumulative[i] > ticket })
+
+		winners = append(winners, entries[i])
+		entries = slices.Delete(entries, i, i+1)
+		cumulative = cumulative[:len(entries)]
+	}
+	return winners, totalTickets
+}
+
+// sortGiveawayEntries sorts e by platform and user.
+func sortGiveawayEntries(e []GiveawayEntry) {
+	slices.SortFunc(e, func(a, b GiveawayEntry) int {
+		return cmp.Or(cmp.Compare(a.Platform, b.Platform), cmp.Compare(a.UserID, b.UserID))
+	})
+}
+
+// intN returns a random number in [0,n) from r or the global source if r is nil.
+func intN(r *rand.Rand, n int) int {
+	if r == nil {
+		return rand.IntN(n)
+	}
+	return r.IntN(n)
+}
@@ -0,0 +1,146 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+func TestDrawGiveawayWinners(t *testing.T) {
+	entries := []GiveawayEntry{
+		{UserID: "a", Weight: 1},
+		{UserID: "b", Weight: 0},
+		{UserID: "c", Weight: 2},
+	}
+	tests := []struct {
+		name      string
+		n         int
+		wantCount int
+	}{
+		{"none", 0, 0},
+		{"one", 1, 1},
+		{"all", 2, 2},
+		{"more than entries", 5, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			winners, total := DrawGiveawayWinners(rand.New(rand.NewPCG(1, 2)), entries, tt.n)
+			if total != 3 {
+				t.Errorf("got %d total tickets, want 3", total)
+			}
+			if len(winners) != tt.wantCount {
+				t.Fatalf("got %d winners, want %d", len(winners), tt.wantCount)
+			}
+			seen := make(map[string]bool)
+			for _, w := range winners {
+				if w.Weight == 0 {
+					t.Errorf("entry %s without tickets won", w.UserID)
+				}
+				if seen[w.UserID] {
+					t.Errorf("entry %s won twice", w.UserID)
+				}
+				seen[w.UserID] = true
+			}
+		})
+	}
+
+	if winners, total := DrawGiveawayWinners(nil, nil, 1); len(winners) != 0 || total != 0 {
+		t.Errorf("DrawGiveawayWinners() without entries = %v, %d", winners, total)
+	}
+}
+
+func TestDrawGiveawayWinnersReproducible(t *testing.T) {
+	entries := []GiveawayEntry{
+		{UserID: "a", Weight: 3},
+		{UserID: "b", Weight: 1},
+		{UserID: "c", Weight: 5},
+		{UserID: "d", Weight: 2},
+	}
+	reversed := []GiveawayEntry{entries[3], entries[2], entries[1], entries[0]}
+
+	for seed := range uint64(20) {
+		a, _ := DrawGiveawayWinners(rand.New(rand.NewPCG(seed, seed)), entries, 3)
+		b, _ := DrawGiveawayWinners(rand.New(rand.NewPCG(seed, seed)), reversed, 3)
+		if !reflect.DeepEqual(a, b) {
+			t.Errorf("seed %d: draw depends on the order of entries: %v != %v", seed, a, b)
+		}
+	}
+}
+
+// TestDrawGiveawayWinnersFair checks the distribution of the first winner with a chi-squared test.
+func TestDrawGiveawayWinnersFair(t *testing.T) {
+	entries := []GiveawayEntry{
+		{UserID: "a", Weight: 1},
+		{UserID: "b", Weight: 2},
+		{UserID: "c", Weight: 3},
+		{UserID: "d", Weight: 4},
+	}
+	const draws = 100000
+	r := rand.New(rand.NewPCG(42, 1337))
+
+	counts := make(map[string]int)
+	for range draws {
+		winners, _ := DrawGiveawayWinners(r, entries, 1)
+		counts[winners[0].UserID]++
+	}
+
+	var chi2 float64
+	for _, e := range entries {
+		expected := float64(draws*e.Weight) / 10
+		diff := float64(counts[e.UserID]) - expected
+		chi2 += diff * diff / expected
+	}
+	// critical value for 3 degrees of freedom at p = 0.001
+	if chi2 > 16.266 {
+		t.Errorf("draws are not distributed by weight: chi² = %.2f, counts = %v", chi2, counts)
+	}
+	// the last ticket must be able to win as well
+	if counts["d"] == 0 {
+		t.Error("last entry never won")
+	}
+}
+
+// TestDrawGiveawayWinnersFairMultiple checks the probability of each entry to be among two
+// winners against the exact value.
+func TestDrawGiveawayWinnersFairMultiple(t *testing.T) {
+	entries := []GiveawayEntry{
+		{UserID: "a", Weight: 1},
+		{UserID: "b", Weight: 1},
+		{UserID: "c", Weight: 2},
+	}
+	// P(a) = P(a first) + P(b first)*P(a|b) + P(c first)*P(a|c) = 1/4 + 1/4*1/3 + 1/2*1/2
+	// P(c) = P(c first) + P(a first)*P(c|a) + P(b first)*P(c|b) = 1/2 + 2 * 1/4*2/3
+	want := map[string]float64{"a": 7.0 / 12, "b": 7.0 / 12, "c": 5.0 / 6}
+	const draws = 100000
+	r := rand.New(rand.NewPCG(7, 7))
+
+	counts := make(map[string]int)
+	for range draws {
+		winners, _ := DrawGiveawayWinners(r, entries, 2)
+		for _, w := range winners {
+			counts[w.UserID]++
+		}
+	}
+	for user, p := range want {
+		got := float64(counts[user]) / draws
+		// allow 5 standard deviations
+		if tolerance := 5 * math.Sqrt(p*(1-p)/draws); math.Abs(got-p) > tolerance {
+			t.Errorf("entry %s won with probability %.4f, want %.4f ± %.4f", user, got, p, tolerance)
+		}
+	}
+}
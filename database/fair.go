@@ -0,0 +1,324 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mathrand "math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrCommitmentMismatch is returned by VerifyFairDraw when the revealed seed doesn't match the
+// published commitment.
+var ErrCommitmentMismatch = errors.New("seed doesn't match the commitment")
+
+// FairDraw is a provably fair draw of a giveaway using a commit-reveal scheme:
+//
+//  1. The bot picks a secret random seed and publishes its SHA-256 hash as commitment before the
+//     draw.
+//  2. The draw uses a ChaCha8 random source keyed with SHA-256(seed || entries), where entries is
+//     the list of all entries sorted by platform and user, one 'platform user weight' per line.
+//     The winners are drawn with DrawGiveawayWinners.
+//  3. The seed is revealed together with the winners and the entries, see FairDraw.Transcript.
+//
+// As the seed is fixed before the final entries are known, neither the bot nor anyone else can pick
+// the result. The hash of the entries can be published when the entries close
+// (GetGiveawayEntriesHash), so they can't be changed afterwards either. Use VerifyFairDraw to
+// recompute the result.
+type FairDraw struct {
+	ID       int64
+	Giveaway string
+	// Seed is the revealed secret seed in hex
+	Seed string
+	// Commitment is the SHA-256 hash of the seed in hex, which was published before the draw
+	Commitment string
+	// Entries are all entries that took part in the draw, sorted by platform and user
+	Entries      []GiveawayEntry
+	Winners      []GiveawayEntry
+	TotalTickets int
+	// NextCommitment is the commitment for the next draw of the same giveaway. It is only set by
+	// DrawGiveawayFair.
+	NextCommitment string
+	CreatedAt      time.Time
+}
+
+// GetGiveawayCommitment returns the commitment for the next draw of the giveaway with the given
+// name. It should be published before the draw, e.g. when the giveaway opens. The giveaway and its
+// seed are created if needed.
+func GetGiveawayCommitment(name string) (string, error) {
+	tx, err := Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	giveawayID, err := getOrAddGiveaway(tx, name)
+	if err != nil {
+		return "", err
+	}
+	_, commitment, err := getGiveawaySeed(tx, giveawayID)
+	if err != nil {
+		return "", err
+	}
+	return commitment, tx.Commit()
+}
+
+// DrawGiveawayFair draws n distinct winners of the giveaway with the given name using its committed
+// seed (see FairDraw). The draw is stored with the revealed seed and the giveaway gets a new seed
+// for the next draw.
+//
+// If the giveaway has no entries, the returned draw has no winners and nothing is changed.
+func DrawGiveawayFair(name string, n int) (FairDraw, error) {
+	tx, err := Begin()
+	if err != nil {
+		return FairDraw{}, err
+	}
+	defer tx.Rollback()
+
+	d, err := drawGiveawayFair(tx, name, n)
+	if err != nil {
+		return FairDraw{}, err
+	}
+	return d, tx.Commit()
+}
+
+// drawGiveawayFair is like DrawGiveawayFair, but runs on q, which should be a transaction.
+func drawGiveawayFair(q Querier, name string, n int) (FairDraw, error) {
+	giveawayID, err := getOrAddGiveaway(q, name)
+	if err != nil {
+		return FairDraw{}, err
+	}
+	seed, commitment, err := getGiveawaySeed(q, giveawayID)
+	if err != nil {
+		return FairDraw{}, err
+	}
+	entries, err := getAllGiveawayEntries(q, name)
+	if err != nil {
+		return FairDraw{}, fmt.Errorf("get entries: %v", err)
+	}
+
+	d, err := fairDraw(seed, entries, n)
+	if err != nil {
+		return FairDraw{}, err
+	}
+	d.Giveaway = name
+	d.Commitment = commitment
+	if d.TotalTickets == 0 {
+		return d, nil
+	}
+
+	d.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	res, err := q.Exec("INSERT INTO giveaway_draws (giveaway_id,seed,commitment,entries,winners,total_tickets,created_at) VALUES (?,?,?,?,?,?,?)",
+		giveawayID, d.Seed, d.Commitment, string(encodeFairEntries(d.Entries)), string(encodeFairEntries(d.Winners)), d.TotalTickets, d.CreatedAt)
+	if err != nil {
+		return FairDraw{}, fmt.Errorf("save draw: %v", err)
+	}
+	if d.ID, err = res.LastInsertId(); err != nil {
+		return FairDraw{}, err
+	}
+
+	// the seed is revealed now, so the next draw needs a new one
+	if _, err = q.Exec("UPDATE giveaways SET seed='',commitment='' WHERE id=?", giveawayID); err != nil {
+		return FairDraw{}, err
+	}
+	if _, d.NextCommitment, err = getGiveawaySeed(q, giveawayID); err != nil {
+		return FairDraw{}, err
+	}
+	return d, nil
+}
+
+// GetLastFairDraw returns the latest draw of the giveaway with the given name. ok is false if
+// there was no draw yet.
+func GetLastFairDraw(name string) (d FairDraw, ok bool, err error) {
+	return getFairDraw("g.name=? ORDER BY d.id DESC LIMIT 1", name)
+}
+
+// GetFairDraw returns the draw with the given ID. ok is false if it doesn't exist.
+func GetFairDraw(id int64) (d FairDraw, ok bool, err error) {
+	return getFairDraw("d.id=?", id)
+}
+
+// getFairDraw returns the first draw matching where, which may use the giveaway_draws table as d
+// and the giveaways table as g.
+func getFairDraw(where string, args ...any) (d FairDraw, ok bool, err error) {
+	var entries, winners string
+	err = QueryRow(`SELECT d.id,g.name,d.seed,d.commitment,d.entries,d.winners,d.total_tickets,d.created_at FROM giveaway_draws d
+JOIN giveaways g ON g.id=d.giveaway_id
+WHERE `+where, args...).Scan(&d.ID, &d.Giveaway, &d.Seed, &d.Commitment, &entries, &winners, &d.TotalTickets, &d.CreatedAt)
+	if err == sql.ErrNoRows {
+		return FairDraw{}, false, nil
+	} else if err != nil {
+		return FairDraw{}, false, err
+	}
+	if d.Entries, err = decodeFairEntries(entries); err != nil {
+		return FairDraw{}, false, fmt.Errorf("decode entries of draw %d: %v", d.ID, err)
+	}
+	if d.Winners, err = decodeFairEntries(winners); err != nil {
+		return FairDraw{}, false, fmt.Errorf("decode winners of draw %d: %v", d.ID, err)
+	}
+	return d, true, nil
+}
+
+// VerifyFairDraw recomputes d from its published data. It returns an error if the seed doesn't
+// match the commitment (ErrCommitmentMismatch) or the recomputed winners differ from d.Winners.
+func VerifyFairDraw(d FairDraw) error {
+	seed, err := hex.DecodeString(d.Seed)
+	if err != nil {
+		return fmt.Errorf("invalid seed: %v", err)
+	}
+	if hash := sha256.Sum256(seed); hex.EncodeToString(hash[:]) != strings.ToLower(d.Commitment) {
+		return ErrCommitmentMismatch
+	}
+
+	want, err := fairDraw(d.Seed, d.Entries, len(d.Winners))
+	if err != nil {
+		return err
+	}
+	if want.TotalTickets != d.TotalTickets {
+		return fmt.Errorf("got %d total tickets, but the entries have %d", d.TotalTickets, want.TotalTickets)
+	}
+	if len(want.Winners) != len(d.Winners) {
+		return fmt.Errorf("got %d winners, but only %d can be drawn", len(d.Winners), len(want.Winners))
+	}
+	for i, w := range want.Winners {
+		if w.Platform != d.Winners[i].Platform || w.UserID != d.Winners[i].UserID {
+			return fmt.Errorf("winner %d is '%s', but the draw results in '%s'", i+1, d.Winners[i].UserID, w.UserID)
+		}
+	}
+	return nil
+}
+
+// EntriesHash returns the SHA-256 hash in hex of the sorted entries of d, as they are used for the
+// draw. It can be published before the seed is revealed, see GetGiveawayEntriesHash.
+func (d FairDraw) EntriesHash() string {
+	hash := sha256.Sum256(encodeFairEntries(d.Entries))
+	return hex.EncodeToString(hash[:])
+}
+
+// Transcript returns everything needed to recompute d as plain text. The entries and winners
+// follow the header lines, one 'platform user weight' per line in the exact form that is hashed
+// for the draw.
+func (d FairDraw) Transcript() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# giveaway: %s\n", d.Giveaway)
+	fmt.Fprintf(&b, "# draw: %d at %s\n", d.ID, d.CreatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "# commitment: %s\n", d.Commitment)
+	fmt.Fprintf(&b, "# seed: %s\n", d.Seed)
+	fmt.Fprintf(&b, "# entries sha256: %s\n", d.EntriesHash())
+	fmt.Fprintf(&b, "# total tickets: %d\n", d.TotalTickets)
+	b.WriteString("# key = SHA-256(seed bytes || entries), winners drawn by cumulative weight from ChaCha8(key)\n")
+	b.WriteString("\n# winners\n")
+	b.Write(encodeFairEntries(d.Winners))
+	b.WriteString("\n# entries\n")
+	b.Write(encodeFairEntries(d.Entries))
+	return b.Bytes()
+}
+
+// GetGiveawayEntriesHash returns the SHA-256 hash in hex of the current entries of the giveaway
+// with the given name, in the form they would be used for a draw (see FairDraw.EntriesHash).
+// Publishing it when the entries close lets anyone check that the entries weren't changed before
+// the draw.
+func GetGiveawayEntriesHash(name string) (hash string, entries, tickets int, err error) {
+	all, err := getAllGiveawayEntries(db, name)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	d := FairDraw{Entries: fairEntries(all)}
+	for _, e := range d.Entries {
+		tickets += e.Weight
+	}
+	return d.EntriesHash(), len(d.Entries), tickets, nil
+}
+
+// fairDraw draws n winners from entries with the random source derived from the hex encoded seed
+// and entries. Entries without tickets are dropped from the result.
+func fairDraw(seed string, entries []GiveawayEntry, n int) (FairDraw, error) {
+	seedBytes, err := hex.DecodeString(seed)
+	if err != nil {
+		return FairDraw{}, fmt.Errorf("invalid seed: %v", err)
+	}
+
+	d := FairDraw{Seed: seed, Entries: fairEntries(entries)}
+
+	key := sha256.Sum256(append(seedBytes, encodeFairEntries(d.Entries)...))
+	d.Winners, d.TotalTickets = DrawGiveawayWinners(mathrand.New(mathrand.NewChaCha8(key)), d.Entries, n)
+	return d, nil
+}
+
+// fairEntries returns the entries with tickets, sorted by platform and user.
+func fairEntries(entries []GiveawayEntry) []GiveawayEntry {
+	var fair []GiveawayEntry
+	for _, e := range entries {
+		if e.Weight > 0 {
+			fair = append(fair, GiveawayEntry{UserID: e.UserID, Platform: e.Platform, Weight: e.Weight})
+		}
+	}
+	sortGiveawayEntries(fair)
+	return fair
+}
+
+// getGiveawaySeed returns the current seed and commitment of the giveaway. A new seed is created
+// if it has none.
+func getGiveawaySeed(q Querier, giveawayID int64) (seed, commitment string, err error) {
+	err = q.QueryRow("SELECT seed,commitment FROM giveaways WHERE id=?", giveawayID).Scan(&seed, &commitment)
+	if err != nil || seed != "" {
+		return seed, commitment, err
+	}
+
+	seedBytes := make([]byte, 32)
+	if _, err = rand.Read(seedBytes); err != nil {
+		return "", "", err
+	}
+	hash := sha256.Sum256(seedBytes)
+	seed, commitment = hex.EncodeToString(seedBytes), hex.EncodeToString(hash[:])
+	_, err = q.Exec("UPDATE giveaways SET seed=?,commitment=? WHERE id=?", seed, commitment, giveawayID)
+	return seed, commitment, err
+}
+
+// encodeFairEntries encodes e as one 'platform user weight' line per entry.
+func encodeFairEntries(e []GiveawayEntry) []byte {
+	var b bytes.Buffer
+	for _, entry := range e {
+		fmt.Fprintf(&b, "%s %s %d\n", entry.Platform, entry.UserID, entry.Weight)
+	}
+	return b.Bytes()
+}
+
+// decodeFairEntries is the inverse of encodeFairEntries.
+func decodeFairEntries(data string) (entries []GiveawayEntry, err error) {
+	for i, line := range strings.Split(strings.TrimSuffix(data, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: want 'platform user weight', got '%s'", i+1, line)
+		}
+		weight, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid weight: %v", i+1, err)
+		}
+		entries = append(entries, GiveawayEntry{UserID: fields[1], Platform: GiveawayPlatform(fields[0]), Weight: weight})
+	}
+	return entries, nil
+}
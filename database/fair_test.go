@@ -0,0 +1,147 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDrawGiveawayFair(t *testing.T) {
+	newTestDatabase(t)
+
+	commitment, err := GetGiveawayCommitment("xmas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := GetGiveawayCommitment("xmas"); err != nil || again != commitment {
+		t.Errorf("GetGiveawayCommitment() = %s, %v, want unchanged %s", again, err, commitment)
+	}
+
+	if d, err := DrawGiveawayFair("xmas", 1); err != nil || len(d.Winners) != 0 || d.TotalTickets != 0 {
+		t.Fatalf("DrawGiveawayFair() without entries = %+v, %v", d, err)
+	}
+
+	AddGiveawayWeight("xmas", GiveawayPlatformDiscord, "1", 3)
+	AddGiveawayWeight("xmas", GiveawayPlatformDiscord, "2", 1)
+	AddGiveawayWeight("xmas", GiveawayPlatformTwitch, "foo", 2)
+	AddGiveawayWeight("other", GiveawayPlatformTwitch, "bar", 5)
+
+	entriesHash, entries, tickets, err := GetGiveawayEntriesHash("xmas")
+	if err != nil || entries != 3 || tickets != 6 {
+		t.Fatalf("GetGiveawayEntriesHash() = %s, %d, %d, %v, want 3 entries and 6 tickets", entriesHash, entries, tickets, err)
+	}
+
+	d, err := DrawGiveawayFair("xmas", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.EntriesHash() != entriesHash {
+		t.Errorf("draw has entries hash %s, want the published %s", d.EntriesHash(), entriesHash)
+	}
+	if d.Commitment != commitment {
+		t.Errorf("draw used commitment %s, want the published %s", d.Commitment, commitment)
+	}
+	seed, _ := hex.DecodeString(d.Seed)
+	if hash := sha256.Sum256(seed); hex.EncodeToString(hash[:]) != commitment {
+		t.Errorf("revealed seed %s doesn't match commitment %s", d.Seed, commitment)
+	}
+	if len(d.Entries) != 3 || d.TotalTickets != 6 || len(d.Winners) != 2 {
+		t.Errorf("got %d entries, %d tickets and %d winners, want 3, 6 and 2", len(d.Entries), d.TotalTickets, len(d.Winners))
+	}
+	if d.NextCommitment == "" || d.NextCommitment == commitment {
+		t.Errorf("got next commitment %q, want a new one", d.NextCommitment)
+	}
+	if next, err := GetGiveawayCommitment("xmas"); err != nil || next != d.NextCommitment {
+		t.Errorf("GetGiveawayCommitment() after draw = %s, %v, want %s", next, err, d.NextCommitment)
+	}
+	if err = VerifyFairDraw(d); err != nil {
+		t.Errorf("VerifyFairDraw() error = %v", err)
+	}
+
+	last, ok, err := GetLastFairDraw("xmas")
+	if err != nil || !ok {
+		t.Fatalf("GetLastFairDraw() = _, %v, %v", ok, err)
+	}
+	if last.ID != d.ID || last.Seed != d.Seed || !reflect.DeepEqual(last.Entries, d.Entries) || !reflect.DeepEqual(last.Winners, d.Winners) {
+		t.Errorf("GetLastFairDraw() = %+v, want %+v", last, d)
+	}
+	if err = VerifyFairDraw(last); err != nil {
+		t.Errorf("VerifyFairDraw() of stored draw error = %v", err)
+	}
+	if byID, ok, err := GetFairDraw(d.ID); err != nil || !ok || byID.Giveaway != "xmas" || byID.Seed != d.Seed {
+		t.Errorf("GetFairDraw(%d) = %+v, %v, %v", d.ID, byID, ok, err)
+	}
+	if transcript := string(d.Transcript()); !strings.Contains(transcript, d.Seed) || !strings.Contains(transcript, string(encodeFairEntries(d.Entries))) {
+		t.Errorf("Transcript() misses the seed or entries:\n%s", transcript)
+	}
+}
+
+func TestVerifyFairDraw(t *testing.T) {
+	seed := "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	hash := sha256.Sum256(mustDecodeHex(t, seed))
+	entries := []GiveawayEntry{
+		{UserID: "a", Platform: GiveawayPlatformTwitch, Weight: 1},
+		{UserID: "b", Platform: GiveawayPlatformTwitch, Weight: 5},
+		{UserID: "c", Platform: GiveawayPlatformTwitch, Weight: 2},
+	}
+	valid, err := fairDraw(seed, entries, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid.Commitment = hex.EncodeToString(hash[:])
+
+	otherWinner := valid
+	otherWinner.Winners = []GiveawayEntry{entries[0]}
+	if valid.Winners[0].UserID == "a" {
+		otherWinner.Winners = []GiveawayEntry{entries[1]}
+	}
+	otherSeed := valid
+	otherSeed.Seed = "ff" + seed[2:]
+	moreTickets := valid
+	moreTickets.TotalTickets++
+
+	tests := []struct {
+		name    string
+		d       FairDraw
+		wantErr bool
+	}{
+		{"valid", valid, false},
+		{"other winner", otherWinner, true},
+		{"other seed", otherSeed, true},
+		{"more tickets", moreTickets, true},
+	}
+	for _, tt := range tests {
+		if err := VerifyFairDraw(tt.d); (err != nil) != tt.wantErr {
+			t.Errorf("%s: VerifyFairDraw() error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+	if err := VerifyFairDraw(otherSeed); !errors.Is(err, ErrCommitmentMismatch) {
+		t.Errorf("VerifyFairDraw() error = %v, want %v", err, ErrCommitmentMismatch)
+	}
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
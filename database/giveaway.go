@@ -21,200 +21,291 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"reflect"
-	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
+// GiveawayPlatform is the platform a user of a giveaway entry belongs to. It defines the meaning
+// of the user identifier.
+type GiveawayPlatform string
+
+const (
+	// GiveawayPlatformDiscord is used for entries of Discord users. Their identifier is the user ID.
+	GiveawayPlatformDiscord GiveawayPlatform = "discord"
+	// GiveawayPlatformTwitch is used for entries of Twitch users. Their identifier is the login name.
+	GiveawayPlatformTwitch GiveawayPlatform = "twitch"
+)
+
+// Giveaway represents a giveaway from the database. Each giveaway has its own entries, so users can
+// take part in multiple giveaways at the same time.
+type Giveaway struct {
+	ID int64
+	// Unique name of the giveaway, like 'xmas'
+	Name      string
+	CreatedAt time.Time
+}
+
 // GiveawayEntry represents a giveaway entry from the database
 type GiveawayEntry struct {
 	// Identification of the entry
 	UserID string
+	// The platform UserID belongs to
+	Platform GiveawayPlatform
 	// The current weight or number of tickets in this entry
 	Weight int
 	// The day of last entry. Useful to check when only one ticket per day is allowed.
 	LastEntry time.Time
 }
 
-// ToEmbedField formats the giveaway entry to an discord message embed field.
+// ToEmbedField formats the giveaway entry to an discord message embed field. If the user linked
+// their Discord and Twitch accounts, the account of the other platform is shown as well.
 func (e GiveawayEntry) ToEmbedField(s *discordgo.Session, totalTickets int) (f *discordgo.MessageEmbedField) {
-	var name string
-	if u, err := s.User(e.UserID); err != nil {
-		log.Printf("Error on getting user '%s': %v", e.UserID, err)
-		name = "???"
-	} else {
-		name = u.Username
+	var name, mention string
+	switch e.Platform {
+	case GiveawayPlatformTwitch:
+		name = e.UserID
+		if link, ok, err := GetAccountLinkByTwitchLogin(e.UserID); err != nil {
+			log.Printf("Error on getting account link of '%s': %v", e.UserID, err)
+		} else if ok {
+			mention = fmt.Sprintf("<@%s>\n", link.DiscordID)
+		}
+	default:
+		if u, err := s.User(e.UserID); err != nil {
+			log.Printf("Error on getting user '%s': %v", e.UserID, err)
+			name = "???"
+		} else {
+			name = u.Username
+		}
+		mention = fmt.Sprintf("<@%s>\n", e.UserID)
+		if link, ok, err := GetAccountLinkByDiscord(e.UserID); err != nil {
+			log.Printf("Error on getting account link of '%s': %v", e.UserID, err)
+		} else if ok && link.TwitchLogin != "" {
+			mention = fmt.Sprintf("<@%s> (twitch: %s)\n", e.UserID, link.TwitchLogin)
+		}
 	}
 
 	return &discordgo.MessageEmbedField{
 		Name:   name,
-		Value:  fmt.Sprintf("<@%s>\n%d tickets\nChance: %.2f%%\nlast entry: <t:%d:R>", e.UserID, e.Weight, float64(e.Weight*100)/float64(totalTickets), e.LastEntry.Unix()),
+		Value:  fmt.Sprintf("%s%d tickets\nChance: %.2f%%\nlast entry: <t:%d:R>", mention, e.Weight, float64(e.Weight*100)/float64(totalTickets), e.LastEntry.Unix()),
 		Inline: true,
 	}
 }
 
-// GetGiveawayEntry gets the giveaway entry for the given user identifier, if their last entry was
-// prefixed with prefix.
-//
-// If an error occours or it doesn't match prefix, an emtpy GiveawayEntry is returned instead.
-func GetGiveawayEntry(prefix, userID string) GiveawayEntry {
-	var (
-		weight      int
-		lastEntryID string
-	)
-	err := QueryRow("SELECT weight,last_entry_id FROM giveaway WHERE id=?", userID).Scan(&weight, &lastEntryID)
+// GetGiveaway gets the giveaway with the given name. ok is false if it doesn't exist.
+func GetGiveaway(name string) (g Giveaway, ok bool, err error) {
+	err = QueryRow("SELECT id,name,created_at FROM giveaways WHERE name=?", name).Scan(&g.ID, &g.Name, &g.CreatedAt)
 	if err == sql.ErrNoRows {
-		return GiveawayEntry{UserID: userID, Weight: 0}
-	}
-	if err != nil {
-		log.Printf("Database failed to get giveaway entries for '%s': %v", userID, err)
-		return GiveawayEntry{}
-	}
-
-	if lastEntryID == "" {
-		return GiveawayEntry{UserID: userID, Weight: weight}
+		return Giveaway{}, false, nil
+	} else if err != nil {
+		return Giveaway{}, false, err
 	}
+	return g, true, nil
+}
 
-	dateValue, ok := strings.CutPrefix(lastEntryID, prefix+"-")
-	if !ok {
-		return GiveawayEntry{}
+// getOrAddGiveaway returns the ID of the giveaway with the given name. The giveaway is created if
+// it doesn't exist yet.
+func getOrAddGiveaway(q Querier, name string) (id int64, err error) {
+	_, err = q.Exec(insertIgnore()+" INTO giveaways (name,created_at) VALUES (?,?)", name, time.Now().UTC().Truncate(time.Second))
+	if err != nil {
+		return 0, err
 	}
+	err = q.QueryRow("SELECT id FROM giveaways WHERE name=?", name).Scan(&id)
+	return id, err
+}
 
-	lastEntry, err := time.Parse(time.DateOnly, dateValue)
+// GetGiveawayEntry gets the entry of the given user in the giveaway with the given name. If the
+// user has no entry yet, an entry with zero weight is returned.
+//
+// If an error occours, an emtpy GiveawayEntry is returned instead.
+func GetGiveawayEntry(name string, platform GiveawayPlatform, userID string) GiveawayEntry {
+	entry, err := getGiveawayEntry(db, name, platform, userID)
 	if err != nil {
-		log.Printf("could not convert last_entry_id '%s' to time: %v", lastEntryID, err)
+		log.Printf("Database failed to get giveaway entry for '%s' in '%s': %v", userID, name, err)
 		return GiveawayEntry{}
 	}
-	return GiveawayEntry{userID, weight, lastEntry}
+	return entry
 }
 
-// DeleteGiveawayEntry deletes the giveaway entry for the given user identifier from the database.
-//
-// If an error occours it will be returned. However if no datbase entry matched it returns err ==
-// nil, not err == sql.ErrNoRows. Because sql.ErrNoRows also results in the non-existence of the
-// requested row and therefore is treated as a successful call.
-func DeleteGiveawayEntry(userID string) error {
-	_, err := Exec("DELETE FROM giveaway WHERE id=?", userID)
+func getGiveawayEntry(q Querier, name string, platform GiveawayPlatform, userID string) (GiveawayEntry, error) {
+	var lastEntry sql.NullTime
+	entry := GiveawayEntry{UserID: userID, Platform: platform}
+	err := q.QueryRow(`SELECT e.weight,e.last_entry FROM giveaway_entries e
+JOIN giveaways g ON g.id=e.giveaway_id
+WHERE g.name=? AND e.platform=? AND e.user_id=?`, name, string(platform), userID).Scan(&entry.Weight, &lastEntry)
 	if err == sql.ErrNoRows {
-		return nil
+		return entry, nil
+	} else if err != nil {
+		return GiveawayEntry{}, err
 	}
-	return err
+	entry.LastEntry = lastEntry.Time
+	return entry, nil
 }
 
-// AddGiveawayWeight adds amount to the given user identifier.
+// DeleteGiveawayEntry deletes the entry of the given user from the giveaway with the given name.
+// The removed weight is logged as an entry event.
 //
-// However if their last entry wasn't prefixed with prefix, their weight will be resetted and starts
-// at amount. If you dont want it to be resetted check with GetGiveawayEntry first.
-//
-// If there was no error the modified entry is returned. If there was an error, an emtpy
-// GiveawayEntry is returned instead.
-func AddGiveawayWeight(prefix, userID string, amount int) GiveawayEntry {
+// If an error occours it will be returned. However if no entry matched it returns err == nil.
+func DeleteGiveawayEntry(name string, platform GiveawayPlatform, userID string) error {
+	tx, err := Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	var (
-		weight      int
-		lastEntryID string
-		new         bool
+		giveawayID int64
+		weight     int
 	)
-	err := QueryRow("SELECT weight,last_entry_id FROM giveaway WHERE id=?", userID).Scan(&weight, &lastEntryID)
+	err = tx.QueryRow(`SELECT e.giveaway_id,e.weight FROM giveaway_entries e
+JOIN giveaways g ON g.id=e.giveaway_id
+WHERE g.name=? AND e.platform=? AND e.user_id=?`, name, string(platform), userID).Scan(&giveawayID, &weight)
 	if err == sql.ErrNoRows {
-		new = true
+		return nil
 	} else if err != nil {
-		log.Printf("Database failed to get giveaway weight for '%s': %v", userID, err)
-		return GiveawayEntry{}
+		return err
 	}
 
-	// validate prefix
-	if _, ok := strings.CutPrefix(lastEntryID, prefix+"-"); !ok {
-		weight = 0
+	_, err = tx.Exec("DELETE FROM giveaway_entries WHERE giveaway_id=? AND platform=? AND user_id=?", giveawayID, string(platform), userID)
+	if err != nil {
+		return err
 	}
+	if err = addGiveawayEntryEvent(tx, giveawayID, platform, userID, -weight, "removed"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
-	weight += amount
-	dateValue := time.Now().Format(time.DateOnly)
-	lastEntryID = fmt.Sprintf("%s-%s", prefix, dateValue)
-	lastEntry, _ := time.Parse(time.DateOnly, dateValue)
+// AddGiveawayWeight adds amount to the entry of the given user in the giveaway with the given
+// name. The giveaway and the entry are created if needed.
+//
+// If there was no error the modified entry is returned. If there was an error, an emtpy
+// GiveawayEntry is returned instead.
+func AddGiveawayWeight(name string, platform GiveawayPlatform, userID string, amount int) GiveawayEntry {
+	return AddGiveawayWeightOn(name, platform, userID, amount, time.Now())
+}
 
-	if new {
-		_, err = Exec("INSERT INTO giveaway (id,weight,last_entry_id) VALUES (?,?,?)", userID, weight, lastEntryID)
-		if err != nil {
-			log.Printf("Database failed to insert giveaway for '%s': %v", userID, err)
-			return GiveawayEntry{}
-		}
-		return GiveawayEntry{userID, weight, lastEntry}
+// AddGiveawayWeightOn is like AddGiveawayWeight, but stores the date of day as the last entry
+// instead of today in the local timezone. Use it when the day depends on another timezone, like
+// the one of a guild.
+func AddGiveawayWeightOn(name string, platform GiveawayPlatform, userID string, amount int, day time.Time) GiveawayEntry {
+	tx, err := Begin()
+	if err != nil {
+		log.Printf("Database failed to begin transaction: %v", err)
+		return GiveawayEntry{}
+	}
+	defer tx.Rollback()
+
+	entry, err := addGiveawayWeight(tx, name, platform, userID, amount, day)
+	if err == nil {
+		err = tx.Commit()
 	}
-	_, err = Exec("UPDATE giveaway SET weight=?,last_entry_id=? WHERE id=?", weight, lastEntryID, userID)
 	if err != nil {
-		log.Printf("Database failed to update weight (new: %d) for '%s': %v", weight, userID, err)
+		log.Printf("Database failed to add giveaway weight for '%s' in '%s': %v", userID, name, err)
 		return GiveawayEntry{}
 	}
-	return GiveawayEntry{userID, weight, lastEntry}
+	return entry
 }
 
-// GetAllGiveawayEntries gets all giveaway entries that matches prefix.
-func GetAllGiveawayEntries(prefix string) []GiveawayEntry {
-	rows, err := Query("SELECT id,weight,last_entry_id FROM giveaway")
+// addGiveawayWeight is like AddGiveawayWeightOn, but runs on q and returns an error instead of
+// logging it.
+func addGiveawayWeight(q Querier, name string, platform GiveawayPlatform, userID string, amount int, day time.Time) (GiveawayEntry, error) {
+	giveawayID, err := getOrAddGiveaway(q, name)
 	if err != nil {
-		log.Printf("ERROR: could not get entries from database: %v", err)
-		return []GiveawayEntry{}
+		return GiveawayEntry{}, fmt.Errorf("get giveaway: %v", err)
 	}
-	defer rows.Close()
 
-	var entries []GiveawayEntry
-	for rows.Next() {
-		var (
-			userID      string
-			weight      int
-			lastEntryID string
-		)
-		err = rows.Scan(&userID, &weight, &lastEntryID)
-		if err != nil {
-			log.Printf("Warning: could not scan variables from row")
-			continue
-		}
+	// entries only store the day of the last entry
+	lastEntry := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
 
-		if lastEntryID == "" {
-			entries = append(entries, GiveawayEntry{UserID: userID, Weight: weight})
-			continue
-		}
+	_, err = q.Exec(insertIgnore()+" INTO giveaway_entries (giveaway_id,user_id,platform,weight,last_entry) VALUES (?,?,?,?,?)",
+		giveawayID, userID, string(platform), 0, lastEntry)
+	if err != nil {
+		return GiveawayEntry{}, fmt.Errorf("insert: %v", err)
+	}
+	_, err = q.Exec("UPDATE giveaway_entries SET weight=weight+?,last_entry=? WHERE giveaway_id=? AND platform=? AND user_id=?",
+		amount, lastEntry, giveawayID, string(platform), userID)
+	if err != nil {
+		return GiveawayEntry{}, fmt.Errorf("update weight: %v", err)
+	}
+	if err = addGiveawayEntryEvent(q, giveawayID, platform, userID, amount, "entry"); err != nil {
+		return GiveawayEntry{}, fmt.Errorf("add event: %v", err)
+	}
+	return getGiveawayEntry(q, name, platform, userID)
+}
 
-		dateValue, ok := strings.CutPrefix(lastEntryID, prefix+"-")
-		if !ok {
-			continue
-		}
+// EnterGiveaway adds userID with a weight of 1 to the giveaway with the given name. ok is false if
+// the user already entered it.
+func EnterGiveaway(name string, platform GiveawayPlatform, userID string) (ok bool, err error) {
+	tx, err := Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
 
-		lastEntry, err := time.Parse(time.DateOnly, dateValue)
-		if err != nil {
-			log.Printf("ERROR: could not convert last_entry_id '%s' to time: %v", lastEntryID, err)
-			continue
-		}
-		entries = append(entries, GiveawayEntry{userID, weight, lastEntry})
+	giveawayID, err := getOrAddGiveaway(tx, name)
+	if err != nil {
+		return false, fmt.Errorf("get giveaway: %v", err)
+	}
+	dateValue := time.Now().Format(time.DateOnly)
+	lastEntry, _ := time.Parse(time.DateOnly, dateValue)
+	res, err := tx.Exec(insertIgnore()+" INTO giveaway_entries (giveaway_id,user_id,platform,weight,last_entry) VALUES (?,?,?,?,?)",
+		giveawayID, userID, string(platform), 1, lastEntry)
+	if err != nil {
+		return false, fmt.Errorf("insert: %v", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if err = addGiveawayEntryEvent(tx, giveawayID, platform, userID, 1, "entry"); err != nil {
+		return false, fmt.Errorf("add event: %v", err)
+	}
+	return true, tx.Commit()
+}
+
+// addGiveawayEntryEvent logs a change of delta to an entry.
+func addGiveawayEntryEvent(q Querier, giveawayID int64, platform GiveawayPlatform, userID string, delta int, reason string) error {
+	_, err := q.Exec("INSERT INTO giveaway_entry_events (giveaway_id,user_id,platform,delta,reason,created_at) VALUES (?,?,?,?,?,?)",
+		giveawayID, userID, string(platform), delta, reason, time.Now().UTC().Truncate(time.Second))
+	return err
+}
+
+// GetAllGiveawayEntries gets all entries of the giveaway with the given name.
+func GetAllGiveawayEntries(name string) []GiveawayEntry {
+	entries, err := getAllGiveawayEntries(db, name)
+	if err != nil {
+		log.Printf("ERROR: could not get entries from database: %v", err)
+		return []GiveawayEntry{}
 	}
 	return entries
 }
 
-// DrawGiveawayWinner takes one of the given entries and draw one winner of them. The probability
-// is based on their Weight value. A higher Weight means a higher probability.
-func DrawGiveawayWinner(e []GiveawayEntry) (winner GiveawayEntry, totalTickets int) {
-	// skip randomizing when there is only one entry
-	if len(e) == 1 {
-		return e[0], e[0].Weight
+func getAllGiveawayEntries(q Querier, name string) ([]GiveawayEntry, error) {
+	rows, err := q.Query(`SELECT e.user_id,e.platform,e.weight,e.last_entry FROM giveaway_entries e
+JOIN giveaways g ON g.id=e.giveaway_id
+WHERE g.name=? AND e.weight>0`, name)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
 	var entries []GiveawayEntry
-	for _, e := range e {
-		for i := 0; i < e.Weight; i++ {
-			entries = append(entries, e)
+	for rows.Next() {
+		var (
+			entry     GiveawayEntry
+			lastEntry sql.NullTime
+		)
+		err = rows.Scan(&entry.UserID, &entry.Platform, &entry.Weight, &lastEntry)
+		if err != nil {
+			log.Printf("Warning: could not scan variables from row: %v", err)
+			continue
 		}
+		entry.LastEntry = lastEntry.Time
+		entries = append(entries, entry)
 	}
-	totalTickets = len(entries)
-	if totalTickets == 0 {
-		return GiveawayEntry{}, 0
-	}
-
-	rand.Shuffle(len(entries), func(i, j int) {
-		entries[i], entries[j] = entries[j], entries[i]
-	})
-	return entries[rand.Intn(totalTickets-1)], totalTickets
+	return entries, rows.Err()
 }
 
 // GiveawayPrizeType represents the type of a giveaway prize
@@ -263,6 +354,30 @@ func NewGiveawayPrize(filename string) (p GiveawayPrize, err error) {
 
 }
 
+// CreateGiveawayPrize creates a new GiveawayPrize with the given prizes and saves it to filename.
+// A single prize is stored as is, multiple prizes are stored as an ordered group. An existing file
+// is overwritten.
+func CreateGiveawayPrize(filename string, prizes ...GiveawayPrizeSingle) (p GiveawayPrize, err error) {
+	if filename == "" {
+		return p, fmt.Errorf("argument filename cannot be empty")
+	}
+	if len(prizes) == 0 {
+		return p, fmt.Errorf("at least one prize is needed")
+	}
+	p.filename = filename
+
+	if len(prizes) == 1 {
+		p.giveawayPrizeInterface = &prizes[0]
+	} else {
+		group := &GiveawayPrizeGroup{Sort: GiveawayPrizeGroupOrdered}
+		for i := range prizes {
+			group.Pool = append(group.Pool, GiveawayPrize{giveawayPrizeInterface: &prizes[i]})
+		}
+		p.giveawayPrizeInterface = group
+	}
+	return p, p.SaveFile()
+}
+
 // ReadFile reads the giveaway file from the configured filename and stores it in p
 func (p *GiveawayPrize) ReadFile() error {
 	if p == nil || p.filename == "" {
@@ -275,16 +390,47 @@ func (p *GiveawayPrize) ReadFile() error {
 	return json.Unmarshal(data, &p)
 }
 
-// SaveFile saves p in the configured json file
+// SaveFile saves p in the configured json file. p is validated before, see Validate. The previous
+// version of the file is kept as '<filename>.bak'.
 func (p GiveawayPrize) SaveFile() error {
 	if p.filename == "" {
 		return fmt.Errorf("cannot save invalid GiveawayPrize! Make sure to use NewGiveawayPrize()")
 	}
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("invalid prizes: %v", err)
+	}
 	data, err := json.MarshalIndent(p, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(p.filename, data, 0644)
+	if err = backupFile(p.filename); err != nil {
+		return fmt.Errorf("backup prizes: %v", err)
+	}
+	return writeFileAtomic(p.filename, data, 0644)
+}
+
+// writeFileAtomic writes data to a temporary file next to filename and renames it afterwards. This
+// way the file is never left half written, e.g. when the bot is stopped during the write.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp, filename)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
 }
 
 // HasPrizeAvailable returns whether p has at least one prize without a winner
@@ -391,6 +537,21 @@ type GiveawayPrizeSingle struct {
 	// The identifier of the winner. An empty string means this prize has no winner yet and is
 	// available.
 	Winner string `json:"winner,omitempty"`
+
+	// The claim status of the winner. It is empty if the winner doesn't need to claim the prize, e.g.
+	// because the winner was set by hand.
+	ClaimStatus GiveawayPrizeClaimStatus `json:"claim_status,omitempty"`
+	// The time the winner was drawn
+	WonAt *time.Time `json:"won_at,omitempty"`
+	// The time until the winner has to claim the prize, while the claim status is pending
+	ClaimDeadline *time.Time `json:"claim_deadline,omitempty"`
+	// The time the winner claimed the prize
+	ClaimedAt *time.Time `json:"claimed_at,omitempty"`
+	// Previous winners who didn't claim the prize in time. They are excluded from redraws.
+	Released []string `json:"released,omitempty"`
+	// The Twitch channel the winner was drawn in. Channels may share a prizes file, so the claim is
+	// only handled in this channel. It is empty for Discord giveaways.
+	Channel string `json:"channel,omitempty"`
 }
 
 func (p GiveawayPrizeSingle) prizeType() GiveawayPrizeType {
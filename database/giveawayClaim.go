@@ -0,0 +1,161 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"slices"
+	"time"
+)
+
+// GiveawayPrizeClaimStatus is the state of the claim of a won prize
+type GiveawayPrizeClaimStatus string
+
+const (
+	// GiveawayPrizeClaimPending means the winner has to claim the prize before its deadline.
+	// Otherwise the prize is released and redrawn.
+	GiveawayPrizeClaimPending GiveawayPrizeClaimStatus = "pending"
+	// GiveawayPrizeClaimClaimed means the winner claimed the prize in time
+	GiveawayPrizeClaimClaimed GiveawayPrizeClaimStatus = "claimed"
+)
+
+// SetWinner sets winner as the winner of p at now. If window is greater than 0, the winner has to
+// claim the prize within it, see Claim. Otherwise no claim is needed. The channel of p is reset.
+func (p *GiveawayPrizeSingle) SetWinner(winner string, now time.Time, window time.Duration) {
+	now = now.UTC().Truncate(time.Second)
+	p.Winner = winner
+	p.Channel = ""
+	p.WonAt = &now
+	p.ClaimStatus = ""
+	p.ClaimDeadline = nil
+	p.ClaimedAt = nil
+	if window > 0 {
+		deadline := now.Add(window)
+		p.ClaimStatus = GiveawayPrizeClaimPending
+		p.ClaimDeadline = &deadline
+	}
+}
+
+// Claim marks p as claimed by its winner at now. It returns false if there is no pending claim or
+// its deadline has passed.
+func (p *GiveawayPrizeSingle) Claim(now time.Time) bool {
+	if p.ClaimStatus != GiveawayPrizeClaimPending || p.ClaimDeadline == nil || now.After(*p.ClaimDeadline) {
+		return false
+	}
+	now = now.UTC().Truncate(time.Second)
+	p.ClaimStatus = GiveawayPrizeClaimClaimed
+	p.ClaimedAt = &now
+	return true
+}
+
+// ClaimExpired returns whether the claim of p is pending and its deadline has passed at now.
+func (p GiveawayPrizeSingle) ClaimExpired(now time.Time) bool {
+	return p.ClaimStatus == GiveawayPrizeClaimPending && p.ClaimDeadline != nil && now.After(*p.ClaimDeadline)
+}
+
+// Release removes the winner of p, so it is available again. The winner is remembered in Released
+// to exclude them from redraws.
+func (p *GiveawayPrizeSingle) Release() {
+	if p.Winner != "" && !slices.Contains(p.Released, p.Winner) {
+		p.Released = append(p.Released, p.Winner)
+	}
+	p.Winner = ""
+	p.Channel = ""
+	p.WonAt = nil
+	p.ClaimStatus = ""
+	p.ClaimDeadline = nil
+	p.ClaimedAt = nil
+}
+
+// RenameWinner replaces oldName with newName in the winners and released winners of all prizes of
+// p, like when a Twitch user changed their login. It returns whether p changed.
+func (p *GiveawayPrize) RenameWinner(oldName, newName string) (changed bool) {
+	for _, s := range p.Singles() {
+		if s.Winner == oldName {
+			s.Winner = newName
+			changed = true
+		}
+		if i := slices.Index(s.Released, oldName); i >= 0 {
+			s.Released[i] = newName
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Singles returns pointers to all single prizes of p in the order of the tree. Changes to them are
+// saved with p.SaveFile().
+func (p *GiveawayPrize) Singles() []*GiveawayPrizeSingle {
+	switch t := p.giveawayPrizeInterface.(type) {
+	case *GiveawayPrizeSingle:
+		return []*GiveawayPrizeSingle{t}
+	case *GiveawayPrizeGroup:
+		var singles []*GiveawayPrizeSingle
+		for i := range t.Pool {
+			singles = append(singles, t.Pool[i].Singles()...)
+		}
+		return singles
+	}
+	return nil
+}
+
+// PendingClaim returns the prize of p that winner still has to claim in channel. ok is false if
+// there is none. channel is empty for Discord giveaways, see GiveawayPrizeSingle.Channel.
+func (p *GiveawayPrize) PendingClaim(channel, winner string) (prize *GiveawayPrizeSingle, ok bool) {
+	for _, s := range p.Singles() {
+		if s.Channel == channel && s.Winner == winner && s.ClaimStatus == GiveawayPrizeClaimPending {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// ExpiredClaims returns all prizes of p won in channel whose winner didn't claim them before their
+// deadline.
+func (p *GiveawayPrize) ExpiredClaims(channel string, now time.Time) (expired []*GiveawayPrizeSingle) {
+	for _, s := range p.Singles() {
+		if s.Channel == channel && s.ClaimExpired(now) {
+			expired = append(expired, s)
+		}
+	}
+	return expired
+}
+
+// RedrawExclusions returns the users that must not win prize, a prize of p, in a redraw. These are
+// all current winners of p and the previous winners of prize who didn't claim it in time.
+func (p *GiveawayPrize) RedrawExclusions(prize *GiveawayPrizeSingle) []string {
+	exclude := slices.Clone(prize.Released)
+	for _, s := range p.Singles() {
+		if s.Winner != "" && !slices.Contains(exclude, s.Winner) {
+			exclude = append(exclude, s.Winner)
+		}
+	}
+	return exclude
+}
+
+// DrawGiveawayFairExcluding is like DrawGiveawayFair, but skips the users in exclude. It draws one
+// more winner for each excluded user, so the stored draw stays verifiable, and returns only the
+// first n winners that are not excluded.
+func DrawGiveawayFairExcluding(name string, n int, exclude []string) (d FairDraw, winners []GiveawayEntry, err error) {
+	d, err = DrawGiveawayFair(name, n+len(exclude))
+	if err != nil {
+		return FairDraw{}, nil, err
+	}
+	for _, w := range d.Winners {
+		if len(winners) < n && !slices.Contains(exclude, w.UserID) {
+			winners = append(winners, w)
+		}
+	}
+	return d, winners, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGiveawayPrizeClaim(t *testing.T) {
+	p, err := CreateGiveawayPrize(filepath.Join(t.TempDir(), "prizes.json"), GiveawayPrizeSingle{Name: "first"}, GiveawayPrizeSingle{Name: "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 12, 24, 18, 0, 0, 0, time.UTC)
+	first, second := p.Singles()[0], p.Singles()[1]
+
+	first.SetWinner("foo", now, 5*time.Minute)
+	first.Channel = "chan"
+	second.SetWinner("bar", now, 0)
+	if second.ClaimStatus != "" || second.ClaimDeadline != nil {
+		t.Errorf("SetWinner() without window = %+v, want no claim", second)
+	}
+	if _, ok := p.PendingClaim("chan", "bar"); ok {
+		t.Error("PendingClaim(chan, bar) found a claim, want none")
+	}
+	if _, ok := p.PendingClaim("other", "foo"); ok {
+		t.Error("PendingClaim(other, foo) found a claim of another channel")
+	}
+	if prize, ok := p.PendingClaim("chan", "foo"); !ok || prize != first {
+		t.Fatalf("PendingClaim(chan, foo) = %v, %v, want first", prize, ok)
+	}
+	if expired := p.ExpiredClaims("chan", now.Add(5*time.Minute)); len(expired) != 0 {
+		t.Errorf("ExpiredClaims() at deadline = %v, want none", expired)
+	}
+	if expired := p.ExpiredClaims("other", now.Add(5*time.Minute+time.Second)); len(expired) != 0 {
+		t.Errorf("ExpiredClaims() of another channel = %v, want none", expired)
+	}
+	if expired := p.ExpiredClaims("chan", now.Add(5*time.Minute+time.Second)); len(expired) != 1 || expired[0] != first {
+		t.Errorf("ExpiredClaims() after deadline = %v, want first", expired)
+	}
+	if first.Claim(now.Add(6 * time.Minute)) {
+		t.Error("Claim() after deadline succeeded")
+	}
+
+	first.Release()
+	if first.Winner != "" || first.Channel != "" || first.ClaimStatus != "" || len(first.Released) != 1 || first.Released[0] != "foo" {
+		t.Errorf("Release() = %+v, want no winner and foo released", first)
+	}
+	if got := p.RedrawExclusions(first); len(got) != 2 || got[0] != "foo" || got[1] != "bar" {
+		t.Errorf("RedrawExclusions() = %v, want [foo bar]", got)
+	}
+	if err = p.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	first.SetWinner("baz", now, time.Minute)
+	if !first.Claim(now.Add(time.Minute)) || first.ClaimStatus != GiveawayPrizeClaimClaimed || first.ClaimedAt == nil {
+		t.Errorf("Claim() at deadline = %+v, want claimed", first)
+	}
+	if first.Claim(now.Add(time.Minute)) {
+		t.Error("second Claim() succeeded")
+	}
+}
+
+func TestDrawGiveawayFairExcluding(t *testing.T) {
+	newTestDatabase(t)
+
+	AddGiveawayWeight("xmas", GiveawayPlatformTwitch, "foo", 3)
+	AddGiveawayWeight("xmas", GiveawayPlatformTwitch, "bar", 1)
+
+	d, winners, err := DrawGiveawayFairExcluding("xmas", 1, []string{"foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(winners) != 1 || winners[0].UserID != "bar" {
+		t.Errorf("DrawGiveawayFairExcluding() winners = %v, want bar", winners)
+	}
+	if err = VerifyFairDraw(d); err != nil {
+		t.Errorf("VerifyFairDraw() error = %v", err)
+	}
+
+	if _, winners, err = DrawGiveawayFairExcluding("xmas", 1, []string{"foo", "bar"}); err != nil || len(winners) != 0 {
+		t.Errorf("DrawGiveawayFairExcluding() excluding everyone = %v, %v, want none", winners, err)
+	}
+}
@@ -0,0 +1,329 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidPrizePath is returned when a PrizePath doesn't point to a prize of the expected kind.
+	ErrInvalidPrizePath = errors.New("invalid prize path")
+	// ErrPrizeAlreadyWon is returned when marking a prize as won that already has a winner.
+	ErrPrizeAlreadyWon = errors.New("prize already has a winner")
+)
+
+// PrizePath addresses a prize in the tree of a GiveawayPrize. Each element is the 1-based position
+// in the pool of the group on that level, so '2.1' is the first prize in the group at position 2
+// of the root group. An empty path is the root itself.
+type PrizePath []int
+
+// ParsePrizePath parses a path like '2.1', see PrizePath. An empty string is the root.
+func ParsePrizePath(s string) (PrizePath, error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), ".")
+	if s == "" {
+		return PrizePath{}, nil
+	}
+	var path PrizePath
+	for _, part := range strings.Split(s, ".") {
+		i, err := strconv.Atoi(part)
+		if err != nil || i <= 0 {
+			return nil, fmt.Errorf("%w '%s'", ErrInvalidPrizePath, s)
+		}
+		path = append(path, i)
+	}
+	return path, nil
+}
+
+// String implements fmt.Stringer. It returns the path in the format accepted by ParsePrizePath.
+func (pp PrizePath) String() string {
+	parts := make([]string, len(pp))
+	for i, p := range pp {
+		parts[i] = strconv.Itoa(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+// PrizeItem is a single node of the tree of a GiveawayPrize, see GiveawayPrize.Items.
+type PrizeItem struct {
+	Path PrizePath
+	// Group is true if the item is a group. Then Sort is set instead of Name and Winner.
+	Group  bool
+	Sort   GiveawayPrizeGroupSort
+	Name   string
+	Winner string
+	// Last is true if the item is the last one in the pool of its group.
+	Last bool
+}
+
+// Items returns all prizes and groups of p in depth-first order. A single prize as root is
+// returned as if it was the only prize in an ordered group.
+func (p GiveawayPrize) Items() []PrizeItem {
+	root := p.asGroup()
+	if root == nil {
+		return nil
+	}
+	return root.items(PrizePath{})
+}
+
+func (pg GiveawayPrizeGroup) items(parent PrizePath) (items []PrizeItem) {
+	for i, child := range pg.Pool {
+		item := PrizeItem{
+			Path: append(append(PrizePath{}, parent...), i+1),
+			Last: i == len(pg.Pool)-1,
+		}
+		switch t := child.giveawayPrizeInterface.(type) {
+		case *GiveawayPrizeSingle:
+			item.Name, item.Winner = t.Name, t.Winner
+			items = append(items, item)
+		case *GiveawayPrizeGroup:
+			item.Group, item.Sort = true, t.Sort
+			items = append(items, item)
+			items = append(items, t.items(item.Path)...)
+		}
+	}
+	return items
+}
+
+// Tree renders p as a tree with one line per prize or group. winner formats the winner of a prize,
+// e.g. as a mention. If winner is nil, the winner is used as is.
+func (p GiveawayPrize) Tree(winner func(string) string) string {
+	if winner == nil {
+		winner = func(w string) string { return w }
+	}
+	root := p.asGroup()
+	if root == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s]", root.Sort)
+	// last[i] is whether the ancestor on depth i+1 was the last one in its group
+	var last []bool
+	for _, item := range p.Items() {
+		depth := len(item.Path)
+		last = append(last[:depth-1], item.Last)
+
+		b.WriteByte('\n')
+		for _, l := range last[:depth-1] {
+			if l {
+				b.WriteString("   ")
+			} else {
+				b.WriteString("│  ")
+			}
+		}
+		if item.Last {
+			b.WriteString("└─ ")
+		} else {
+			b.WriteString("├─ ")
+		}
+		if item.Group {
+			fmt.Fprintf(&b, "%s. [%s]", item.Path, item.Sort)
+			continue
+		}
+		fmt.Fprintf(&b, "%s. %s", item.Path, item.Name)
+		if item.Winner != "" {
+			fmt.Fprintf(&b, " ✓ %s", winner(item.Winner))
+		}
+	}
+	return b.String()
+}
+
+// AddPrize adds a new prize with the given name at the end of the group at parent. If the root of
+// p is a single prize, it is turned into an ordered group first. It returns the path of the new
+// prize.
+func (p *GiveawayPrize) AddPrize(parent PrizePath, name string) (PrizePath, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("prize name cannot be empty")
+	}
+	group, err := p.group(parent)
+	if err != nil {
+		return nil, err
+	}
+	group.Pool = append(group.Pool, GiveawayPrize{giveawayPrizeInterface: &GiveawayPrizeSingle{Name: name}})
+	return append(append(PrizePath{}, parent...), len(group.Pool)), nil
+}
+
+// MovePrize moves the prize or group at path to the given 1-based position in its group. It
+// returns the new path.
+func (p *GiveawayPrize) MovePrize(path PrizePath, position int) (PrizePath, error) {
+	group, i, err := p.parent(path)
+	if err != nil {
+		return nil, err
+	}
+	if position < 1 || position > len(group.Pool) {
+		return nil, fmt.Errorf("%w: position %d is out of range 1-%d", ErrInvalidPrizePath, position, len(group.Pool))
+	}
+	moved := group.Pool[i]
+	group.Pool = append(group.Pool[:i], group.Pool[i+1:]...)
+	group.Pool = append(group.Pool[:position-1], append([]GiveawayPrize{moved}, group.Pool[position-1:]...)...)
+	return append(append(PrizePath{}, path[:len(path)-1]...), position), nil
+}
+
+// GroupPrizes moves count prizes or groups starting at path into a new group with the given sort.
+// The new group takes the place of the first moved prize, which is also the returned path.
+func (p *GiveawayPrize) GroupPrizes(path PrizePath, count int, sort GiveawayPrizeGroupSort) (PrizePath, error) {
+	if sort != GiveawayPrizeGroupOrdered && sort != GiveawayPrizeGroupRandom {
+		return nil, fmt.Errorf("invalid group sort '%s'", sort)
+	}
+	group, i, err := p.parent(path)
+	if err != nil {
+		return nil, err
+	}
+	if count < 1 || i+count > len(group.Pool) {
+		return nil, fmt.Errorf("%w: cannot group %d prizes starting at %s", ErrInvalidPrizePath, count, path)
+	}
+	newGroup := &GiveawayPrizeGroup{Sort: sort, Pool: append([]GiveawayPrize{}, group.Pool[i:i+count]...)}
+	group.Pool = append(group.Pool[:i+1], group.Pool[i+count:]...)
+	group.Pool[i] = GiveawayPrize{giveawayPrizeInterface: newGroup}
+	return path, nil
+}
+
+// RemovePrize removes the prize or group at path.
+func (p *GiveawayPrize) RemovePrize(path PrizePath) error {
+	group, i, err := p.parent(path)
+	if err != nil {
+		return err
+	}
+	group.Pool = append(group.Pool[:i], group.Pool[i+1:]...)
+	return nil
+}
+
+// SetPrizeWinner sets the winner of the prize at path. The winner doesn't need to claim the prize.
+// An empty winner makes the prize available again. Setting a winner on a prize that was already
+// won returns ErrPrizeAlreadyWon.
+func (p *GiveawayPrize) SetPrizeWinner(path PrizePath, winner string) error {
+	group, i, err := p.parent(path)
+	if err != nil {
+		return err
+	}
+	single, ok := group.Pool[i].giveawayPrizeInterface.(*GiveawayPrizeSingle)
+	if !ok {
+		return fmt.Errorf("%w: %s is a group", ErrInvalidPrizePath, path)
+	}
+	if winner == "" {
+		*single = GiveawayPrizeSingle{Name: single.Name, Released: single.Released}
+		return nil
+	}
+	if single.Winner != "" {
+		return fmt.Errorf("%w: %s is already won by '%s'", ErrPrizeAlreadyWon, path, single.Winner)
+	}
+	single.SetWinner(winner, time.Now(), 0)
+	return nil
+}
+
+// Validate checks that p and all of its prizes are well formed, i.e. every prize has a name and a
+// valid claim and every group a known sort and at least one prize.
+func (p GiveawayPrize) Validate() error {
+	switch t := p.giveawayPrizeInterface.(type) {
+	case *GiveawayPrizeSingle:
+		if strings.TrimSpace(t.Name) == "" {
+			return fmt.Errorf("prize without a name")
+		}
+		switch t.ClaimStatus {
+		case "", GiveawayPrizeClaimClaimed:
+		case GiveawayPrizeClaimPending:
+			if t.Winner == "" || t.ClaimDeadline == nil {
+				return fmt.Errorf("prize '%s' has a pending claim without winner or deadline", t.Name)
+			}
+		default:
+			return fmt.Errorf("prize '%s' has an invalid claim status '%s'", t.Name, t.ClaimStatus)
+		}
+	case *GiveawayPrizeGroup:
+		if t.Sort != GiveawayPrizeGroupOrdered && t.Sort != GiveawayPrizeGroupRandom {
+			return fmt.Errorf("group with invalid sort '%s'", t.Sort)
+		}
+		if len(t.Pool) == 0 {
+			return fmt.Errorf("group without prizes")
+		}
+		for i, child := range t.Pool {
+			if err := child.Validate(); err != nil {
+				return fmt.Errorf("%d: %v", i+1, err)
+			}
+		}
+	default:
+		return fmt.Errorf("prize is empty")
+	}
+	return nil
+}
+
+// asGroup returns the root of p as a group. A single prize as root is wrapped in an ordered group,
+// which is not stored in p.
+func (p GiveawayPrize) asGroup() *GiveawayPrizeGroup {
+	switch t := p.giveawayPrizeInterface.(type) {
+	case *GiveawayPrizeSingle:
+		return &GiveawayPrizeGroup{Sort: GiveawayPrizeGroupOrdered, Pool: []GiveawayPrize{{giveawayPrizeInterface: t}}}
+	case *GiveawayPrizeGroup:
+		return t
+	}
+	return nil
+}
+
+// group returns the group at path. Before, a single prize as root is turned into an ordered group,
+// so it can be edited like any other prize.
+func (p *GiveawayPrize) group(path PrizePath) (*GiveawayPrizeGroup, error) {
+	root := p.asGroup()
+	if root == nil {
+		return nil, fmt.Errorf("prize is empty")
+	}
+	p.giveawayPrizeInterface = root
+
+	group := root
+	for depth, i := range path {
+		if i < 1 || i > len(group.Pool) {
+			return nil, fmt.Errorf("%w: %s does not exist", ErrInvalidPrizePath, path[:depth+1])
+		}
+		next, ok := group.Pool[i-1].giveawayPrizeInterface.(*GiveawayPrizeGroup)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not a group", ErrInvalidPrizePath, path[:depth+1])
+		}
+		group = next
+	}
+	return group, nil
+}
+
+// parent returns the group containing the prize at path and the 0-based index of the prize in its
+// pool.
+func (p *GiveawayPrize) parent(path PrizePath) (*GiveawayPrizeGroup, int, error) {
+	if len(path) == 0 {
+		return nil, 0, fmt.Errorf("%w: the root cannot be changed", ErrInvalidPrizePath)
+	}
+	group, err := p.group(path[:len(path)-1])
+	if err != nil {
+		return nil, 0, err
+	}
+	i := path[len(path)-1]
+	if i < 1 || i > len(group.Pool) {
+		return nil, 0, fmt.Errorf("%w: %s does not exist", ErrInvalidPrizePath, path)
+	}
+	return group, i - 1, nil
+}
+
+// backupFile copies filename to '<filename>.bak', replacing the previous backup. It does nothing
+// if filename doesn't exist yet.
+func backupFile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename+".bak", data, 0644)
+}
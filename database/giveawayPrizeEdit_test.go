@@ -0,0 +1,145 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParsePrizePath(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    PrizePath
+		wantErr bool
+	}{
+		{"", PrizePath{}, false},
+		{"1", PrizePath{1}, false},
+		{"2.1.", PrizePath{2, 1}, false},
+		{" 3.10 ", PrizePath{3, 10}, false},
+		{"0", nil, true},
+		{"1..2", nil, true},
+		{"a", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParsePrizePath(tt.s)
+			if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePrizePath(%q) = %v, %v, want %v, err %v", tt.s, got, err, tt.want, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidPrizePath) {
+				t.Errorf("ParsePrizePath(%q) error = %v, want %v", tt.s, err, ErrInvalidPrizePath)
+			}
+		})
+	}
+}
+
+func TestGiveawayPrizeEdit(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "prizes.json")
+	p, err := CreateGiveawayPrize(filename, GiveawayPrizeSingle{Name: "A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a single root becomes a group on the first add
+	for _, name := range []string{"B", "C", "D"} {
+		if _, err = p.AddPrize(PrizePath{}, name); err != nil {
+			t.Fatalf("AddPrize(%s) error = %v", name, err)
+		}
+	}
+	if path, err := p.GroupPrizes(PrizePath{2}, 2, GiveawayPrizeGroupRandom); err != nil || path.String() != "2" {
+		t.Fatalf("GroupPrizes() = %v, %v, want 2", path, err)
+	}
+	if path, err := p.AddPrize(PrizePath{2}, "E"); err != nil || path.String() != "2.3" {
+		t.Fatalf("AddPrize(2) = %v, %v, want 2.3", path, err)
+	}
+	if path, err := p.MovePrize(PrizePath{3}, 1); err != nil || path.String() != "1" {
+		t.Fatalf("MovePrize() = %v, %v, want 1", path, err)
+	}
+	if err = p.SetPrizeWinner(PrizePath{3, 1}, "foo"); err != nil {
+		t.Fatalf("SetPrizeWinner() error = %v", err)
+	}
+	if err = p.SetPrizeWinner(PrizePath{3, 1}, "bar"); !errors.Is(err, ErrPrizeAlreadyWon) {
+		t.Errorf("SetPrizeWinner() on won prize error = %v, want %v", err, ErrPrizeAlreadyWon)
+	}
+	if err = p.SetPrizeWinner(PrizePath{3}, "bar"); !errors.Is(err, ErrInvalidPrizePath) {
+		t.Errorf("SetPrizeWinner() on group error = %v, want %v", err, ErrInvalidPrizePath)
+	}
+	if _, err = p.AddPrize(PrizePath{1}, "X"); !errors.Is(err, ErrInvalidPrizePath) {
+		t.Errorf("AddPrize() to single prize error = %v, want %v", err, ErrInvalidPrizePath)
+	}
+	if _, err = p.MovePrize(PrizePath{4}, 1); !errors.Is(err, ErrInvalidPrizePath) {
+		t.Errorf("MovePrize() of missing prize error = %v, want %v", err, ErrInvalidPrizePath)
+	}
+
+	want := `[ordered]
+├─ 1. D
+├─ 2. A
+└─ 3. [random]
+   ├─ 3.1. B ✓ foo
+   ├─ 3.2. C
+   └─ 3.3. E`
+	if got := p.Tree(nil); got != want {
+		t.Errorf("Tree() =\n%s\nwant\n%s", got, want)
+	}
+
+	if err = p.SaveFile(); err != nil {
+		t.Fatalf("SaveFile() error = %v", err)
+	}
+	if _, err = os.Stat(filename + ".bak"); err != nil {
+		t.Errorf("no backup of the previous version: %v", err)
+	}
+
+	p, err = NewGiveawayPrize(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.RemovePrize(PrizePath{3, 2}); err != nil {
+		t.Fatalf("RemovePrize() error = %v", err)
+	}
+	if err = p.SetPrizeWinner(PrizePath{3, 1}, ""); err != nil {
+		t.Fatalf("SetPrizeWinner() un-win error = %v", err)
+	}
+	var names []string
+	for _, item := range p.Items() {
+		if !item.Group {
+			names = append(names, item.Path.String()+item.Name+item.Winner)
+		}
+	}
+	if want := []string{"1D", "2A", "3.1B", "3.2E"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Items() = %v, want %v", names, want)
+	}
+
+	// invalid prizes are not saved
+	if _, err = p.AddPrize(PrizePath{}, " "); err == nil {
+		t.Error("AddPrize() with empty name succeeded")
+	}
+	p.giveawayPrizeInterface.(*GiveawayPrizeGroup).Sort = "shuffled"
+	if err = p.SaveFile(); err == nil {
+		t.Error("SaveFile() with invalid sort succeeded")
+	}
+	p.giveawayPrizeInterface.(*GiveawayPrizeGroup).Sort = GiveawayPrizeGroupOrdered
+	for _, path := range []PrizePath{{3, 2}, {3, 1}} {
+		if err = p.RemovePrize(path); err != nil {
+			t.Fatalf("RemovePrize(%s) error = %v", path, err)
+		}
+	}
+	if err = p.Validate(); err == nil {
+		t.Error("Validate() with empty group succeeded")
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGiveawayEntries(t *testing.T) {
+	newTestDatabase(t)
+
+	if e := AddGiveawayWeight("xmas", GiveawayPlatformDiscord, "1234", 3); e.Weight != 3 {
+		t.Fatalf("AddGiveawayWeight() = %+v, want weight 3", e)
+	}
+	// joining another giveaway doesn't touch the first one
+	if e := AddGiveawayWeight("tw11", GiveawayPlatformTwitch, "foo", 1); e.Weight != 1 {
+		t.Fatalf("AddGiveawayWeight() = %+v, want weight 1", e)
+	}
+	if e := AddGiveawayWeight("tw11", GiveawayPlatformDiscord, "1234", 2); e.Weight != 2 {
+		t.Fatalf("AddGiveawayWeight() = %+v, want weight 2", e)
+	}
+	if e := AddGiveawayWeight("xmas", GiveawayPlatformDiscord, "1234", 1); e.Weight != 4 {
+		t.Fatalf("AddGiveawayWeight() = %+v, want weight 4", e)
+	}
+
+	today, _ := time.Parse(time.DateOnly, time.Now().Format(time.DateOnly))
+	want := GiveawayEntry{UserID: "1234", Platform: GiveawayPlatformDiscord, Weight: 4, LastEntry: today}
+	if got := GetGiveawayEntry("xmas", GiveawayPlatformDiscord, "1234"); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetGiveawayEntry() = %+v, want %+v", got, want)
+	}
+	// same user on another platform is a different entry
+	if got := GetGiveawayEntry("xmas", GiveawayPlatformTwitch, "1234"); got.UserID != "1234" || got.Weight != 0 {
+		t.Errorf("GetGiveawayEntry() = %+v, want empty entry", got)
+	}
+
+	if err := DeleteGiveawayEntry("tw11", GiveawayPlatformDiscord, "1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteGiveawayEntry("tw11", GiveawayPlatformDiscord, "1234"); err != nil {
+		t.Errorf("DeleteGiveawayEntry() on missing entry error = %v", err)
+	}
+	if got := GetAllGiveawayEntries("tw11"); len(got) != 1 || got[0].UserID != "foo" {
+		t.Errorf("GetAllGiveawayEntries(tw11) = %+v, want only foo", got)
+	}
+	if got := GetAllGiveawayEntries("xmas"); len(got) != 1 || got[0].Weight != 4 {
+		t.Errorf("GetAllGiveawayEntries(xmas) = %+v, want one entry with weight 4", got)
+	}
+
+	var deltas []int
+	rows, err := Query("SELECT delta FROM giveaway_entry_events WHERE user_id=? ORDER BY id", "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d int
+		if err = rows.Scan(&d); err != nil {
+			t.Fatal(err)
+		}
+		deltas = append(deltas, d)
+	}
+	if want := []int{3, 2, 1, -2}; !slices.Equal(deltas, want) {
+		t.Errorf("got entry events %v, want %v", deltas, want)
+	}
+}
+
+func TestAddGiveawayWeightOn(t *testing.T) {
+	newTestDatabase(t)
+
+	// already the 25th in the guild, but still the 24th in UTC
+	loc := time.FixedZone("UTC+14", 14*60*60)
+	day := time.Date(2024, 12, 24, 23, 0, 0, 0, time.UTC).In(loc)
+	want, _ := time.Parse(time.DateOnly, "2024-12-25")
+
+	if e := AddGiveawayWeightOn("xmas", GiveawayPlatformDiscord, "1234", 1, day); !e.LastEntry.Equal(want) {
+		t.Errorf("AddGiveawayWeightOn() last entry = %v, want %v", e.LastEntry, want)
+	}
+	if e := GetGiveawayEntry("xmas", GiveawayPlatformDiscord, "1234"); !e.LastEntry.Equal(want) || e.Weight != 1 {
+		t.Errorf("GetGiveawayEntry() = %+v, want weight 1 on %v", e, want)
+	}
+}
+
+func TestGiveawayEntriesMigration(t *testing.T) {
+	s := newTestStore(t)
+	if err := migrateTo(s, 7); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range [][]any{
+		{"1234", 5, "xmas-2023-12-24"},
+		{"foo", 3, "tw11-2024-11-02"},
+		{"bar", 1, "my-channel-20241101-2024-11-03"},
+		{"5678", 2, ""},
+	} {
+		if _, err := s.Exec("INSERT INTO giveaway (id,weight,last_entry_id) VALUES (?,?,?)", row...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Migrate(s); err != nil {
+		t.Fatal(err)
+	}
+	useTestStore(t, s)
+
+	tests := []struct {
+		giveaway string
+		want     GiveawayEntry
+	}{
+		{"xmas", GiveawayEntry{"1234", GiveawayPlatformDiscord, 5, time.Date(2023, 12, 24, 0, 0, 0, 0, time.UTC)}},
+		{"tw11", GiveawayEntry{"foo", GiveawayPlatformTwitch, 3, time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC)}},
+		{"my-channel-20241101", GiveawayEntry{"bar", GiveawayPlatformTwitch, 1, time.Date(2024, 11, 3, 0, 0, 0, 0, time.UTC)}},
+		{"legacy", GiveawayEntry{"5678", GiveawayPlatformDiscord, 2, time.Time{}}},
+	}
+	for _, tt := range tests {
+		got := GetAllGiveawayEntries(tt.giveaway)
+		if len(got) != 1 || got[0].UserID != tt.want.UserID || got[0].Platform != tt.want.Platform ||
+			got[0].Weight != tt.want.Weight || !got[0].LastEntry.Equal(tt.want.LastEntry) {
+			t.Errorf("GetAllGiveawayEntries(%s) = %+v, want [%+v]", tt.giveaway, got, tt.want)
+		}
+	}
+
+	var n int
+	if err := s.QueryRow("SELECT COUNT(*) FROM giveaway_legacy").Scan(&n); err != nil || n != 4 {
+		t.Errorf("got %d rows in giveaway_legacy, %v, want 4", n, err)
+	}
+	if _, err := s.Query("SELECT id FROM giveaway"); err == nil || !strings.Contains(err.Error(), "no such table") {
+		t.Errorf("old giveaway table still exists: %v", err)
+	}
+}
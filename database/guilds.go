@@ -0,0 +1,210 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// GuildSettingColumns lists all columns of the guilds table that hold a per-guild setting. Each of
+// them stores a channel or role ID, where 0 means not set.
+var GuildSettingColumns = []string{
+	"birthday_id",
+	"no_mic_id",
+	"youtube_channel",
+	"youtube_role",
+	"adventcalendar_channel",
+	"log_channel",
+	"twitch_channel",
+	"twitch_role",
+}
+
+// insertIgnore returns the statement start for an insert that silently skips rows that would
+// violate a unique key.
+func insertIgnore() string {
+	if db.Driver() == DriverSQLite {
+		return "INSERT OR IGNORE"
+	}
+	return "INSERT IGNORE"
+}
+
+// AddGuild inserts an empty settings row for the given guild, if it doesn't exist yet.
+func AddGuild(guildID string) error {
+	_, err := Exec(insertIgnore()+" INTO guilds (id) VALUES (?)", guildID)
+	return err
+}
+
+// GetGuildSettings returns the settings of the given guild mapped from column to ID. Unset values
+// are returned as an empty string. If the guild has no row yet, all values are empty.
+//
+// When no columns are given, all of GuildSettingColumns are returned.
+func GetGuildSettings(guildID string, columns ...string) (map[string]string, error) {
+	if len(columns) == 0 {
+		columns = GuildSettingColumns
+	}
+	settings := make(map[string]string, len(columns))
+	values := make([]uint64, len(columns))
+	dest := make([]any, len(columns))
+	query := "SELECT "
+	for i, c := range columns {
+		if !slices.Contains(GuildSettingColumns, c) {
+			return nil, fmt.Errorf("unknown guild setting '%s'", c)
+		}
+		if i > 0 {
+			query += ","
+		}
+		query += c
+		dest[i] = &values[i]
+		settings[c] = ""
+	}
+
+	err := QueryRow(query+" FROM guilds WHERE id=?", guildID).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for i, c := range columns {
+		if values[i] != 0 {
+			settings[c] = strconv.FormatUint(values[i], 10)
+		}
+	}
+	return settings, nil
+}
+
+// SetGuildSetting sets column of the given guild to id. An empty id resets the setting. The guild
+// row is created if it doesn't exist yet.
+func SetGuildSetting(guildID, column, id string) error {
+	if !slices.Contains(GuildSettingColumns, column) {
+		return fmt.Errorf("unknown guild setting '%s'", column)
+	}
+	var value uint64
+	if id != "" {
+		var err error
+		value, err = strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid id '%s' for guild setting '%s': %v", id, column, err)
+		}
+	}
+
+	if err := AddGuild(guildID); err != nil {
+		return err
+	}
+	_, err := Exec("UPDATE guilds SET "+column+"=? WHERE id=?", value, guildID)
+	return err
+}
+
+// GetGuildIDs returns the IDs of all guilds in the guilds table.
+func GetGuildIDs() ([]string, error) {
+	rows, err := Query("SELECT id FROM guilds")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var guildIDs []string
+	for rows.Next() {
+		var id uint64
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		guildIDs = append(guildIDs, strconv.FormatUint(id, 10))
+	}
+	return guildIDs, rows.Err()
+}
+
+// RemoveGuild deletes the guild and all its settings.
+func RemoveGuild(guildID string) error {
+	_, err := Exec("DELETE FROM guilds WHERE id=?", guildID)
+	return err
+}
+
+// GetGuildTimezone returns the name of the configured timezone of the given guild. It is empty if
+// no timezone is set.
+func GetGuildTimezone(guildID string) (string, error) {
+	var name string
+	err := QueryRow("SELECT timezone FROM guilds WHERE id=?", guildID).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return name, err
+}
+
+// GetGuildLocation returns the location of the configured timezone of the given guild. If none is
+// set, time.Local is returned.
+func GetGuildLocation(guildID string) (*time.Location, error) {
+	name, err := GetGuildTimezone(guildID)
+	if err != nil {
+		return time.Local, err
+	}
+	return loadLocation(name)
+}
+
+// GetGuildLocations returns the locations of all guilds in the guilds table mapped by their ID.
+// Guilds without a valid timezone use time.Local.
+func GetGuildLocations() (map[string]*time.Location, error) {
+	rows, err := Query("SELECT id,timezone FROM guilds")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := make(map[string]*time.Location)
+	for rows.Next() {
+		var (
+			id   uint64
+			name string
+		)
+		if err = rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		guildID := strconv.FormatUint(id, 10)
+		locations[guildID], err = loadLocation(name)
+		if err != nil {
+			log.Printf("Warning: guild '%s' has an invalid timezone: %v\n", guildID, err)
+		}
+	}
+	return locations, rows.Err()
+}
+
+// SetGuildTimezone sets the timezone of the given guild. name must be a valid IANA timezone name,
+// like 'Europe/Berlin'. An empty name resets it to the local timezone of the bot.
+func SetGuildTimezone(guildID, name string) error {
+	if _, err := loadLocation(name); err != nil {
+		return err
+	}
+	if err := AddGuild(guildID); err != nil {
+		return err
+	}
+	_, err := Exec("UPDATE guilds SET timezone=? WHERE id=?", name, guildID)
+	return err
+}
+
+// loadLocation is like time.LoadLocation, but an empty name results in time.Local instead of UTC.
+// On error time.Local is returned as well.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local, fmt.Errorf("invalid timezone '%s': %v", name, err)
+	}
+	return loc, nil
+}
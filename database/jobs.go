@@ -0,0 +1,43 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GetJobLastRun returns the time of the last successful run of the scheduled job with the given
+// name. ok is false if the job never ran.
+func GetJobLastRun(name string) (lastRun time.Time, ok bool, err error) {
+	err = QueryRow("SELECT last_run FROM scheduled_jobs WHERE name=?", name).Scan(&lastRun)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	} else if err != nil {
+		return time.Time{}, false, err
+	}
+	return lastRun, true, nil
+}
+
+// SetJobLastRun records t as the last successful run of the scheduled job with the given name.
+func SetJobLastRun(name string, t time.Time) error {
+	t = t.UTC().Truncate(time.Second)
+	_, err := Exec(insertIgnore()+" INTO scheduled_jobs (name,last_run) VALUES (?,?)", name, t)
+	if err != nil {
+		return err
+	}
+	_, err = Exec("UPDATE scheduled_jobs SET last_run=? WHERE name=?", t, name)
+	return err
+}
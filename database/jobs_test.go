@@ -0,0 +1,40 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJobLastRun(t *testing.T) {
+	newTestDatabase(t)
+
+	if _, ok, err := GetJobLastRun("foo"); err != nil || ok {
+		t.Fatalf("GetJobLastRun() = _, %v, %v, want false, nil", ok, err)
+	}
+	for _, want := range []time.Time{
+		time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC),
+		time.Date(2024, 12, 2, 8, 0, 1, 0, time.FixedZone("CET", 3600)),
+	} {
+		if err := SetJobLastRun("foo", want); err != nil {
+			t.Fatal(err)
+		}
+		got, ok, err := GetJobLastRun("foo")
+		if err != nil || !ok || !got.Equal(want) {
+			t.Errorf("GetJobLastRun() = %v, %v, %v, want %v", got, ok, err, want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles contains the ordered SQL migrations for each driver, i.e.
+// 'migrations/<driver>/<version>_<name>.sql'
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// ErrSchemaTooNew is returned by Migrate when the database was already migrated by a newer
+// version of the bot. Running an older binary against it could corrupt data.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+// migration is a single schema migration
+type migration struct {
+	version    int
+	name       string
+	statements []string
+}
+
+// Migrate brings the schema of s up to date. It records the applied versions in the
+// 'schema_version' table and applies every embedded migration that is newer than the current
+// version in ascending order.
+//
+// If the database has a higher version than the newest known migration ErrSchemaTooNew is
+// returned and nothing is changed.
+func Migrate(s Store) error {
+	return migrateTo(s, 0)
+}
+
+// migrateTo is like Migrate, but stops after the migration with the target version. A target of 0
+// applies all migrations.
+func migrateTo(s Store, target int) error {
+	migrations, err := loadMigrations(s.Driver())
+	if err != nil {
+		return fmt.Errorf("load migrations: %v", err)
+	}
+
+	_, err = s.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+	version    INT          NOT NULL PRIMARY KEY,
+	name       VARCHAR(255) NOT NULL,
+	applied_at TIMESTAMP    NOT NULL
+)`)
+	if err != nil {
+		return fmt.Errorf("create schema_version table: %v", err)
+	}
+
+	current, err := SchemaVersion(s)
+	if err != nil {
+		return err
+	}
+	var latest int
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].version
+	}
+	if current > latest {
+		return fmt.Errorf("%w: database is at version %d, but the latest known version is %d", ErrSchemaTooNew, current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if target > 0 && m.version > target {
+			break
+		}
+		log.Printf("Applying database migration %04d_%s...", m.version, m.name)
+		if err = applyMigration(s, m); err != nil {
+			return fmt.Errorf("migration %04d_%s: %v", m.version, m.name, err)
+		}
+	}
+	if target > 0 && target < latest {
+		latest = target
+	}
+	if latest > current {
+		log.Printf("Database schema migrated from version %d to %d", current, latest)
+	}
+	return nil
+}
+
+// SchemaVersion returns the currently applied schema version of s. It returns 0 if no migration
+// was applied yet.
+func SchemaVersion(s Querier) (version int, err error) {
+	err = s.QueryRow("SELECT COALESCE(MAX(version),0) FROM schema_version").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("get schema version: %v", err)
+	}
+	return version, nil
+}
+
+// applyMigration runs all statements of m and records it in the same transaction.
+//
+// Note that MySQL implicitly commits most schema changes (like CREATE or ALTER), so a failed
+// migration may be partially applied there.
+func applyMigration(s Store, m migration) error {
+	tx, err := s.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, stmt := range m.statements {
+		if _, err = tx.Exec(stmt); err != nil {
+			return fmt.Errorf("statement %d: %v", i+1, err)
+		}
+	}
+	_, err = tx.Exec("INSERT INTO schema_version (version,name,applied_at) VALUES (?,?,?)", m.version, m.name, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("record version: %v", err)
+	}
+	return tx.Commit()
+}
+
+// loadMigrations reads all embedded migrations for the given driver and returns them sorted by
+// version.
+func loadMigrations(driver string) ([]migration, error) {
+	dir := path.Join("migrations", driver)
+	files, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migration
+	seen := make(map[int]string)
+	for _, f := range files {
+		if f.IsDir() || path.Ext(f.Name()) != ".sql" {
+			continue
+		}
+		base := strings.TrimSuffix(f.Name(), ".sql")
+		versionString, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name '%s': want <version>_<name>.sql", f.Name())
+		}
+		version, err := strconv.Atoi(versionString)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in '%s'", f.Name())
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d: '%s' and '%s'", version, other, f.Name())
+		}
+		seen[version] = f.Name()
+
+		data, err := migrationFiles.ReadFile(path.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration{
+			version:    version,
+			name:       name,
+			statements: splitStatements(string(data)),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
+
+// splitStatements splits a migration file into its single statements. A statement ends with a
+// semicolon at the end of a line. Lines starting with '--' are comments and ignored.
+func splitStatements(data string) (statements []string) {
+	var current strings.Builder
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteByte('\n')
+		if strings.HasSuffix(trimmed, ";") {
+			stmt := strings.TrimSpace(current.String())
+			statements = append(statements, strings.TrimSuffix(stmt, ";"))
+			current.Reset()
+		}
+	}
+	if stmt := strings.TrimSpace(current.String()); stmt != "" {
+		statements = append(statements, stmt)
+	}
+	return statements
+}
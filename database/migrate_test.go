@@ -0,0 +1,118 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	s := newTestStore(t)
+
+	if err := Migrate(s); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	migrations, err := loadMigrations(DriverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := migrations[len(migrations)-1].version
+	if v, err := SchemaVersion(s); err != nil || v != latest {
+		t.Fatalf("SchemaVersion() = %d, %v, want %d", v, err, latest)
+	}
+
+	// the columns the modules rely on must exist
+	for _, q := range []string{
+		"SELECT id,birthday_id,no_mic_id,youtube_channel,youtube_role,adventcalendar_channel,log_channel,timezone FROM guilds",
+		"SELECT id,day,month,year,visible FROM birthdays",
+	} {
+		rows, err := s.Query(q)
+		if err != nil {
+			t.Errorf("%s: %v", q, err)
+			continue
+		}
+		rows.Close()
+	}
+
+	// running again is a no-op
+	if err := Migrate(s); err != nil {
+		t.Fatalf("second Migrate() error = %v", err)
+	}
+	var n int
+	if err := s.QueryRow("SELECT COUNT(*) FROM schema_version").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != len(migrations) {
+		t.Errorf("got %d recorded versions, want %d", n, len(migrations))
+	}
+}
+
+func TestMigrateSchemaTooNew(t *testing.T) {
+	s := newTestStore(t)
+	if err := Migrate(s); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Exec("INSERT INTO schema_version (version,name,applied_at) VALUES (9999,'future',CURRENT_TIMESTAMP)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Migrate(s); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Migrate() error = %v, want %v", err, ErrSchemaTooNew)
+	}
+}
+
+func TestMigrationsInSync(t *testing.T) {
+	versions := func(driver string) (v []int) {
+		migrations, err := loadMigrations(driver)
+		if err != nil {
+			t.Fatalf("loadMigrations(%s) error = %v", driver, err)
+		}
+		for i, m := range migrations {
+			if m.version != i+1 {
+				t.Errorf("%s: migration %d has version %d, versions must be consecutive", driver, i+1, m.version)
+			}
+			v = append(v, m.version)
+		}
+		return v
+	}
+
+	mysql, sqlite := versions(DriverMySQL), versions(DriverSQLite)
+	if !reflect.DeepEqual(mysql, sqlite) {
+		t.Errorf("mysql and sqlite migrations differ: %v != %v", mysql, sqlite)
+	}
+}
+
+func Test_splitStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"comments only", "-- foo\n\n  -- bar\n", nil},
+		{"single", "CREATE TABLE a (id INT);\n", []string{"CREATE TABLE a (id INT)"}},
+		{"multi line", "CREATE TABLE a (\n\tid INT\n);\n-- comment\nDROP TABLE b;", []string{"CREATE TABLE a (\n\tid INT\n)", "DROP TABLE b"}},
+		{"missing semicolon", "DROP TABLE a;\nDROP TABLE b", []string{"DROP TABLE a", "DROP TABLE b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
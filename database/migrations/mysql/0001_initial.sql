@@ -0,0 +1,26 @@
+-- Initial schema. All tables are created only if they don't exist yet, so databases that were set
+-- up by hand before migrations existed are adopted as version 1.
+
+CREATE TABLE IF NOT EXISTS guilds (
+	id                     BIGINT UNSIGNED NOT NULL PRIMARY KEY,
+	birthday_id            BIGINT UNSIGNED NOT NULL DEFAULT 0,
+	no_mic_id              BIGINT UNSIGNED NOT NULL DEFAULT 0,
+	youtube_channel        BIGINT UNSIGNED NOT NULL DEFAULT 0,
+	youtube_role           BIGINT UNSIGNED NOT NULL DEFAULT 0,
+	adventcalendar_channel BIGINT UNSIGNED NOT NULL DEFAULT 0,
+	log_channel            BIGINT UNSIGNED NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS birthdays (
+	id      BIGINT UNSIGNED   NOT NULL PRIMARY KEY,
+	day     TINYINT UNSIGNED  NOT NULL,
+	month   TINYINT UNSIGNED  NOT NULL,
+	year    SMALLINT UNSIGNED NOT NULL DEFAULT 0,
+	visible BOOLEAN           NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE IF NOT EXISTS giveaway (
+	id            VARCHAR(64) NOT NULL PRIMARY KEY,
+	weight        INT         NOT NULL DEFAULT 0,
+	last_entry_id VARCHAR(64) NOT NULL DEFAULT ''
+);
@@ -0,0 +1,7 @@
+-- Last successful run of each scheduled job, used to catch up on runs that were missed while the
+-- bot was offline.
+
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+	name     VARCHAR(64) NOT NULL PRIMARY KEY,
+	last_run DATETIME    NOT NULL
+);
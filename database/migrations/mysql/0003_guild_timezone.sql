@@ -0,0 +1,3 @@
+-- IANA timezone of a guild, like 'Europe/Berlin'. Empty uses the local timezone of the bot.
+
+ALTER TABLE guilds ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT '';
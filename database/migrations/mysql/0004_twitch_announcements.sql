@@ -0,0 +1,24 @@
+-- Per-guild channel and ping role for Twitch go-live announcements, plus the announced streams and
+-- their messages, so they can be edited when the stream ends.
+
+ALTER TABLE guilds ADD COLUMN twitch_channel BIGINT UNSIGNED NOT NULL DEFAULT 0;
+ALTER TABLE guilds ADD COLUMN twitch_role BIGINT UNSIGNED NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS twitch_streams (
+	id                VARCHAR(64)  NOT NULL PRIMARY KEY,
+	broadcaster_id    VARCHAR(64)  NOT NULL,
+	broadcaster_login VARCHAR(64)  NOT NULL,
+	title             VARCHAR(255) NOT NULL DEFAULT '',
+	game              VARCHAR(255) NOT NULL DEFAULT '',
+	started_at        DATETIME     NOT NULL,
+	ended_at          DATETIME     NULL,
+	peak_viewers      INT          NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS twitch_announcements (
+	stream_id  VARCHAR(64)     NOT NULL,
+	guild_id   BIGINT UNSIGNED NOT NULL,
+	channel_id BIGINT UNSIGNED NOT NULL,
+	message_id BIGINT UNSIGNED NOT NULL,
+	PRIMARY KEY (stream_id, guild_id)
+);
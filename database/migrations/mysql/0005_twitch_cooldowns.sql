@@ -0,0 +1,9 @@
+-- Time of the last giveaway ticket bought by a user in a Twitch channel. Replaces the times file
+-- of the Twitch giveaway, see event.twitch_giveaway.times in the config.
+
+CREATE TABLE IF NOT EXISTS twitch_cooldowns (
+	channel   VARCHAR(64) NOT NULL,
+	username  VARCHAR(64) NOT NULL,
+	last_used DATETIME    NOT NULL,
+	PRIMARY KEY (channel, username)
+);
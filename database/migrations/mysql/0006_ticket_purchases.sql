@@ -0,0 +1,14 @@
+-- Ledger of giveaway ticket purchases. Every step of a purchase (point deduction, ticket write,
+-- refund) is recorded as its own row, so failed or partial purchases can be audited later.
+
+CREATE TABLE IF NOT EXISTS ticket_purchases (
+	id          BIGINT       NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	purchase_id VARCHAR(32)  NOT NULL,
+	channel     VARCHAR(64)  NOT NULL,
+	username    VARCHAR(64)  NOT NULL,
+	step        VARCHAR(32)  NOT NULL,
+	amount      INT          NOT NULL DEFAULT 0,
+	error       VARCHAR(512) NOT NULL DEFAULT '',
+	created_at  DATETIME     NOT NULL,
+	INDEX (purchase_id)
+);
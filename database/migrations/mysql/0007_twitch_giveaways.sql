@@ -0,0 +1,9 @@
+-- The giveaway of each Twitch channel. Its prefix separates the entries of this giveaway from
+-- others in the giveaway table.
+
+CREATE TABLE IF NOT EXISTS twitch_giveaways (
+	channel    VARCHAR(64) NOT NULL PRIMARY KEY,
+	prefix     VARCHAR(64) NOT NULL,
+	open       BOOLEAN     NOT NULL DEFAULT TRUE,
+	started_at DATETIME    NOT NULL
+);
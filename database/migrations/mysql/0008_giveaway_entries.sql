@@ -0,0 +1,56 @@
+-- Normalized giveaways. Previously the giveaway table was keyed by user and the giveaway was only
+-- encoded in last_entry_id as '<prefix>-<date>', so a user could only take part in one giveaway at
+-- a time. Now every giveaway has its own row and entries are keyed by giveaway and user. Each
+-- change of an entry is logged in giveaway_entry_events.
+--
+-- The old entries are copied into the new tables, grouped by their prefix. Entries without a
+-- last_entry_id are moved to a giveaway named 'legacy'. The old table is kept as giveaway_legacy.
+
+CREATE TABLE IF NOT EXISTS giveaways (
+	id         BIGINT      NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	name       VARCHAR(64) NOT NULL UNIQUE,
+	created_at DATETIME    NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS giveaway_entries (
+	giveaway_id BIGINT      NOT NULL,
+	user_id     VARCHAR(64) NOT NULL,
+	platform    VARCHAR(16) NOT NULL,
+	weight      INT         NOT NULL DEFAULT 0,
+	last_entry  DATETIME    NULL,
+	PRIMARY KEY (giveaway_id, platform, user_id),
+	FOREIGN KEY (giveaway_id) REFERENCES giveaways (id)
+);
+
+CREATE TABLE IF NOT EXISTS giveaway_entry_events (
+	id          BIGINT      NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	giveaway_id BIGINT      NOT NULL,
+	user_id     VARCHAR(64) NOT NULL,
+	platform    VARCHAR(16) NOT NULL,
+	delta       INT         NOT NULL,
+	reason      VARCHAR(16) NOT NULL,
+	created_at  DATETIME    NOT NULL,
+	INDEX (giveaway_id, platform, user_id)
+);
+
+INSERT INTO giveaways (name,created_at)
+SELECT DISTINCT
+	CASE WHEN last_entry_id = '' THEN 'legacy' ELSE LEFT(last_entry_id, LENGTH(last_entry_id) - 11) END,
+	UTC_TIMESTAMP()
+FROM giveaway;
+
+INSERT INTO giveaway_entries (giveaway_id,user_id,platform,weight,last_entry)
+SELECT
+	g.id,
+	o.id,
+	CASE WHEN o.id REGEXP '^[0-9]+$' THEN 'discord' ELSE 'twitch' END,
+	o.weight,
+	CASE WHEN o.last_entry_id = '' THEN NULL ELSE CONCAT(RIGHT(o.last_entry_id, 10), ' 00:00:00') END
+FROM giveaway o
+JOIN giveaways g ON g.name = CASE WHEN o.last_entry_id = '' THEN 'legacy' ELSE LEFT(o.last_entry_id, LENGTH(o.last_entry_id) - 11) END;
+
+INSERT INTO giveaway_entry_events (giveaway_id,user_id,platform,delta,reason,created_at)
+SELECT giveaway_id, user_id, platform, weight, 'migrated', UTC_TIMESTAMP()
+FROM giveaway_entries;
+
+ALTER TABLE giveaway RENAME TO giveaway_legacy;
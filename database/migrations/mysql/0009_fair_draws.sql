@@ -0,0 +1,18 @@
+-- Commit-reveal draws. Each giveaway holds a secret seed, whose SHA-256 hash (the commitment) is
+-- published before the draw. Every draw is stored with the revealed seed and the exact entry list,
+-- so anyone can recompute the result. After a draw the giveaway gets a new seed for the next one.
+
+ALTER TABLE giveaways ADD COLUMN seed VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE giveaways ADD COLUMN commitment VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS giveaway_draws (
+	id            BIGINT      NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	giveaway_id   BIGINT      NOT NULL,
+	seed          VARCHAR(64) NOT NULL,
+	commitment    VARCHAR(64) NOT NULL,
+	entries       MEDIUMTEXT  NOT NULL,
+	winners       TEXT        NOT NULL,
+	total_tickets INT         NOT NULL,
+	created_at    DATETIME    NOT NULL,
+	FOREIGN KEY (giveaway_id) REFERENCES giveaways (id)
+);
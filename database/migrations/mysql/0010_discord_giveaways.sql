@@ -0,0 +1,22 @@
+-- Giveaways hosted in a Discord channel via '/giveaway create'. Each row belongs to a giveaway in
+-- the giveaways table and holds the message with the entry button. Once ends_at has passed the
+-- giveaway is drawn automatically. draw_id references the stored fair draw and is NULL until the
+-- winners are drawn. The giveaway is ended and drawn in one transaction. announced is set once the
+-- winners are announced, so a failed announcement is retried.
+
+CREATE TABLE IF NOT EXISTS discord_giveaways (
+	giveaway_id BIGINT          NOT NULL PRIMARY KEY,
+	guild_id    BIGINT UNSIGNED NOT NULL,
+	channel_id  BIGINT UNSIGNED NOT NULL,
+	message_id  BIGINT UNSIGNED NOT NULL DEFAULT 0,
+	host_id     BIGINT UNSIGNED NOT NULL,
+	prize       VARCHAR(255)    NOT NULL,
+	winners     INT             NOT NULL,
+	role_id     BIGINT UNSIGNED NOT NULL DEFAULT 0,
+	ends_at     DATETIME        NOT NULL,
+	ended       BOOLEAN         NOT NULL DEFAULT FALSE,
+	draw_id     BIGINT          NULL,
+	announced   BOOLEAN         NOT NULL DEFAULT FALSE,
+	FOREIGN KEY (giveaway_id) REFERENCES giveaways (id),
+	INDEX (ended, ends_at)
+);
@@ -0,0 +1,30 @@
+-- Native loyalty points of Twitch channels that don't use StreamElements. twitch_points holds the
+-- balance of each user, twitch_points_ledger every change of it. Users get points for chatting,
+-- limited by last_chat, and for watching, tracked by joining and leaving the chat in
+-- twitch_viewers.
+
+CREATE TABLE IF NOT EXISTS twitch_points (
+	channel   VARCHAR(64) NOT NULL,
+	username  VARCHAR(64) NOT NULL,
+	points    BIGINT      NOT NULL DEFAULT 0,
+	last_chat DATETIME    NULL,
+	PRIMARY KEY (channel, username)
+);
+
+CREATE TABLE IF NOT EXISTS twitch_points_ledger (
+	id         BIGINT      NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	channel    VARCHAR(64) NOT NULL,
+	username   VARCHAR(64) NOT NULL,
+	amount     BIGINT      NOT NULL,
+	reason     VARCHAR(32) NOT NULL,
+	created_at DATETIME    NOT NULL,
+	INDEX (channel, username)
+);
+
+CREATE TABLE IF NOT EXISTS twitch_viewers (
+	channel     VARCHAR(64) NOT NULL,
+	username    VARCHAR(64) NOT NULL,
+	joined_at   DATETIME    NOT NULL,
+	rewarded_at DATETIME    NOT NULL,
+	PRIMARY KEY (channel, username)
+);
@@ -0,0 +1,23 @@
+-- Links between Discord and Twitch accounts. twitch_users maps the stable Twitch user IDs to their
+-- current login. It is updated from the chat, so the rows keyed by login (entries, cooldowns,
+-- points) follow a user that renamed. account_links stores the Twitch user ID linked to a Discord
+-- user, which is verified by typing a one-time code from account_link_codes in the Twitch chat.
+
+CREATE TABLE IF NOT EXISTS twitch_users (
+	id         VARCHAR(32) NOT NULL PRIMARY KEY,
+	login      VARCHAR(64) NOT NULL,
+	updated_at DATETIME    NOT NULL,
+	INDEX (login)
+);
+
+CREATE TABLE IF NOT EXISTS account_links (
+	discord_id VARCHAR(32) NOT NULL PRIMARY KEY,
+	twitch_id  VARCHAR(32) NOT NULL UNIQUE,
+	linked_at  DATETIME    NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS account_link_codes (
+	code       VARCHAR(16) NOT NULL PRIMARY KEY,
+	discord_id VARCHAR(32) NOT NULL UNIQUE,
+	expires_at DATETIME    NOT NULL
+);
@@ -0,0 +1,25 @@
+-- Initial schema. See the mysql migration of the same version for details.
+
+CREATE TABLE IF NOT EXISTS guilds (
+	id                     INTEGER NOT NULL PRIMARY KEY,
+	birthday_id            INTEGER NOT NULL DEFAULT 0,
+	no_mic_id              INTEGER NOT NULL DEFAULT 0,
+	youtube_channel        INTEGER NOT NULL DEFAULT 0,
+	youtube_role           INTEGER NOT NULL DEFAULT 0,
+	adventcalendar_channel INTEGER NOT NULL DEFAULT 0,
+	log_channel            INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS birthdays (
+	id      INTEGER NOT NULL PRIMARY KEY,
+	day     INTEGER NOT NULL,
+	month   INTEGER NOT NULL,
+	year    INTEGER NOT NULL DEFAULT 0,
+	visible BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE IF NOT EXISTS giveaway (
+	id            TEXT    NOT NULL PRIMARY KEY,
+	weight        INTEGER NOT NULL DEFAULT 0,
+	last_entry_id TEXT    NOT NULL DEFAULT ''
+);
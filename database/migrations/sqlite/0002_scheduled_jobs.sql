@@ -0,0 +1,6 @@
+-- Last run of each scheduled job. See the mysql migration of the same version for details.
+
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+	name     TEXT     NOT NULL PRIMARY KEY,
+	last_run DATETIME NOT NULL
+);
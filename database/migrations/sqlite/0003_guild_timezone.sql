@@ -0,0 +1,3 @@
+-- Timezone of a guild. See the mysql migration of the same version for details.
+
+ALTER TABLE guilds ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
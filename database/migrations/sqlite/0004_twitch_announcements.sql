@@ -0,0 +1,23 @@
+-- Twitch go-live announcements. See the mysql migration of the same version for details.
+
+ALTER TABLE guilds ADD COLUMN twitch_channel INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE guilds ADD COLUMN twitch_role INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS twitch_streams (
+	id                TEXT     NOT NULL PRIMARY KEY,
+	broadcaster_id    TEXT     NOT NULL,
+	broadcaster_login TEXT     NOT NULL,
+	title             TEXT     NOT NULL DEFAULT '',
+	game              TEXT     NOT NULL DEFAULT '',
+	started_at        DATETIME NOT NULL,
+	ended_at          DATETIME NULL,
+	peak_viewers      INTEGER  NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS twitch_announcements (
+	stream_id  TEXT    NOT NULL,
+	guild_id   INTEGER NOT NULL,
+	channel_id INTEGER NOT NULL,
+	message_id INTEGER NOT NULL,
+	PRIMARY KEY (stream_id, guild_id)
+);
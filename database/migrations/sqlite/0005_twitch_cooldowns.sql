@@ -0,0 +1,8 @@
+-- Twitch giveaway cooldowns. See the mysql migration of the same version for details.
+
+CREATE TABLE IF NOT EXISTS twitch_cooldowns (
+	channel   TEXT     NOT NULL,
+	username  TEXT     NOT NULL,
+	last_used DATETIME NOT NULL,
+	PRIMARY KEY (channel, username)
+);
@@ -0,0 +1,14 @@
+-- Giveaway ticket purchase ledger. See the mysql migration of the same version for details.
+
+CREATE TABLE IF NOT EXISTS ticket_purchases (
+	id          INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+	purchase_id TEXT     NOT NULL,
+	channel     TEXT     NOT NULL,
+	username    TEXT     NOT NULL,
+	step        TEXT     NOT NULL,
+	amount      INTEGER  NOT NULL DEFAULT 0,
+	error       TEXT     NOT NULL DEFAULT '',
+	created_at  DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS ticket_purchases_purchase_id ON ticket_purchases (purchase_id);
@@ -0,0 +1,8 @@
+-- Twitch giveaway per channel. See the mysql migration of the same version for details.
+
+CREATE TABLE IF NOT EXISTS twitch_giveaways (
+	channel    TEXT     NOT NULL PRIMARY KEY,
+	prefix     TEXT     NOT NULL,
+	open       BOOLEAN  NOT NULL DEFAULT TRUE,
+	started_at DATETIME NOT NULL
+);
@@ -0,0 +1,50 @@
+-- Normalized giveaways. See the mysql migration of the same version for details.
+
+CREATE TABLE IF NOT EXISTS giveaways (
+	id         INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+	name       TEXT     NOT NULL UNIQUE,
+	created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS giveaway_entries (
+	giveaway_id INTEGER  NOT NULL REFERENCES giveaways (id),
+	user_id     TEXT     NOT NULL,
+	platform    TEXT     NOT NULL,
+	weight      INTEGER  NOT NULL DEFAULT 0,
+	last_entry  DATETIME NULL,
+	PRIMARY KEY (giveaway_id, platform, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS giveaway_entry_events (
+	id          INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+	giveaway_id INTEGER  NOT NULL,
+	user_id     TEXT     NOT NULL,
+	platform    TEXT     NOT NULL,
+	delta       INTEGER  NOT NULL,
+	reason      TEXT     NOT NULL,
+	created_at  DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS giveaway_entry_events_entry ON giveaway_entry_events (giveaway_id, platform, user_id);
+
+INSERT INTO giveaways (name,created_at)
+SELECT DISTINCT
+	CASE WHEN last_entry_id = '' THEN 'legacy' ELSE SUBSTR(last_entry_id, 1, LENGTH(last_entry_id) - 11) END,
+	CURRENT_TIMESTAMP
+FROM giveaway;
+
+INSERT INTO giveaway_entries (giveaway_id,user_id,platform,weight,last_entry)
+SELECT
+	g.id,
+	o.id,
+	CASE WHEN o.id GLOB '*[^0-9]*' THEN 'twitch' ELSE 'discord' END,
+	o.weight,
+	CASE WHEN o.last_entry_id = '' THEN NULL ELSE SUBSTR(o.last_entry_id, -10) || ' 00:00:00' END
+FROM giveaway o
+JOIN giveaways g ON g.name = CASE WHEN o.last_entry_id = '' THEN 'legacy' ELSE SUBSTR(o.last_entry_id, 1, LENGTH(o.last_entry_id) - 11) END;
+
+INSERT INTO giveaway_entry_events (giveaway_id,user_id,platform,delta,reason,created_at)
+SELECT giveaway_id, user_id, platform, weight, 'migrated', CURRENT_TIMESTAMP
+FROM giveaway_entries;
+
+ALTER TABLE giveaway RENAME TO giveaway_legacy;
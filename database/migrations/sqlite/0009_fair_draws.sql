@@ -0,0 +1,15 @@
+-- Commit-reveal draws. See the mysql migration of the same version for details.
+
+ALTER TABLE giveaways ADD COLUMN seed TEXT NOT NULL DEFAULT '';
+ALTER TABLE giveaways ADD COLUMN commitment TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS giveaway_draws (
+	id            INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+	giveaway_id   INTEGER  NOT NULL REFERENCES giveaways (id),
+	seed          TEXT     NOT NULL,
+	commitment    TEXT     NOT NULL,
+	entries       TEXT     NOT NULL,
+	winners       TEXT     NOT NULL,
+	total_tickets INTEGER  NOT NULL,
+	created_at    DATETIME NOT NULL
+);
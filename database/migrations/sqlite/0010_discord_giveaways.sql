@@ -0,0 +1,18 @@
+-- Giveaways hosted in a Discord channel. See the mysql migration of the same version for details.
+
+CREATE TABLE IF NOT EXISTS discord_giveaways (
+	giveaway_id INTEGER  NOT NULL PRIMARY KEY REFERENCES giveaways (id),
+	guild_id    INTEGER  NOT NULL,
+	channel_id  INTEGER  NOT NULL,
+	message_id  INTEGER  NOT NULL DEFAULT 0,
+	host_id     INTEGER  NOT NULL,
+	prize       TEXT     NOT NULL,
+	winners     INTEGER  NOT NULL,
+	role_id     INTEGER  NOT NULL DEFAULT 0,
+	ends_at     DATETIME NOT NULL,
+	ended       BOOLEAN  NOT NULL DEFAULT FALSE,
+	draw_id     INTEGER  NULL,
+	announced   BOOLEAN  NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS discord_giveaways_due ON discord_giveaways (ended, ends_at);
@@ -0,0 +1,28 @@
+-- Native Twitch loyalty points. See the mysql migration of the same version for details.
+
+CREATE TABLE IF NOT EXISTS twitch_points (
+	channel   TEXT     NOT NULL,
+	username  TEXT     NOT NULL,
+	points    INTEGER  NOT NULL DEFAULT 0,
+	last_chat DATETIME NULL,
+	PRIMARY KEY (channel, username)
+);
+
+CREATE TABLE IF NOT EXISTS twitch_points_ledger (
+	id         INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+	channel    TEXT     NOT NULL,
+	username   TEXT     NOT NULL,
+	amount     INTEGER  NOT NULL,
+	reason     TEXT     NOT NULL,
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS twitch_points_ledger_user ON twitch_points_ledger (channel, username);
+
+CREATE TABLE IF NOT EXISTS twitch_viewers (
+	channel     TEXT     NOT NULL,
+	username    TEXT     NOT NULL,
+	joined_at   DATETIME NOT NULL,
+	rewarded_at DATETIME NOT NULL,
+	PRIMARY KEY (channel, username)
+);
@@ -0,0 +1,21 @@
+-- Linked Discord and Twitch accounts. See the mysql migration of the same version for details.
+
+CREATE TABLE IF NOT EXISTS twitch_users (
+	id         TEXT     NOT NULL PRIMARY KEY,
+	login      TEXT     NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS twitch_users_login ON twitch_users (login);
+
+CREATE TABLE IF NOT EXISTS account_links (
+	discord_id TEXT     NOT NULL PRIMARY KEY,
+	twitch_id  TEXT     NOT NULL UNIQUE,
+	linked_at  DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS account_link_codes (
+	code       TEXT     NOT NULL PRIMARY KEY,
+	discord_id TEXT     NOT NULL UNIQUE,
+	expires_at DATETIME NOT NULL
+);
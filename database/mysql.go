@@ -0,0 +1,51 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	// mysql driver used for database
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// MySQLConfig is the connection data for a MySQL database.
+type MySQLConfig struct {
+	User     string `mapstructure:"user"`
+	Password string `mapstructure:"password"`
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Database string `mapstructure:"database"`
+}
+
+// String implements fmt.Stringer. It omits the password.
+func (c MySQLConfig) String() string {
+	return fmt.Sprintf("user='%s', host:port='%s:%d', database='%s'", c.User, c.Host, c.Port, c.Database)
+}
+
+// NewMySQL opens a new MySQL Store with the given connection data.
+func NewMySQL(config MySQLConfig) (Store, error) {
+	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true", config.User, config.Password, config.Host, config.Port, config.Database)
+
+	db, err := sql.Open("mysql", dataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("open mysql connection: %v", err)
+	}
+	db.SetConnMaxLifetime(3 * time.Minute)
+
+	return sqlStore{DB: db, driver: DriverMySQL}, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// ErrMaxTickets is returned by TicketPurchase.AddTicket when the user already has the maximum
+// number of tickets.
+var ErrMaxTickets = errors.New("maximum number of tickets reached")
+
+// PurchaseStep is a single step of a ticket purchase as recorded in the ledger
+type PurchaseStep string
+
+const (
+	// PurchaseStarted is recorded before anything else happens
+	PurchaseStarted PurchaseStep = "started"
+	// PurchasePointsDeducted is recorded after the cost was taken from the users points
+	PurchasePointsDeducted PurchaseStep = "points_deducted"
+	// PurchaseDeductFailed is recorded when the points could not be taken. The purchase ends here.
+	PurchaseDeductFailed PurchaseStep = "deduct_failed"
+	// PurchaseTicketAdded is recorded in the same transaction as the ticket itself. A purchase with
+	// this step is complete.
+	PurchaseTicketAdded PurchaseStep = "ticket_added"
+	// PurchaseTicketFailed is recorded when the ticket could not be written after the points were
+	// deducted
+	PurchaseTicketFailed PurchaseStep = "ticket_failed"
+	// PurchaseRefunded is recorded after the points of a failed purchase were given back
+	PurchaseRefunded PurchaseStep = "refunded"
+	// PurchaseRefundFailed is recorded when the refund failed too. These purchases need manual
+	// attention.
+	PurchaseRefundFailed PurchaseStep = "refund_failed"
+)
+
+// maxLedgerError is the maximum length of an error message stored in the ledger
+const maxLedgerError = 512
+
+// TicketPurchase is a running purchase of a giveaway ticket. Each step is recorded in the
+// 'ticket_purchases' ledger table under the same ID.
+type TicketPurchase struct {
+	ID      string
+	Channel string
+	User    string
+	Cost    int
+}
+
+// StartTicketPurchase starts a new purchase of a ticket for cost points by user in channel and
+// records it in the ledger.
+func StartTicketPurchase(channel, user string, cost int) (*TicketPurchase, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	p := &TicketPurchase{
+		ID:      hex.EncodeToString(id),
+		Channel: channel,
+		User:    user,
+		Cost:    cost,
+	}
+	return p, p.Record(PurchaseStarted, 0, nil)
+}
+
+// StartTicketRedemption is like StartTicketPurchase, but for a ticket user redeemed in a store,
+// e.g. the loyalty store of StreamElements. The ID of the redemption is used as the purchase ID, so
+// GetPurchaseStep tells whether the redemption was handled already.
+func StartTicketRedemption(redemptionID, channel, user string, cost int) (*TicketPurchase, error) {
+	p := &TicketPurchase{
+		ID:      redemptionID,
+		Channel: channel,
+		User:    user,
+		Cost:    cost,
+	}
+	return p, p.Record(PurchaseStarted, 0, nil)
+}
+
+// GetPurchaseStep returns the last recorded step of the purchase with the given ID. ok is false if
+// there is no such purchase.
+func GetPurchaseStep(purchaseID string) (step PurchaseStep, ok bool, err error) {
+	err = QueryRow("SELECT step FROM ticket_purchases WHERE purchase_id=? ORDER BY id DESC LIMIT 1", purchaseID).Scan(&step)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return step, err == nil, err
+}
+
+// Record adds step to the ledger. amount is the change of the users points in this step and err
+// the reason of a failed step, if any.
+func (p *TicketPurchase) Record(step PurchaseStep, amount int, err error) error {
+	return p.record(db, step, amount, err)
+}
+
+func (p *TicketPurchase) record(q Querier, step PurchaseStep, amount int, err error) error {
+	var errString string
+	if err != nil {
+		errString = err.Error()
+		if len(errString) > maxLedgerError {
+			errString = errString[:maxLedgerError]
+		}
+	}
+	_, err = q.Exec("INSERT INTO ticket_purchases (purchase_id,channel,username,step,amount,error,created_at) VALUES (?,?,?,?,?,?,?)",
+		p.ID, p.Channel, p.User, string(step), amount, errString, time.Now().UTC().Truncate(time.Second))
+	return err
+}
+
+// AddTicket adds one ticket to the users entry in the giveaway with the given name and records
+// PurchaseTicketAdded in a single transaction. If the user would have more than maxTickets afterwards, nothing is
+// changed and ErrMaxTickets is returned.
+func (p *TicketPurchase) AddTicket(name string, maxTickets int) (GiveawayEntry, error) {
+	tx, err := Begin()
+	if err != nil {
+		return GiveawayEntry{}, err
+	}
+	defer tx.Rollback()
+
+	entry, err := addGiveawayWeight(tx, name, GiveawayPlatformTwitch, p.User, 1, time.Now())
+	if err != nil {
+		return GiveawayEntry{}, err
+	}
+	if entry.Weight > maxTickets {
+		return GiveawayEntry{}, ErrMaxTickets
+	}
+	if err = p.record(tx, PurchaseTicketAdded, 0, nil); err != nil {
+		return GiveawayEntry{}, err
+	}
+	return entry, tx.Commit()
+}
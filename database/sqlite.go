@@ -0,0 +1,52 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	// pure go sqlite driver, so the bot can run without cgo and without a database server
+	_ "modernc.org/sqlite"
+)
+
+// NewSQLite opens a new embedded SQLite Store. path is the database file to use, it is created if
+// it doesn't exist. Use ":memory:" for a temporary database that lives as long as the Store, e.g.
+// in tests.
+func NewSQLite(path string) (Store, error) {
+	if path == "" {
+		return nil, fmt.Errorf("sqlite path cannot be empty")
+	}
+
+	dataSourceName := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	if path != ":memory:" {
+		dataSourceName += "&_pragma=journal_mode(WAL)"
+	}
+
+	db, err := sql.Open("sqlite", dataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite database: %v", err)
+	}
+
+	if path == ":memory:" {
+		// An in-memory database only exists for a single connection, so it must not be closed or
+		// replaced by the pool.
+		db.SetMaxOpenConns(1)
+		db.SetConnMaxLifetime(0)
+		db.SetConnMaxIdleTime(0)
+	}
+
+	return sqlStore{DB: db, driver: DriverSQLite}, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"database/sql"
+)
+
+const (
+	// DriverMySQL is the name of the MySQL storage backend
+	DriverMySQL = "mysql"
+	// DriverSQLite is the name of the embedded SQLite storage backend
+	DriverSQLite = "sqlite"
+)
+
+// Querier is the common interface of a Store and a running transaction (*sql.Tx). Functions that
+// should work both inside and outside of a transaction can accept a Querier.
+type Querier interface {
+	// Exec executes a query without returning any rows.
+	Exec(query string, args ...any) (sql.Result, error)
+	// Query executes a query that returns rows, typically a SELECT.
+	Query(query string, args ...any) (*sql.Rows, error)
+	// QueryRow executes a query that is expected to return at most one row.
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// Store is a storage backend for the bot. All modules talk to the database through a Store, so
+// the actual backend (MySQL or the embedded SQLite) can be chosen in the config.
+//
+// Queries are written with '?' placeholders, which are understood by all backends. If a query
+// needs backend specific syntax, check Driver() first.
+type Store interface {
+	Querier
+
+	// Driver returns the name of the backend, i.e. DriverMySQL or DriverSQLite.
+	Driver() string
+	// Begin starts a new transaction.
+	Begin() (*sql.Tx, error)
+	// Ping verifies a connection to the database is still alive, establishing a connection if
+	// necessary.
+	Ping() error
+	// Close closes the database and prevents new queries from starting.
+	Close() error
+}
+
+// sqlStore implements Store on top of a *sql.DB. The actual backends embed it and only differ in
+// how the connection is opened.
+type sqlStore struct {
+	*sql.DB
+	driver string
+}
+
+func (s sqlStore) Driver() string {
+	return s.driver
+}
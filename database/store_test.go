@@ -0,0 +1,123 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func newTestStore(t *testing.T) Store {
+	t.Helper()
+	s, err := NewSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// newTestDatabase returns a new migrated in-memory store, which is used as the database for the
+// rest of the test.
+func newTestDatabase(t *testing.T) Store {
+	t.Helper()
+	s := newTestStore(t)
+	if err := Migrate(s); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	useTestStore(t, s)
+	return s
+}
+
+// useTestStore sets s as the database for the rest of the test.
+func useTestStore(t *testing.T, s Store) {
+	old := db
+	SetStore(s)
+	t.Cleanup(func() { SetStore(old) })
+}
+
+func TestSQLiteStore(t *testing.T) {
+	s := newTestStore(t)
+	if s.Driver() != DriverSQLite {
+		t.Errorf("Driver() = %s, want %s", s.Driver(), DriverSQLite)
+	}
+	if err := s.Ping(); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	_, err := s.Exec("CREATE TABLE birthdays (id BIGINT UNSIGNED PRIMARY KEY, day INT, month INT, year INT, visible BOOLEAN)")
+	if err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	// queries in the same style as the modules use them
+	_, err = s.Exec("INSERT INTO birthdays(id,day,month,year,visible) VALUES(?,?,?,?,?);", uint64(1234567890123456789), 24, 12, 2000, true)
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	var exists bool
+	err = s.QueryRow("SELECT EXISTS(SELECT id FROM birthdays WHERE id=?)", uint64(1234567890123456789)).Scan(&exists)
+	if err != nil {
+		t.Fatalf("select exists: %v", err)
+	}
+	if !exists {
+		t.Error("expected birthday to exist")
+	}
+
+	var (
+		id      uint64
+		day     int
+		visible bool
+	)
+	err = s.QueryRow("SELECT id,day,visible FROM birthdays WHERE month=?", 12).Scan(&id, &day, &visible)
+	if err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if id != 1234567890123456789 || day != 24 || !visible {
+		t.Errorf("got id=%d day=%d visible=%v", id, day, visible)
+	}
+
+	err = s.QueryRow("SELECT id FROM birthdays WHERE month=?", 1).Scan(&id)
+	if err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestSQLiteStoreTransaction(t *testing.T) {
+	s := newTestStore(t)
+	if _, err := s.Exec("CREATE TABLE t (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := s.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if _, err = tx.Exec("INSERT INTO t (id) VALUES (?)", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	if err = s.QueryRow("SELECT COUNT(*) FROM t").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("expected rolled back insert, got %d rows", n)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// TwitchStream is a Twitch stream that was announced in Discord.
+type TwitchStream struct {
+	ID               string
+	BroadcasterID    string
+	BroadcasterLogin string
+	Title            string
+	Game             string
+	StartedAt        time.Time
+	// EndedAt is the zero time while the stream is live.
+	EndedAt     time.Time
+	PeakViewers int
+}
+
+// TwitchAnnouncement is a message in a guild that announced a TwitchStream.
+type TwitchAnnouncement struct {
+	StreamID  string
+	GuildID   string
+	ChannelID string
+	MessageID string
+}
+
+const twitchStreamColumns = "id,broadcaster_id,broadcaster_login,title,game,started_at,ended_at,peak_viewers"
+
+// AddTwitchStream saves a new stream. If a stream with the same ID exists already, nothing is
+// changed.
+func AddTwitchStream(s TwitchStream) error {
+	_, err := Exec(insertIgnore()+" INTO twitch_streams (id,broadcaster_id,broadcaster_login,title,game,started_at,peak_viewers) VALUES (?,?,?,?,?,?,?)",
+		s.ID, s.BroadcasterID, s.BroadcasterLogin, s.Title, s.Game, s.StartedAt.UTC(), s.PeakViewers)
+	return err
+}
+
+// GetLiveTwitchStreams returns all streams that didn't end yet.
+func GetLiveTwitchStreams() ([]TwitchStream, error) {
+	rows, err := Query("SELECT " + twitchStreamColumns + " FROM twitch_streams WHERE ended_at IS NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var streams []TwitchStream
+	for rows.Next() {
+		s, err := scanTwitchStream(rows)
+		if err != nil {
+			return nil, err
+		}
+		streams = append(streams, s)
+	}
+	return streams, rows.Err()
+}
+
+// GetLiveTwitchStream returns the latest stream of the given broadcaster that didn't end yet. ok
+// is false if there is none.
+func GetLiveTwitchStream(broadcasterID string) (s TwitchStream, ok bool, err error) {
+	row := QueryRow("SELECT "+twitchStreamColumns+" FROM twitch_streams WHERE broadcaster_id=? AND ended_at IS NULL ORDER BY started_at DESC LIMIT 1", broadcasterID)
+	s, err = scanTwitchStream(row)
+	if err == sql.ErrNoRows {
+		return s, false, nil
+	}
+	return s, err == nil, err
+}
+
+func scanTwitchStream(row interface{ Scan(...any) error }) (s TwitchStream, err error) {
+	var endedAt sql.NullTime
+	err = row.Scan(&s.ID, &s.BroadcasterID, &s.BroadcasterLogin, &s.Title, &s.Game, &s.StartedAt, &endedAt, &s.PeakViewers)
+	s.EndedAt = endedAt.Time
+	return s, err
+}
+
+// UpdateTwitchStream updates the title and game of a stream and raises its peak viewer count, if
+// viewers is higher.
+func UpdateTwitchStream(id, title, game string, viewers int) error {
+	_, err := Exec("UPDATE twitch_streams SET title=?,game=?,peak_viewers=CASE WHEN peak_viewers<? THEN ? ELSE peak_viewers END WHERE id=?",
+		title, game, viewers, viewers, id)
+	return err
+}
+
+// EndTwitchStream marks the stream as ended at t.
+func EndTwitchStream(id string, t time.Time) error {
+	_, err := Exec("UPDATE twitch_streams SET ended_at=? WHERE id=?", t.UTC(), id)
+	return err
+}
+
+// AddTwitchAnnouncement saves the message of a stream announcement.
+func AddTwitchAnnouncement(a TwitchAnnouncement) error {
+	_, err := Exec("INSERT INTO twitch_announcements (stream_id,guild_id,channel_id,message_id) VALUES (?,?,?,?)",
+		a.StreamID, a.GuildID, a.ChannelID, a.MessageID)
+	return err
+}
+
+// GetTwitchAnnouncements returns all announcement messages of the given stream.
+func GetTwitchAnnouncements(streamID string) ([]TwitchAnnouncement, error) {
+	rows, err := Query("SELECT guild_id,channel_id,message_id FROM twitch_announcements WHERE stream_id=?", streamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var announcements []TwitchAnnouncement
+	for rows.Next() {
+		var guildID, channelID, messageID uint64
+		if err = rows.Scan(&guildID, &channelID, &messageID); err != nil {
+			return nil, err
+		}
+		announcements = append(announcements, TwitchAnnouncement{
+			StreamID:  streamID,
+			GuildID:   strconv.FormatUint(guildID, 10),
+			ChannelID: strconv.FormatUint(channelID, 10),
+			MessageID: strconv.FormatUint(messageID, 10),
+		})
+	}
+	return announcements, rows.Err()
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TwitchGiveaway is the giveaway of a Twitch channel.
+type TwitchGiveaway struct {
+	Channel string
+	// Prefix is the name of the giveaway holding the entries, see GetGiveaway.
+	Prefix string
+	// Open is true while users can buy tickets.
+	Open      bool
+	StartedAt time.Time
+}
+
+// GetTwitchGiveaway returns the latest giveaway of the given channel. ok is false if the channel
+// never started one.
+func GetTwitchGiveaway(channel string) (g TwitchGiveaway, ok bool, err error) {
+	g.Channel = channel
+	err = QueryRow("SELECT prefix,open,started_at FROM twitch_giveaways WHERE channel=?", channel).Scan(&g.Prefix, &g.Open, &g.StartedAt)
+	if err == sql.ErrNoRows {
+		return TwitchGiveaway{}, false, nil
+	} else if err != nil {
+		return TwitchGiveaway{}, false, err
+	}
+	return g, true, nil
+}
+
+// StartTwitchGiveaway opens a giveaway with the given prefix in channel. It replaces the previous
+// giveaway of the channel. Using the prefix of a previous giveaway continues it with its existing
+// entries.
+func StartTwitchGiveaway(channel, prefix string, now time.Time) error {
+	now = now.UTC().Truncate(time.Second)
+	_, err := Exec(insertIgnore()+" INTO twitch_giveaways (channel,prefix,open,started_at) VALUES (?,?,?,?)", channel, prefix, true, now)
+	if err != nil {
+		return err
+	}
+	_, err = Exec("UPDATE twitch_giveaways SET prefix=?,open=?,started_at=? WHERE channel=?", prefix, true, now, channel)
+	return err
+}
+
+// CloseTwitchGiveaway closes the giveaway of channel, so no more tickets can be bought. Its entries
+// are kept for drawing. ok is false if there was no open giveaway.
+func CloseTwitchGiveaway(channel string) (ok bool, err error) {
+	res, err := Exec("UPDATE twitch_giveaways SET open=? WHERE channel=? AND open=?", false, channel, true)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
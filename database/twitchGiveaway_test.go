@@ -0,0 +1,54 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTwitchGiveaway(t *testing.T) {
+	newTestDatabase(t)
+
+	if _, ok, err := GetTwitchGiveaway("foo"); err != nil || ok {
+		t.Fatalf("GetTwitchGiveaway() = _, %v, %v, want false, nil", ok, err)
+	}
+	if ok, err := CloseTwitchGiveaway("foo"); err != nil || ok {
+		t.Fatalf("CloseTwitchGiveaway() = %v, %v, want false, nil", ok, err)
+	}
+
+	start := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	for _, prefix := range []string{"first", "second"} {
+		if err := StartTwitchGiveaway("foo", prefix, start); err != nil {
+			t.Fatal(err)
+		}
+		want := TwitchGiveaway{Channel: "foo", Prefix: prefix, Open: true, StartedAt: start}
+		if g, ok, err := GetTwitchGiveaway("foo"); err != nil || !ok || g != want {
+			t.Errorf("GetTwitchGiveaway() = %+v, %v, %v, want %+v", g, ok, err, want)
+		}
+
+		if ok, err := CloseTwitchGiveaway("foo"); err != nil || !ok {
+			t.Errorf("CloseTwitchGiveaway() = %v, %v, want true, nil", ok, err)
+		}
+		if g, _, err := GetTwitchGiveaway("foo"); err != nil || g.Open || g.Prefix != prefix {
+			t.Errorf("GetTwitchGiveaway() after close = %+v, %v", g, err)
+		}
+	}
+
+	// other channels are independent
+	if _, ok, err := GetTwitchGiveaway("bar"); err != nil || ok {
+		t.Errorf("GetTwitchGiveaway(bar) = _, %v, %v, want false, nil", ok, err)
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrInsufficientPoints is returned when removing more native points than a user has.
+var ErrInsufficientPoints = errors.New("insufficient points")
+
+// TwitchPointsReason is the reason of a change of native Twitch points in the ledger
+type TwitchPointsReason string
+
+const (
+	// TwitchPointsChat is the reward for chatting
+	TwitchPointsChat TwitchPointsReason = "chat"
+	// TwitchPointsWatch is the reward for watching
+	TwitchPointsWatch TwitchPointsReason = "watch"
+	// TwitchPointsAdjust is any other change, e.g. buying a giveaway ticket
+	TwitchPointsAdjust TwitchPointsReason = "adjust"
+)
+
+// GetTwitchPoints returns the native points of user in channel. It is 0 if they never got any.
+func GetTwitchPoints(channel, user string) (points int, err error) {
+	err = QueryRow("SELECT points FROM twitch_points WHERE channel=? AND username=?", channel, user).Scan(&points)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return points, err
+}
+
+// TwitchPointsRank is the position of a user in the points leaderboard of a channel.
+type TwitchPointsRank struct {
+	Username string
+	Points   int
+	// Rank starts at 1. Users with the same points are ordered by name, so every rank is unique. A
+	// user without points has rank 0.
+	Rank int
+}
+
+// GetTwitchPointsTop returns the users with the most native points in channel, skipping the first
+// offset users.
+func GetTwitchPointsTop(channel string, limit, offset int) ([]TwitchPointsRank, error) {
+	rows, err := Query("SELECT username,points FROM twitch_points WHERE channel=? AND points>0 ORDER BY points DESC, username ASC LIMIT ? OFFSET ?",
+		channel, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var top []TwitchPointsRank
+	for rows.Next() {
+		r := TwitchPointsRank{Rank: offset + len(top) + 1}
+		if err = rows.Scan(&r.Username, &r.Points); err != nil {
+			return nil, err
+		}
+		top = append(top, r)
+	}
+	return top, rows.Err()
+}
+
+// GetTwitchPointsRank returns the native points and the rank of user in channel, see
+// GetTwitchPointsTop.
+func GetTwitchPointsRank(channel, user string) (r TwitchPointsRank, err error) {
+	r.Username = user
+	if r.Points, err = GetTwitchPoints(channel, user); err != nil || r.Points <= 0 {
+		return r, err
+	}
+	err = QueryRow("SELECT COUNT(*) FROM twitch_points WHERE channel=? AND (points>? OR (points=? AND username<?))",
+		channel, r.Points, r.Points, user).Scan(&r.Rank)
+	r.Rank++
+	return r, err
+}
+
+// AddTwitchPoints adds amount native points to user in channel and records the change with
+// reason in the ledger. A negative amount removes points. If the user doesn't have enough points,
+// nothing is changed and ErrInsufficientPoints is returned. It returns the new balance.
+func AddTwitchPoints(channel, user string, amount int, reason TwitchPointsReason) (balance int, err error) {
+	tx, err := Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(insertIgnore()+" INTO twitch_points (channel,username,points) VALUES (?,?,0)", channel, user); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec("UPDATE twitch_points SET points=points+? WHERE channel=? AND username=? AND points+?>=0", amount, channel, user, amount)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, ErrInsufficientPoints
+	}
+	if err = addTwitchPointsLedger(tx, channel, user, amount, reason, time.Now()); err != nil {
+		return 0, err
+	}
+	if err = tx.QueryRow("SELECT points FROM twitch_points WHERE channel=? AND username=?", channel, user).Scan(&balance); err != nil {
+		return 0, err
+	}
+	return balance, tx.Commit()
+}
+
+// AwardTwitchChatPoints gives amount native points to user in channel for chatting. Only one
+// message per cooldown is rewarded, so ok is false if user was already rewarded less than cooldown
+// before now.
+func AwardTwitchChatPoints(channel, user string, amount int, cooldown time.Duration, now time.Time) (ok bool, err error) {
+	now = now.UTC().Truncate(time.Second)
+	tx, err := Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(insertIgnore()+" INTO twitch_points (channel,username,points) VALUES (?,?,0)", channel, user); err != nil {
+		return false, err
+	}
+	res, err := tx.Exec("UPDATE twitch_points SET points=points+?, last_chat=? WHERE channel=? AND username=? AND (last_chat IS NULL OR last_chat<=?)",
+		amount, now, channel, user, now.Add(-cooldown))
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if err = addTwitchPointsLedger(tx, channel, user, amount, TwitchPointsChat, now); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// JoinTwitchViewer marks user as watching channel since now. They get watch points after each
+// full interval, see AwardTwitchWatchPoints.
+func JoinTwitchViewer(channel, user string, now time.Time) error {
+	now = now.UTC().Truncate(time.Second)
+	_, err := Exec(insertIgnore()+" INTO twitch_viewers (channel,username,joined_at,rewarded_at) VALUES (?,?,?,?)", channel, user, now, now)
+	return err
+}
+
+// PartTwitchViewer marks user as no longer watching channel.
+func PartTwitchViewer(channel, user string) error {
+	_, err := Exec("DELETE FROM twitch_viewers WHERE channel=? AND username=?", channel, user)
+	return err
+}
+
+// ClearTwitchViewers removes all viewers of channel, e.g. because the bot rejoined it and the
+// viewers are sent again.
+func ClearTwitchViewers(channel string) error {
+	_, err := Exec("DELETE FROM twitch_viewers WHERE channel=?", channel)
+	return err
+}
+
+// AwardTwitchWatchPoints gives amount native points to every viewer of channel that was last
+// rewarded at least interval before now. It returns the number of rewarded viewers.
+//
+// Points are only given while channel is live, i.e. it has a stream in twitch_streams that didn't
+// end yet. Otherwise the interval of all viewers restarts at now.
+func AwardTwitchWatchPoints(channel string, amount int, interval time.Duration, now time.Time) (n int, err error) {
+	now = now.UTC().Truncate(time.Second)
+	tx, err := Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var live bool
+	err = tx.QueryRow("SELECT EXISTS(SELECT id FROM twitch_streams WHERE broadcaster_login=? AND ended_at IS NULL)", channel).Scan(&live)
+	if err != nil {
+		return 0, err
+	}
+	if !live {
+		if _, err = tx.Exec("UPDATE twitch_viewers SET rewarded_at=? WHERE channel=?", now, channel); err != nil {
+			return 0, err
+		}
+		return 0, tx.Commit()
+	}
+
+	rows, err := tx.Query("SELECT username FROM twitch_viewers WHERE channel=? AND rewarded_at<=?", channel, now.Add(-interval))
+	if err != nil {
+		return 0, err
+	}
+	var users []string
+	for rows.Next() {
+		var user string
+		if err = rows.Scan(&user); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		users = append(users, user)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, user := range users {
+		if _, err = tx.Exec(insertIgnore()+" INTO twitch_points (channel,username,points) VALUES (?,?,0)", channel, user); err != nil {
+			return 0, err
+		}
+		if _, err = tx.Exec("UPDATE twitch_points SET points=points+? WHERE channel=? AND username=?", amount, channel, user); err != nil {
+			return 0, err
+		}
+		if _, err = tx.Exec("UPDATE twitch_viewers SET rewarded_at=? WHERE channel=? AND username=?", now, channel, user); err != nil {
+			return 0, err
+		}
+		if err = addTwitchPointsLedger(tx, channel, user, amount, TwitchPointsWatch, now); err != nil {
+			return 0, err
+		}
+	}
+	return len(users), tx.Commit()
+}
+
+// addTwitchPointsLedger records a change of the native points of user in channel.
+func addTwitchPointsLedger(q Querier, channel, user string, amount int, reason TwitchPointsReason, now time.Time) error {
+	_, err := q.Exec("INSERT INTO twitch_points_ledger (channel,username,amount,reason,created_at) VALUES (?,?,?,?,?)",
+		channel, user, amount, string(reason), now.UTC().Truncate(time.Second))
+	return err
+}
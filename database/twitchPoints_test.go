@@ -0,0 +1,148 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTwitchPoints(t *testing.T) {
+	newTestDatabase(t)
+
+	if points, err := GetTwitchPoints("chan", "foo"); err != nil || points != 0 {
+		t.Fatalf("GetTwitchPoints() = %d, %v, want 0, nil", points, err)
+	}
+	if _, err := AddTwitchPoints("chan", "foo", -1, TwitchPointsAdjust); !errors.Is(err, ErrInsufficientPoints) {
+		t.Errorf("AddTwitchPoints() without points error = %v, want %v", err, ErrInsufficientPoints)
+	}
+	if balance, err := AddTwitchPoints("chan", "foo", 100, TwitchPointsAdjust); err != nil || balance != 100 {
+		t.Errorf("AddTwitchPoints() = %d, %v, want 100, nil", balance, err)
+	}
+	if balance, err := AddTwitchPoints("chan", "foo", -30, TwitchPointsAdjust); err != nil || balance != 70 {
+		t.Errorf("AddTwitchPoints() = %d, %v, want 70, nil", balance, err)
+	}
+	if points, _ := GetTwitchPoints("other", "foo"); points != 0 {
+		t.Errorf("GetTwitchPoints() in other channel = %d, want 0", points)
+	}
+
+	now := time.Date(2024, 12, 24, 18, 0, 0, 0, time.UTC)
+	if ok, err := AwardTwitchChatPoints("chan", "foo", 5, time.Minute, now); err != nil || !ok {
+		t.Errorf("AwardTwitchChatPoints() = %v, %v, want true, nil", ok, err)
+	}
+	if ok, err := AwardTwitchChatPoints("chan", "foo", 5, time.Minute, now.Add(30*time.Second)); err != nil || ok {
+		t.Errorf("AwardTwitchChatPoints() in cooldown = %v, %v, want false, nil", ok, err)
+	}
+	if ok, err := AwardTwitchChatPoints("chan", "foo", 5, time.Minute, now.Add(time.Minute)); err != nil || !ok {
+		t.Errorf("AwardTwitchChatPoints() after cooldown = %v, %v, want true, nil", ok, err)
+	}
+	if points, _ := GetTwitchPoints("chan", "foo"); points != 80 {
+		t.Errorf("GetTwitchPoints() after chat = %d, want 80", points)
+	}
+
+	if err := AddTwitchStream(TwitchStream{ID: "1", BroadcasterID: "1", BroadcasterLogin: "chan", StartedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := JoinTwitchViewer("chan", "foo", now); err != nil {
+		t.Fatal(err)
+	}
+	if err := JoinTwitchViewer("chan", "bar", now.Add(5*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := AwardTwitchWatchPoints("chan", 10, 10*time.Minute, now.Add(10*time.Minute)); err != nil || n != 1 {
+		t.Errorf("AwardTwitchWatchPoints() = %d, %v, want 1, nil", n, err)
+	}
+	if n, err := AwardTwitchWatchPoints("chan", 10, 10*time.Minute, now.Add(15*time.Minute)); err != nil || n != 1 {
+		t.Errorf("AwardTwitchWatchPoints() = %d, %v, want 1, nil", n, err)
+	}
+	if err := PartTwitchViewer("chan", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := AwardTwitchWatchPoints("chan", 10, 10*time.Minute, now.Add(time.Hour)); err != nil || n != 1 {
+		t.Errorf("AwardTwitchWatchPoints() after part = %d, %v, want 1, nil", n, err)
+	}
+	if err := ClearTwitchViewers("chan"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := AwardTwitchWatchPoints("chan", 10, 10*time.Minute, now.Add(2*time.Hour)); err != nil || n != 0 {
+		t.Errorf("AwardTwitchWatchPoints() after clear = %d, %v, want 0, nil", n, err)
+	}
+
+	// offline channels don't give watch points and restart the interval
+	if err := JoinTwitchViewer("chan", "baz", now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := EndTwitchStream("1", now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := AwardTwitchWatchPoints("chan", 10, 10*time.Minute, now.Add(3*time.Hour)); err != nil || n != 0 {
+		t.Errorf("AwardTwitchWatchPoints() while offline = %d, %v, want 0, nil", n, err)
+	}
+	if err := AddTwitchStream(TwitchStream{ID: "2", BroadcasterID: "1", BroadcasterLogin: "chan", StartedAt: now.Add(3 * time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := AwardTwitchWatchPoints("chan", 10, 10*time.Minute, now.Add(3*time.Hour+5*time.Minute)); err != nil || n != 0 {
+		t.Errorf("AwardTwitchWatchPoints() right after going live = %d, %v, want 0, nil", n, err)
+	}
+	if n, err := AwardTwitchWatchPoints("chan", 10, 10*time.Minute, now.Add(3*time.Hour+10*time.Minute)); err != nil || n != 1 {
+		t.Errorf("AwardTwitchWatchPoints() while live = %d, %v, want 1, nil", n, err)
+	}
+
+	if points, _ := GetTwitchPoints("chan", "foo"); points != 90 {
+		t.Errorf("GetTwitchPoints(foo) = %d, want 90", points)
+	}
+	if points, _ := GetTwitchPoints("chan", "bar"); points != 20 {
+		t.Errorf("GetTwitchPoints(bar) = %d, want 20", points)
+	}
+	if points, _ := GetTwitchPoints("chan", "baz"); points != 10 {
+		t.Errorf("GetTwitchPoints(baz) = %d, want 10", points)
+	}
+}
+
+func TestTwitchPointsRank(t *testing.T) {
+	newTestDatabase(t)
+
+	for user, points := range map[string]int{"a": 10, "b": 30, "c": 10, "d": 5} {
+		if _, err := AddTwitchPoints("chan", user, points, TwitchPointsAdjust); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := AddTwitchPoints("other", "e", 100, TwitchPointsAdjust); err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := GetTwitchPointsTop("chan", 2, 1)
+	want := []TwitchPointsRank{{"a", 10, 2}, {"c", 10, 3}}
+	if err != nil || !reflect.DeepEqual(top, want) {
+		t.Errorf("GetTwitchPointsTop() = %v, %v, want %v", top, err, want)
+	}
+
+	tests := []struct {
+		user string
+		want TwitchPointsRank
+	}{
+		{"b", TwitchPointsRank{"b", 30, 1}},
+		{"c", TwitchPointsRank{"c", 10, 3}},
+		{"d", TwitchPointsRank{"d", 5, 4}},
+		{"unknown", TwitchPointsRank{"unknown", 0, 0}},
+	}
+	for _, tt := range tests {
+		if got, err := GetTwitchPointsRank("chan", tt.user); err != nil || got != tt.want {
+			t.Errorf("GetTwitchPointsRank(%s) = %v, %v, want %v", tt.user, got, err, tt.want)
+		}
+	}
+}
@@ -15,16 +15,24 @@
 package command
 
 import (
+	"cake4everybot/database"
 	"cake4everybot/modules/adventcalendar"
 	"cake4everybot/modules/birthday"
+	"cake4everybot/modules/giveaway"
 	"cake4everybot/modules/info"
+	"cake4everybot/modules/link"
+	"cake4everybot/modules/points"
 	"cake4everybot/modules/secretsanta"
+	"cake4everybot/modules/settings"
 	"cake4everybot/util"
 	"fmt"
 	"log"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/spf13/viper"
 )
 
 // Command is an interface wrapper for all commands. Including chat-comamnds (slash-commands),
@@ -53,18 +61,26 @@ type Command interface {
 // one.
 var CommandMap map[string]Command
 
+var (
+	// commandsList is the list of all commands, built once by initCommands
+	commandsList []Command
+	initOnce     sync.Once
+
+	// registeredGuilds holds all guilds the commands are currently registered in
+	registeredGuilds = make(map[string]bool)
+	registerMutex    sync.Mutex
+)
+
 func init() {
 	CommandMap = make(map[string]Command)
 }
 
-// Register registers all application commands
-func Register(s *discordgo.Session, guildID string) error {
-
+// initCommands builds the list of commands and fills CommandMap.
+func initCommands() {
 	// This is the list of commands to use. Add a command via simply appending the struct (which
 	// must implement the Command interface) to the list, i.e.:
 	//
 	// commandsList = append(commandsList, command.MyCommand{})
-	var commandsList []Command
 
 	// chat (slash) commands
 	commandsList = append(commandsList, &birthday.Chat{})
@@ -72,10 +88,80 @@ func Register(s *discordgo.Session, guildID string) error {
 	commandsList = append(commandsList, &adventcalendar.Chat{})
 	commandsList = append(commandsList, &secretsanta.Chat{})
 	commandsList = append(commandsList, &secretsanta.MsgCmd{})
+	commandsList = append(commandsList, &settings.Chat{})
+	commandsList = append(commandsList, &giveaway.Chat{})
+	commandsList = append(commandsList, &points.Chat{})
+	commandsList = append(commandsList, &link.Chat{})
 	// messsage commands
 	// user commands
 	commandsList = append(commandsList, &birthday.UserShow{})
 
+	for _, cmd := range commandsList {
+		CommandMap[cmd.AppCmd().Name] = cmd
+	}
+}
+
+// IsGlobal returns whether the commands are configured to be registered globally instead of per
+// guild.
+func IsGlobal() bool {
+	return viper.GetString("discord.command_scope") == "global"
+}
+
+// Register registers all application commands. Depending on the 'discord.command_scope' config
+// they are registered globally or in every guild of the guilds table (and the configured
+// 'discord.guildID', if any). Commands left over from the other scope are removed.
+func Register(s *discordgo.Session) error {
+	guildIDs, err := database.GetGuildIDs()
+	if err != nil {
+		return fmt.Errorf("get guilds from database: %v", err)
+	}
+
+	if IsGlobal() {
+		if err = RegisterGuild(s, ""); err != nil {
+			return err
+		}
+		// remove the commands of a previous per guild registration
+		for _, guildID := range guildIDs {
+			removeUnusedCommands(s, guildID, nil)
+		}
+		return nil
+	}
+	// remove the commands of a previous global registration
+	removeUnusedCommands(s, "", nil)
+
+	if guildID := viper.GetString("discord.guildID"); guildID != "" && guildID != "0" && !slices.Contains(guildIDs, guildID) {
+		if err = database.AddGuild(guildID); err != nil {
+			return fmt.Errorf("add configured guild '%s': %v", guildID, err)
+		}
+		guildIDs = append(guildIDs, guildID)
+	}
+
+	var failed []string
+	for _, guildID := range guildIDs {
+		if err = RegisterGuild(s, guildID); err != nil {
+			log.Printf("Error registering commands in guild '%s': %v\n", guildID, err)
+			failed = append(failed, guildID)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to register commands in %d/%d guild(s): [%s]", len(failed), len(guildIDs), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// RegisterGuild registers all application commands in the given guild. An empty guildID registers
+// them globally. Commands that were registered before, but are no longer in use, are removed.
+//
+// Calling it again for a guild that is already registered does nothing.
+func RegisterGuild(s *discordgo.Session, guildID string) error {
+	initOnce.Do(initCommands)
+
+	registerMutex.Lock()
+	defer registerMutex.Unlock()
+	if registeredGuilds[guildID] {
+		return nil
+	}
+
 	// early return when there're no commands to add, and remove all previously registered commands
 	if len(commandsList) == 0 {
 		removeUnusedCommands(s, guildID, nil)
@@ -86,33 +172,42 @@ func Register(s *discordgo.Session, guildID string) error {
 	appCommandsList := make([]*discordgo.ApplicationCommand, 0, len(commandsList))
 	for _, cmd := range commandsList {
 		appCommandsList = append(appCommandsList, cmd.AppCmd())
-		CommandMap[cmd.AppCmd().Name] = cmd
 	}
 	commandNames := make([]string, 0, len(CommandMap))
 	for k := range CommandMap {
 		commandNames = append(commandNames, k)
 	}
 
-	log.Printf("Adding used commands: [%s]...\n", strings.Join(commandNames, ", "))
+	log.Printf("Adding used commands to guild '%s': [%s]...\n", guildID, strings.Join(commandNames, ", "))
 	createdCommands, err := s.ApplicationCommandBulkOverwrite(s.State.User.ID, guildID, appCommandsList)
 	if err != nil {
 		return fmt.Errorf("failed on bulk overwrite commands: %v", err)
 	}
 
+	// set the utility map. The IDs differ for every guild, so a command only keeps its ID when it
+	// is registered globally.
+	cmdIDMap := make(map[string]string)
 	for _, cmd := range createdCommands {
-		CommandMap[cmd.Name].SetID(cmd.ID)
+		if guildID == "" {
+			CommandMap[cmd.Name].SetID(cmd.ID)
+		}
+		cmdIDMap[cmd.Name] = cmd.ID
 	}
+	util.SetCommandMap(guildID, cmdIDMap)
 
 	removeUnusedCommands(s, guildID, createdCommands)
 
-	// set the utility map
-	cmdIDMap := make(map[string]string)
-	for k, v := range CommandMap {
-		cmdIDMap[k] = v.GetID()
-	}
-	util.SetCommandMap(cmdIDMap)
+	registeredGuilds[guildID] = true
+	return nil
+}
 
-	return err
+// UnregisterGuild forgets the registered commands of the given guild, e.g. after the bot was
+// removed from it. Discord already deletes the commands of a guild the bot is no longer part of.
+func UnregisterGuild(guildID string) {
+	registerMutex.Lock()
+	defer registerMutex.Unlock()
+	delete(registeredGuilds, guildID)
+	util.SetCommandMap(guildID, nil)
 }
 
 func removeUnusedCommands(s *discordgo.Session, guildID string, createdCommands []*discordgo.ApplicationCommand) {
@@ -2,7 +2,9 @@ package component
 
 import (
 	"cake4everybot/modules/adventcalendar"
+	"cake4everybot/modules/giveaway"
 	"cake4everybot/modules/secretsanta"
+	"cake4everybot/modules/settings"
 	"log"
 
 	"github.com/bwmarrin/discordgo"
@@ -32,7 +34,9 @@ func Register() {
 	var componentList []Component
 
 	componentList = append(componentList, adventcalendar.Component{})
+	componentList = append(componentList, giveaway.Component{})
 	componentList = append(componentList, secretsanta.Component{})
+	componentList = append(componentList, settings.Component{})
 
 	if len(componentList) == 0 {
 		return
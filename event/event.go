@@ -19,7 +19,10 @@ import (
 	"cake4everybot/event/component"
 	"cake4everybot/event/modal"
 	"cake4everybot/event/twitch"
+	"context"
+	"fmt"
 	logger "log"
+	"sync"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/kesuaheli/twitchgo"
@@ -27,9 +30,12 @@ import (
 
 var log = *logger.New(logger.Writer(), "[Events] ", logger.LstdFlags|logger.Lmsgprefix)
 
+// running tracks the background goroutines of the scheduled triggers
+var running sync.WaitGroup
+
 // PostRegister registers all events, like commands after the bots are started.
-func PostRegister(dc *discordgo.Session, t *twitchgo.Twitch, guildID string) error {
-	err := command.Register(dc, guildID)
+func PostRegister(dc *discordgo.Session, t *twitchgo.Twitch) error {
+	err := command.Register(dc)
 	if err != nil {
 		return err
 	}
@@ -41,16 +47,50 @@ func PostRegister(dc *discordgo.Session, t *twitchgo.Twitch, guildID string) err
 	return nil
 }
 
-// AddListeners adds all event handlers to the given bots.
-func AddListeners(dc *discordgo.Session, t *twitchgo.Twitch, webChan chan struct{}) {
+// AddListeners adds all event handlers to the given bots. Background tasks like the scheduled
+// triggers stop when ctx is done.
+func AddListeners(ctx context.Context, dc *discordgo.Session, t *twitchgo.Twitch, webChan chan struct{}) {
 	dc.AddHandler(handleInteractionCreate)
 	addVoiceStateListeners(dc)
+	addGuildListeners(dc)
 
 	t.OnChannelCommandMessage("ticket", true, twitch.HandleCmdJoin)
 	t.OnChannelCommandMessage("tickets", true, twitch.HandleCmdTickets)
 	t.OnChannelCommandMessage("draw", true, twitch.HandleCmdDraw)
+	t.OnChannelCommandMessage("giveaway", true, twitch.HandleCmdGiveaway)
+	t.OnChannelCommandMessage("verify", true, twitch.HandleCmdVerify)
+	t.OnChannelCommandMessage("prize", true, twitch.HandleCmdPrize)
+	t.OnChannelCommandMessage("claim", true, twitch.HandleCmdClaim)
+	t.OnChannelCommandMessage("top", true, twitch.HandleCmdTop)
+	t.OnChannelCommandMessage("birthday", true, twitch.HandleCmdBirthday)
 	t.OnChannelMessage(twitch.MessageHandler)
+	t.OnChannelJoin(twitch.HandleJoin)
+	t.OnChannelLeave(twitch.HandlePart)
+	// handles the link command too, as it needs the tags of the message
+	t.OnAny(twitch.HandleAny)
 
 	addYouTubeListeners(dc)
-	addScheduledTriggers(dc, t, webChan)
+	addTwitchEventListeners(ctx, dc, webChan)
+	addScheduledTriggers(ctx, dc, t, webChan)
+}
+
+// Shutdown waits for the scheduled triggers to stop and for the running Twitch handlers to finish
+// their writes. The context passed to AddListeners must be done already, otherwise it blocks until
+// ctx is done.
+func Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for scheduled triggers: %v", ctx.Err())
+	}
+
+	if err := twitch.Shutdown(ctx); err != nil {
+		return fmt.Errorf("waiting for twitch handlers: %v", err)
+	}
+	return nil
 }
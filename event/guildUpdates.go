@@ -0,0 +1,58 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package event
+
+import (
+	"cake4everybot/database"
+	"cake4everybot/event/command"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func addGuildListeners(s *discordgo.Session) {
+	s.AddHandler(handleGuildCreate)
+	s.AddHandler(handleGuildDelete)
+}
+
+// handleGuildCreate is called on startup for every guild the bot is in and whenever the bot joins
+// a new guild. It makes sure the guild has a settings row and its commands are registered.
+func handleGuildCreate(s *discordgo.Session, e *discordgo.GuildCreate) {
+	if e.Unavailable {
+		return
+	}
+	if err := database.AddGuild(e.ID); err != nil {
+		log.Printf("Error on adding guild '%s' (%s) to database: %v\n", e.Name, e.ID, err)
+		return
+	}
+	if command.IsGlobal() {
+		return
+	}
+	if err := command.RegisterGuild(s, e.ID); err != nil {
+		log.Printf("Error on registering commands in guild '%s' (%s): %v\n", e.Name, e.ID, err)
+	}
+}
+
+// handleGuildDelete is called when the bot left or was removed from a guild. If the guild is only
+// unavailable because of an outage nothing is changed.
+func handleGuildDelete(s *discordgo.Session, e *discordgo.GuildDelete) {
+	if e.Unavailable {
+		return
+	}
+	log.Printf("Removed from guild '%s', deleting its settings\n", e.ID)
+	if err := database.RemoveGuild(e.ID); err != nil {
+		log.Printf("Error on removing guild '%s' from database: %v\n", e.ID, err)
+	}
+	command.UnregisterGuild(e.ID)
+}
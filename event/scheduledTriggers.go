@@ -15,62 +15,51 @@
 package event
 
 import (
-	"cake4everybot/modules/adventcalendar"
-	"cake4everybot/modules/birthday"
+	"cake4everybot/event/scheduler"
+	"cake4everybot/util"
 	webYT "cake4everybot/webserver/youtube"
 
+	"context"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/kesuaheli/twitchgo"
-	"github.com/spf13/viper"
 )
 
-func addScheduledTriggers(dc *discordgo.Session, t *twitchgo.Twitch, webChan chan struct{}) {
-	go scheduleFunction(dc, t, 0, 0,
-		adventcalendar.Midnight,
-	)
+func addScheduledTriggers(ctx context.Context, dc *discordgo.Session, t *twitchgo.Twitch, webChan chan struct{}) {
+	// The jobs itself are registered by the modules, see scheduler.Register
+	goTracked(func() {
+		// give the bots some time to connect
+		if !util.Sleep(ctx, time.Second*5) {
+			return
+		}
+		scheduler.Run(ctx, dc, t)
+	})
 
-	go scheduleFunction(dc, t, viper.GetInt("event.morning_hour"), viper.GetInt("event.morning_minute"),
-		birthday.Check,
-		adventcalendar.Post,
-	)
+	goTracked(func() { refreshYoutube(ctx, webChan) })
+}
 
-	go refreshYoutube(webChan)
+// goTracked runs f in a new goroutine. Shutdown waits for it to return.
+func goTracked(f func()) {
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		f()
+	}()
 }
 
-func scheduleFunction(dc *discordgo.Session, t *twitchgo.Twitch, hour, min int, callbacks ...interface{}) {
-	if len(callbacks) == 0 {
+func refreshYoutube(ctx context.Context, webChan chan struct{}) {
+	select {
+	case <-webChan:
+	case <-ctx.Done():
 		return
 	}
-	log.Printf("scheduled %d function(s) for %2d:%02d!", len(callbacks), hour, min)
-	time.Sleep(time.Second * 5)
-	for {
-		now := time.Now()
-
-		nextRun := time.Date(now.Year(), now.Month(), now.Day(), hour, min, 0, 0, now.Location())
-		if nextRun.Before(now) {
-			nextRun = nextRun.Add(time.Hour * 24)
-		}
-		time.Sleep(nextRun.Sub(now))
-
-		for _, c := range callbacks {
-			switch f := c.(type) {
-			case func(*discordgo.Session):
-				f(dc)
-			case func(*twitchgo.Twitch):
-				f(t)
-			}
-		}
-	}
-}
-
-func refreshYoutube(webChan chan struct{}) {
-	<-webChan
 	for {
 		webYT.RefreshSubscriptions()
 
 		// loop every 4 days
-		time.Sleep(4 * 24 * time.Hour)
+		if !util.Sleep(ctx, 4*24*time.Hour) {
+			return
+		}
 	}
 }
@@ -0,0 +1,197 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression. Use Parse to create one.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// domStar and dowStar are set if the field was '*'. Cron matches a day if either the day of the
+	// month or the day of the week matches, unless one of them is '*'.
+	domStar, dowStar bool
+}
+
+// field describes the valid range of one field of a cron expression
+type field struct {
+	name     string
+	min, max int
+}
+
+var (
+	minuteField = field{"minute", 0, 59}
+	hourField   = field{"hour", 0, 23}
+	domField    = field{"day of month", 1, 31}
+	monthField  = field{"month", 1, 12}
+	dowField    = field{"day of week", 0, 7}
+)
+
+// descriptors are the supported shorthands for common expressions
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a standard cron expression with the five fields
+//
+//	minute hour day-of-month month day-of-week
+//
+// Each field can be a '*', a single number, a range 'a-b' or a comma separated list of them. Ranges
+// and '*' can have a step like '*/15' or '1-10/2'. For the day of week both 0 and 7 are Sunday.
+// Additionally the descriptors @yearly, @annually, @monthly, @weekly, @daily, @midnight and @hourly
+// are supported.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := descriptors[spec]; ok {
+		spec = d
+	} else if strings.HasPrefix(spec, "@") {
+		return nil, fmt.Errorf("unknown descriptor '%s'", spec)
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d in '%s'", len(fields), spec)
+	}
+
+	var (
+		s   = &Schedule{}
+		err error
+	)
+	if s.minute, err = parseField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], domField); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], dowField); err != nil {
+		return nil, err
+	}
+	// 7 is also Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar = fields[2] == "*"
+	s.dowStar = fields[4] == "*"
+	return s, nil
+}
+
+// parseField parses a single field of a cron expression and returns a bit set of all matching
+// values.
+func parseField(s string, f field) (bitset uint64, err error) {
+	for _, part := range strings.Split(s, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step '%s' in %s field", stepPart, f.name)
+			}
+		}
+
+		var start, end int
+		switch {
+		case rangePart == "*":
+			start, end = f.min, f.max
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			if start, err = parseValue(a, f); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(b, f); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range '%s' in %s field", rangePart, f.name)
+			}
+		default:
+			if start, err = parseValue(rangePart, f); err != nil {
+				return 0, err
+			}
+			end = start
+			if hasStep {
+				end = f.max
+			}
+		}
+
+		for i := start; i <= end; i += step {
+			bitset |= 1 << i
+		}
+	}
+	return bitset, nil
+}
+
+func parseValue(s string, f field) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value '%s' in %s field: must be in %d-%d", s, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first time after t that matches s. The returned time is in the same location
+// as t. If no such time exists within the next five years, the zero time is returned.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	// start at the next full minute
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	limit := t.Year() + 5
+
+	for t.Year() <= limit {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
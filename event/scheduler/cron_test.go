@@ -0,0 +1,117 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@sometimes",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) expected error", spec)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no timezone data: %v", err)
+	}
+	date := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"0 8 * * *", date(2024, 12, 1, 7, 59), date(2024, 12, 1, 8, 0)},
+		{"0 8 * * *", date(2024, 12, 1, 8, 0), date(2024, 12, 2, 8, 0)},
+		{"0 8 * * *", date(2024, 12, 31, 9, 0), date(2025, 1, 1, 8, 0)},
+		{"*/15 * * * *", date(2024, 12, 1, 7, 16), date(2024, 12, 1, 7, 30)},
+		{"30 9-17/4 * * *", date(2024, 12, 1, 14, 0), date(2024, 12, 1, 17, 30)},
+		{"0 0 1,15 * *", date(2024, 12, 2, 0, 0), date(2024, 12, 15, 0, 0)},
+		{"0 0 29 2 *", date(2025, 1, 1, 0, 0), date(2028, 2, 29, 0, 0)},
+		{"0 12 * * 7", date(2024, 12, 2, 0, 0), date(2024, 12, 8, 12, 0)},
+		// day of month or day of week
+		{"0 0 13 * 5", date(2024, 12, 1, 0, 0), date(2024, 12, 6, 0, 0)},
+		{"@daily", date(2024, 12, 1, 0, 0), date(2024, 12, 2, 0, 0)},
+		{"@hourly", date(2024, 12, 1, 0, 30), date(2024, 12, 1, 1, 0)},
+		{"0 8 * * *", time.Date(2024, 12, 1, 8, 30, 0, 0, berlin), time.Date(2024, 12, 2, 8, 0, 0, 0, berlin)},
+		// 02:30 doesn't exist when switching to summer time
+		{"30 2 * * *", time.Date(2024, 3, 31, 0, 0, 0, 0, berlin), time.Date(2024, 4, 1, 2, 30, 0, 0, berlin)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next(%s) = %s, want %s", tt.spec, tt.from, got, tt.want)
+		}
+	}
+}
+
+func Test_missedRun(t *testing.T) {
+	s, err := Parse("0 8 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := func(day, hour, min int) time.Time {
+		return time.Date(2024, 12, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name    string
+		lastRun time.Time
+		hasRun  bool
+		now     time.Time
+		grace   time.Duration
+		want    bool
+	}{
+		{"restart after trigger", date(1, 8, 0), true, date(2, 8, 1), time.Hour, true},
+		{"already ran", date(2, 8, 0), true, date(2, 8, 1), time.Hour, false},
+		{"never ran", time.Time{}, false, date(2, 8, 1), time.Hour, true},
+		{"outside grace", date(1, 8, 0), true, date(2, 10, 0), time.Hour, false},
+		{"before trigger", date(1, 8, 0), true, date(2, 7, 59), time.Hour, false},
+		{"no grace", date(1, 8, 0), true, date(2, 8, 1), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due, got := missedRun(s, tt.lastRun, tt.hasRun, tt.now, tt.grace)
+			if got != tt.want {
+				t.Errorf("missedRun() = %s, %v, want %v", due, got, tt.want)
+			}
+		})
+	}
+}
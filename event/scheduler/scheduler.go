@@ -0,0 +1,279 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"cake4everybot/database"
+	"context"
+	"fmt"
+	logger "log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/kesuaheli/twitchgo"
+	"github.com/spf13/viper"
+)
+
+var log = logger.New(logger.Writer(), "[Scheduler] ", logger.LstdFlags|logger.Lmsgprefix)
+
+// Morning is a special spec for a job that runs daily at the configured 'event.morning_hour' and
+// 'event.morning_minute'.
+const Morning = "@morning"
+
+// defaultGrace is used when 'event.schedule.grace' is not set
+const defaultGrace = 6 * time.Hour
+
+// Job is a function that is called repeatedly by the scheduler.
+type Job struct {
+	// Name uniquely identifies the job. It is used to store the last run in the database and to
+	// overwrite the spec in the config via 'event.schedule.jobs.<name>'.
+	Name string
+	// Spec is the cron expression (see Parse) or Morning.
+	Spec string
+	// Func is the function to call. It can be one of
+	//
+	//	func(*discordgo.Session)
+	//	func(*twitchgo.Twitch)
+	//	func(context.Context) error
+	//	GuildFunc
+	//
+	// Only when the function returns without error the run is recorded as successful.
+	Func interface{}
+}
+
+// GuildFunc is the function of a per-guild job, see RegisterGuild. t is the scheduled time in the
+// timezone of the guild. Only when it returns without error the run is recorded as successful.
+type GuildFunc func(s *discordgo.Session, guildID string, t time.Time) error
+
+var (
+	jobs    []Job
+	jobsMux sync.Mutex
+)
+
+// Register adds a new job to the scheduler. It is meant to be called from the init function of a
+// module, so it must be called before Run.
+func Register(name, spec string, f interface{}) {
+	switch f.(type) {
+	case func(*discordgo.Session), func(*twitchgo.Twitch), func(context.Context) error, GuildFunc:
+	default:
+		panic(fmt.Sprintf("scheduler: job '%s' has unsupported function type %T", name, f))
+	}
+
+	jobsMux.Lock()
+	defer jobsMux.Unlock()
+	for _, j := range jobs {
+		if j.Name == name {
+			panic(fmt.Sprintf("scheduler: job '%s' registered twice", name))
+		}
+	}
+	jobs = append(jobs, Job{Name: name, Spec: spec, Func: f})
+}
+
+// RegisterGuild adds a new job to the scheduler that runs separately for every guild in the guilds
+// table. The spec is evaluated in the configured timezone of each guild. Like Register it must be
+// called before Run.
+func RegisterGuild(name, spec string, f GuildFunc) {
+	Register(name, spec, f)
+}
+
+// Run starts all registered jobs and blocks until ctx is done and all running jobs returned.
+//
+// Jobs that missed their last scheduled time while the bot was offline are run once on startup,
+// if this time is no longer ago than the configured grace window 'event.schedule.grace'.
+func Run(ctx context.Context, dc *discordgo.Session, t *twitchgo.Twitch) {
+	grace := viper.GetDuration("event.schedule.grace")
+	if !viper.IsSet("event.schedule.grace") {
+		grace = defaultGrace
+	}
+
+	jobsMux.Lock()
+	registered := make([]Job, len(jobs))
+	copy(registered, jobs)
+	jobsMux.Unlock()
+
+	var wg sync.WaitGroup
+	for _, j := range registered {
+		spec := resolveSpec(j)
+		schedule, err := Parse(spec)
+		if err != nil {
+			log.Printf("Error on parsing schedule '%s' of job '%s': %v\n", spec, j.Name, err)
+			continue
+		}
+		log.Printf("Scheduled job '%s' for '%s'\n", j.Name, spec)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if f, ok := j.Func.(GuildFunc); ok {
+				runGuildJob(ctx, j.Name, f, schedule, grace, dc)
+			} else {
+				runJob(ctx, j, schedule, grace, dc, t)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// resolveSpec returns the spec of j, taking the config overwrite and the Morning spec into
+// account.
+func resolveSpec(j Job) string {
+	spec := j.Spec
+	if s := viper.GetString("event.schedule.jobs." + j.Name); s != "" {
+		spec = s
+	}
+	if spec == Morning {
+		spec = fmt.Sprintf("%d %d * * *", viper.GetInt("event.morning_minute"), viper.GetInt("event.morning_hour"))
+	}
+	return spec
+}
+
+func runJob(ctx context.Context, j Job, schedule *Schedule, grace time.Duration, dc *discordgo.Session, t *twitchgo.Twitch) {
+	lastRun, ok, err := database.GetJobLastRun(j.Name)
+	if err != nil {
+		log.Printf("Error on getting last run of job '%s': %v\n", j.Name, err)
+	} else if missed, ok := missedRun(schedule, lastRun, ok, time.Now(), grace); ok {
+		log.Printf("Catching up on job '%s' scheduled for %s\n", j.Name, missed.Format(time.DateTime))
+		execute(ctx, j, dc, t)
+	}
+
+	for {
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("Job '%s' has no next run, stopping it\n", j.Name)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		execute(ctx, j, dc, t)
+	}
+}
+
+// runGuildJob checks every minute for each guild, whether the job is due in the timezone of that
+// guild. The guilds and their timezones are reloaded every time, so new guilds and changed
+// timezones are picked up without a restart. A failed run is retried every minute within the
+// grace window.
+func runGuildJob(ctx context.Context, name string, f GuildFunc, schedule *Schedule, grace time.Duration, dc *discordgo.Session) {
+	type lastRun struct {
+		t      time.Time
+		hasRun bool
+		failed bool
+	}
+	lastRuns := make(map[string]lastRun)
+
+	var prevCheck time.Time
+	for {
+		now := time.Now()
+		// on startup look back for the whole grace window, afterwards only since the last check
+		window := grace
+		if !prevCheck.IsZero() {
+			window = now.Sub(prevCheck)
+		}
+		prevCheck = now
+
+		locations, err := database.GetGuildLocations()
+		if err != nil {
+			log.Printf("Error on getting guild timezones for job '%s': %v\n", name, err)
+		}
+
+		for guildID, loc := range locations {
+			key := name + ":" + guildID
+			last, ok := lastRuns[guildID]
+			if !ok {
+				last.t, last.hasRun, err = database.GetJobLastRun(key)
+				if err != nil {
+					log.Printf("Error on getting last run of job '%s': %v\n", key, err)
+					continue
+				}
+				lastRuns[guildID] = last
+			}
+
+			guildWindow := window
+			if last.failed {
+				guildWindow = grace
+			}
+			due, ok := missedRun(schedule, last.t, last.hasRun, now.In(loc), guildWindow)
+			if !ok {
+				continue
+			}
+			if now.Sub(due) >= time.Minute {
+				log.Printf("Catching up on job '%s' scheduled for %s\n", key, due.Format(time.DateTime+" MST"))
+			}
+
+			start := time.Now()
+			if err = f(dc, guildID, due); err != nil {
+				log.Printf("Error on running job '%s': %v\n", key, err)
+				last.failed = true
+				lastRuns[guildID] = last
+				continue
+			}
+			lastRuns[guildID] = lastRun{t: start, hasRun: true}
+			if err = database.SetJobLastRun(key, start); err != nil {
+				log.Printf("Error on saving last run of job '%s': %v\n", key, err)
+			}
+		}
+
+		nextCheck := now.Truncate(time.Minute).Add(time.Minute)
+		timer := time.NewTimer(time.Until(nextCheck))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// missedRun returns the earliest scheduled time within the grace window before now, if the job
+// didn't run successfully since then. hasRun is false if the job never ran.
+func missedRun(schedule *Schedule, lastRun time.Time, hasRun bool, now time.Time, grace time.Duration) (time.Time, bool) {
+	if grace <= 0 {
+		return time.Time{}, false
+	}
+	due := schedule.Next(now.Add(-grace))
+	if due.IsZero() || due.After(now) {
+		return time.Time{}, false
+	}
+	if hasRun && !lastRun.Before(due) {
+		return time.Time{}, false
+	}
+	return due, true
+}
+
+// execute calls the function of j and records the run in the database if it succeeded.
+func execute(ctx context.Context, j Job, dc *discordgo.Session, t *twitchgo.Twitch) {
+	start := time.Now()
+	switch f := j.Func.(type) {
+	case func(*discordgo.Session):
+		f(dc)
+	case func(*twitchgo.Twitch):
+		f(t)
+	case func(context.Context) error:
+		if err := f(ctx); err != nil {
+			log.Printf("Error on running job '%s': %v\n", j.Name, err)
+			return
+		}
+	}
+
+	if err := database.SetJobLastRun(j.Name, start); err != nil {
+		log.Printf("Error on saving last run of job '%s': %v\n", j.Name, err)
+	}
+}
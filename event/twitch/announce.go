@@ -0,0 +1,313 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"cake4everybot/event/scheduler"
+	"cake4everybot/tools/helix"
+	"cake4everybot/util"
+	webTwitch "cake4everybot/webserver/twitch"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/spf13/viper"
+)
+
+const (
+	channelBaseURL = "https://twitch.tv/%s"
+	vodsBaseURL    = "https://twitch.tv/%s/videos"
+	twitchColor    = 0x9146FF
+)
+
+var (
+	hx *helix.Helix
+	// stopCtx is done when the bot shuts down, see SetHelix
+	stopCtx = context.Background()
+)
+
+// SetHelix sets the Helix API client used for stream announcements. When ctx is done, the
+// announcements stop waiting for the API.
+func SetHelix(ctx context.Context, h *helix.Helix) {
+	stopCtx = ctx
+	hx = h
+}
+
+func init() {
+	scheduler.Register("twitch_live_streams", "*/5 * * * *", updateLiveStreams)
+}
+
+// isAnnounced returns whether go-live announcements are enabled for the given broadcaster.
+func isAnnounced(login string) bool {
+	return slices.ContainsFunc(viper.GetStringSlice("twitch.announce"), func(s string) bool {
+		return strings.EqualFold(s, login)
+	})
+}
+
+// AnnounceOnline is the handler for the stream.online event. It saves the stream, as native watch
+// points are only given while a channel is live, and announces the stream of a configured
+// broadcaster in the Twitch channel of every guild.
+func AnnounceOnline(s *discordgo.Session, e *webTwitch.StreamOnlineEvent) {
+	if _, ok, err := database.GetLiveTwitchStream(e.BroadcasterUserID); err != nil {
+		log.Printf("Error on getting live stream of '%s' from database: %v", e.BroadcasterUserLogin, err)
+		return
+	} else if ok {
+		// already announced, e.g. because twitch resent the event
+		return
+	}
+
+	stream := getStream(e)
+	err := database.AddTwitchStream(database.TwitchStream{
+		ID:               e.ID,
+		BroadcasterID:    e.BroadcasterUserID,
+		BroadcasterLogin: e.BroadcasterUserLogin,
+		Title:            stream.Title,
+		Game:             stream.GameName,
+		StartedAt:        e.StartedAt,
+		PeakViewers:      stream.ViewerCount,
+	})
+	if err != nil {
+		log.Printf("Error on saving stream of '%s' to database: %v", e.BroadcasterUserLogin, err)
+		return
+	}
+	if !isAnnounced(e.BroadcasterUserLogin) {
+		return
+	}
+
+	var profileImage string
+	if users, err := hx.GetUsers(e.BroadcasterUserID); err != nil {
+		log.Printf("Error on getting twitch user '%s': %v", e.BroadcasterUserLogin, err)
+	} else if len(users) > 0 {
+		profileImage = users[0].ProfileImageURL
+	}
+
+	channels, err := util.GetChannelsFromDatabase(s, "twitch_channel")
+	if err != nil {
+		log.Printf("Error on getting twitch announcement channels: %v", err)
+		return
+	}
+	if len(channels) == 0 {
+		log.Printf("No channels to announce stream. Dropping announcement for '%s'", e.BroadcasterUserLogin)
+		return
+	}
+
+	data := &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{liveEmbed(s, e.BroadcasterUserName, e.BroadcasterUserLogin, profileImage, stream)},
+		Components: linkButton(lang.GetDefault("twitch.announce.msg.watch"), fmt.Sprintf(channelBaseURL, e.BroadcasterUserLogin)),
+	}
+	for guildID, channelID := range channels {
+		data.Content = ""
+		if settings, err := database.GetGuildSettings(guildID, "twitch_role"); err != nil {
+			log.Printf("Error on getting twitch role of guild '%s': %v", guildID, err)
+		} else if roleID := settings["twitch_role"]; roleID != "" {
+			data.Content = fmt.Sprintf("<@&%s>", roleID)
+		}
+
+		msg, err := s.ChannelMessageSendComplex(channelID, data)
+		if err != nil {
+			log.Printf("Error on sending stream announcement to channel '%s' in guild '%s': %v", channelID, guildID, err)
+			continue
+		}
+		err = database.AddTwitchAnnouncement(database.TwitchAnnouncement{
+			StreamID:  e.ID,
+			GuildID:   guildID,
+			ChannelID: channelID,
+			MessageID: msg.ID,
+		})
+		if err != nil {
+			log.Printf("Error on saving stream announcement '%s/%s': %v", channelID, msg.ID, err)
+		}
+	}
+}
+
+// AnnounceOffline is the handler for the stream.offline event. It edits the announcements of the
+// stream to show its duration and peak viewers.
+func AnnounceOffline(s *discordgo.Session, e *webTwitch.StreamOfflineEvent) {
+	stream, ok, err := database.GetLiveTwitchStream(e.BroadcasterUserID)
+	if err != nil {
+		log.Printf("Error on getting live stream of '%s' from database: %v", e.BroadcasterUserLogin, err)
+		return
+	}
+	if !ok {
+		return
+	}
+	endStream(s, stream, time.Now())
+}
+
+// endStream marks the stream as ended and edits all its announcements.
+func endStream(s *discordgo.Session, stream database.TwitchStream, endedAt time.Time) {
+	if err := database.EndTwitchStream(stream.ID, endedAt); err != nil {
+		log.Printf("Error on ending stream '%s' of '%s': %v", stream.ID, stream.BroadcasterLogin, err)
+		return
+	}
+	stream.EndedAt = endedAt
+
+	announcements, err := database.GetTwitchAnnouncements(stream.ID)
+	if err != nil {
+		log.Printf("Error on getting announcements of stream '%s': %v", stream.ID, err)
+		return
+	}
+
+	var (
+		content    = ""
+		embeds     = []*discordgo.MessageEmbed{offlineEmbed(s, stream)}
+		components = linkButton(lang.GetDefault("twitch.announce.msg.vods"), fmt.Sprintf(vodsBaseURL, stream.BroadcasterLogin))
+	)
+	for _, a := range announcements {
+		// keep the existing author icon
+		if msg, err := s.ChannelMessage(a.ChannelID, a.MessageID); err == nil && len(msg.Embeds) > 0 && msg.Embeds[0].Author != nil {
+			embeds[0].Author.IconURL = msg.Embeds[0].Author.IconURL
+		}
+
+		_, err = s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			ID:         a.MessageID,
+			Channel:    a.ChannelID,
+			Content:    &content,
+			Embeds:     &embeds,
+			Components: &components,
+		})
+		if err != nil {
+			log.Printf("Error on editing stream announcement '%s/%s': %v", a.ChannelID, a.MessageID, err)
+		}
+	}
+}
+
+// getStream returns the current stream information from the API. Right after going live the
+// stream might not be listed yet, so it is retried a few times. If it still fails, only the data
+// of the event is used.
+func getStream(e *webTwitch.StreamOnlineEvent) helix.Stream {
+	for i := 0; hx != nil && i < 3; i++ {
+		if i > 0 && !util.Sleep(stopCtx, 10*time.Second) {
+			break
+		}
+		streams, err := hx.GetStreams(e.BroadcasterUserID)
+		if err != nil {
+			log.Printf("Error on getting stream of '%s': %v", e.BroadcasterUserLogin, err)
+			continue
+		}
+		if len(streams) > 0 {
+			return streams[0]
+		}
+	}
+	return helix.Stream{
+		ID:        e.ID,
+		UserID:    e.BroadcasterUserID,
+		UserLogin: e.BroadcasterUserLogin,
+		UserName:  e.BroadcasterUserName,
+		StartedAt: e.StartedAt,
+	}
+}
+
+// updateLiveStreams is a scheduled job that updates the peak viewers of all live streams. Streams
+// that are no longer live are ended, in case the offline event was missed.
+func updateLiveStreams(dc *discordgo.Session) {
+	if hx == nil {
+		return
+	}
+	streams, err := database.GetLiveTwitchStreams()
+	if err != nil {
+		log.Printf("Error on getting live streams from database: %v", err)
+		return
+	}
+	if len(streams) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(streams))
+	for _, s := range streams {
+		ids = append(ids, s.BroadcasterID)
+	}
+	live, err := hx.GetStreams(ids...)
+	if err != nil {
+		log.Printf("Error on getting live streams: %v", err)
+		return
+	}
+
+	for _, s := range streams {
+		i := slices.IndexFunc(live, func(l helix.Stream) bool { return l.UserID == s.BroadcasterID })
+		if i == -1 {
+			// give the API some time to list new streams
+			if time.Since(s.StartedAt) > 10*time.Minute {
+				log.Printf("Stream of '%s' is no longer live, but didn't receive an offline event", s.BroadcasterLogin)
+				endStream(dc, s, time.Now())
+			}
+			continue
+		}
+		if err = database.UpdateTwitchStream(s.ID, live[i].Title, live[i].GameName, live[i].ViewerCount); err != nil {
+			log.Printf("Error on updating stream '%s' of '%s': %v", s.ID, s.BroadcasterLogin, err)
+		}
+	}
+}
+
+func liveEmbed(s *discordgo.Session, name, login, profileImage string, stream helix.Stream) *discordgo.MessageEmbed {
+	e := &discordgo.MessageEmbed{
+		Title: stream.Title,
+		URL:   fmt.Sprintf(channelBaseURL, login),
+		Color: twitchColor,
+		Author: &discordgo.MessageEmbedAuthor{
+			URL:     fmt.Sprintf(channelBaseURL, login),
+			Name:    fmt.Sprintf(lang.GetDefault("twitch.announce.msg.live"), name),
+			IconURL: profileImage,
+		},
+	}
+	if e.Title == "" {
+		e.Title = fmt.Sprintf(channelBaseURL, login)
+	}
+	if stream.GameName != "" {
+		util.AddEmbedField(e, lang.GetDefault("twitch.announce.msg.game"), stream.GameName, true)
+	}
+	if stream.ThumbnailURL != "" {
+		// add a timestamp to prevent discord from showing a cached thumbnail
+		e.Image = &discordgo.MessageEmbedImage{URL: fmt.Sprintf("%s?t=%d", stream.Thumbnail(1280, 720), time.Now().Unix())}
+	}
+	util.SetEmbedFooter(s, "twitch.announce.embed_footer", e)
+	return e
+}
+
+func offlineEmbed(s *discordgo.Session, stream database.TwitchStream) *discordgo.MessageEmbed {
+	e := &discordgo.MessageEmbed{
+		Title: stream.Title,
+		URL:   fmt.Sprintf(vodsBaseURL, stream.BroadcasterLogin),
+		Color: 0x808080,
+		Author: &discordgo.MessageEmbedAuthor{
+			URL:  fmt.Sprintf(channelBaseURL, stream.BroadcasterLogin),
+			Name: fmt.Sprintf(lang.GetDefault("twitch.announce.msg.offline"), stream.BroadcasterLogin),
+		},
+	}
+	if e.Title == "" {
+		e.Title = fmt.Sprintf(channelBaseURL, stream.BroadcasterLogin)
+	}
+	if stream.Game != "" {
+		util.AddEmbedField(e, lang.GetDefault("twitch.announce.msg.game"), stream.Game, true)
+	}
+	duration := stream.EndedAt.Sub(stream.StartedAt).Round(time.Minute)
+	util.AddEmbedField(e, lang.GetDefault("twitch.announce.msg.duration"), duration.String(), true)
+	if stream.PeakViewers > 0 {
+		util.AddEmbedField(e, lang.GetDefault("twitch.announce.msg.peak_viewers"), fmt.Sprint(stream.PeakViewers), true)
+	}
+	util.SetEmbedFooter(s, "twitch.announce.embed_footer", e)
+	return e
+}
+
+func linkButton(label, url string) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+		discordgo.Button{Label: label, Style: discordgo.LinkButton, URL: url},
+	}}}
+}
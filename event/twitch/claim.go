@@ -0,0 +1,178 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"cake4everybot/event/scheduler"
+	webGiveaway "cake4everybot/webserver/giveaway"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/kesuaheli/twitchgo"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	scheduler.Register("twitch_giveaway_claims", "* * * * *", checkClaims)
+}
+
+// drawPrize draws a winner for prize, a prize of p, and announces them in channel. Previous winners
+// of prize who didn't claim it can't win it again. The winner is saved to the prizes file and their
+// tickets are removed before the announcement. The caller has to hold the lock of the prizes file,
+// see LockPrizes.
+//
+// It returns false if nobody could be drawn.
+func drawPrize(t *twitchgo.Twitch, channel string, g database.TwitchGiveaway, config giveawayConfig, p *database.GiveawayPrize, prize *database.GiveawayPrizeSingle) (ok bool, err error) {
+	draw, winners, err := database.DrawGiveawayFairExcluding(g.Prefix, 1, prize.Released)
+	if err != nil {
+		return false, err
+	}
+	if len(winners) == 0 {
+		return false, nil
+	}
+	winner := winners[0]
+
+	prize.SetWinner(winner.UserID, time.Now(), config.ClaimWindow)
+	prize.Channel = channel
+	if err = p.SaveFile(); err != nil {
+		return false, fmt.Errorf("save winner '%s': %v", winner.UserID, err)
+	}
+	err = database.DeleteGiveawayEntry(g.Prefix, database.GiveawayPlatformTwitch, winner.UserID)
+	if err != nil {
+		// the winner is already saved, so they are announced anyway
+		log.Printf("Error deleting entry of winner '%s' in giveaway '%s': %v", winner.UserID, g.Prefix, err)
+	}
+
+	t.SendMessagef(channel, lang.GetDefault(tp+"draw.msg.winner"), winner.UserID, prize.Name, winner.Weight, config.MaxTickets, float64(winner.Weight*100)/float64(draw.TotalTickets))
+	t.SendMessagef(channel, lang.GetDefault(tp+"draw.msg.reveal"), draw.Seed, draw.EntriesHash(), draw.NextCommitment)
+	if url := webGiveaway.DrawURL(draw.ID); url != "" {
+		t.SendMessagef(channel, lang.GetDefault(tp+"draw.msg.entries"), url)
+	}
+	if config.ClaimWindow > 0 {
+		t.SendMessagef(channel, lang.GetDefault(tp+"claim.msg.instructions"), winner.UserID, config.ClaimWindow.String())
+	}
+	return true, nil
+}
+
+// HandleCmdClaim is the handler for the claim command in a twitch chat. The winner of a prize has
+// to use it within the configured claim window. Otherwise the prize is drawn again, see
+// checkClaims.
+func HandleCmdClaim(t *twitchgo.Twitch, channel string, user *twitchgo.User, args []string) {
+	channel, _ = strings.CutPrefix(channel, "#")
+	const tp = tp + "claim."
+
+	done, ok := begin()
+	if !ok {
+		return
+	}
+	defer done()
+
+	config := getGiveawayConfig(channel)
+	defer LockPrizes(config.Prizes)()
+	p, err := database.NewGiveawayPrize(config.Prizes)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.no_claim"), user.Nickname)
+		return
+	} else if err != nil {
+		log.Printf("Error reading prizes file: %v", err)
+		t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+		return
+	}
+
+	prize, ok := p.PendingClaim(channel, user.Nickname)
+	if !ok {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.no_claim"), user.Nickname)
+		return
+	}
+	if !prize.Claim(time.Now()) {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.too_late"), user.Nickname, prize.Name)
+		return
+	}
+	if err = p.SaveFile(); err != nil {
+		log.Printf("Error saving prizes file: %v", err)
+		t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+		return
+	}
+	log.Printf("%s claimed '%s' in channel '%s'", user.Nickname, prize.Name, channel)
+	t.SendMessagef(channel, lang.GetDefault(tp+"msg.claimed"), user.Nickname, prize.Name)
+}
+
+// checkClaims is a scheduled function to run every minute. In each joined channel it releases all
+// prizes whose winner didn't claim them in time and draws new winners for them.
+func checkClaims(t *twitchgo.Twitch) {
+	for _, channel := range viper.GetStringSlice("twitch.channels") {
+		checkChannelClaims(t, strings.ToLower(channel), time.Now())
+	}
+}
+
+// checkChannelClaims is like checkClaims for a single channel.
+func checkChannelClaims(t *twitchgo.Twitch, channel string, now time.Time) {
+	const tp = tp + "claim."
+
+	done, ok := begin()
+	if !ok {
+		return
+	}
+	defer done()
+
+	config := getGiveawayConfig(channel)
+	if config.Prizes == "" {
+		return
+	}
+	defer LockPrizes(config.Prizes)()
+	p, err := database.NewGiveawayPrize(config.Prizes)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	} else if err != nil {
+		log.Printf("Error reading prizes file: %v", err)
+		return
+	}
+	expired := p.ExpiredClaims(channel, now)
+	if len(expired) == 0 {
+		return
+	}
+
+	g, running, err := database.GetTwitchGiveaway(channel)
+	if err != nil {
+		log.Printf("Error getting giveaway of channel '%s': %v", channel, err)
+		return
+	}
+	for _, prize := range expired {
+		log.Printf("%s didn't claim '%s' in channel '%s' in time", prize.Winner, prize.Name, channel)
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.expired"), prize.Winner, prize.Name)
+		prize.Release()
+		if !running {
+			t.SendMessagef(channel, lang.GetDefault(tp+"msg.no_redraw"), prize.Name)
+			continue
+		}
+
+		ok, err := drawPrize(t, channel, g, config, &p, prize)
+		if err != nil {
+			log.Printf("Error redrawing '%s' in giveaway '%s': %v", prize.Name, g.Prefix, err)
+			t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+		} else if !ok {
+			t.SendMessagef(channel, lang.GetDefault(tp+"msg.no_redraw"), prize.Name)
+		}
+	}
+
+	if err = p.SaveFile(); err != nil {
+		log.Printf("Error saving prizes file: %v", err)
+	}
+}
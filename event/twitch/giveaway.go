@@ -0,0 +1,227 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	webGiveaway "cake4everybot/webserver/giveaway"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kesuaheli/twitchgo"
+	"github.com/spf13/viper"
+)
+
+// defaultMaxTickets is the maximum number of tickets per user if not configured otherwise
+const defaultMaxTickets = 10
+
+// giveawayConfig holds the giveaway settings of a single Twitch channel
+type giveawayConfig struct {
+	// TicketCost is the amount of points a single ticket costs
+	TicketCost int
+	// MaxTickets is the maximum number of tickets a single user can own
+	MaxTickets int
+	// Cooldown is the time a user has to wait before buying another ticket
+	Cooldown time.Duration
+	// Prizes is the path of the prizes file
+	Prizes string
+	// ClaimWindow is the time a winner has to claim their prize with !claim. If it is 0, no claim
+	// is needed.
+	ClaimWindow time.Duration
+	// TicketItem is the name or ID of the StreamElements store item that is redeemed for a ticket.
+	// If it is empty, tickets can't be redeemed.
+	TicketItem string
+}
+
+// getGiveawayConfig returns the giveaway settings for channel. Each setting is read from
+// 'event.twitch_giveaway.channels.<channel>' and falls back to the global one in
+// 'event.twitch_giveaway'.
+func getGiveawayConfig(channel string) giveawayConfig {
+	key := func(name string) string {
+		channelKey := fmt.Sprintf("event.twitch_giveaway.channels.%s.%s", strings.ToLower(channel), name)
+		if viper.IsSet(channelKey) {
+			return channelKey
+		}
+		return "event.twitch_giveaway." + name
+	}
+
+	config := giveawayConfig{
+		TicketCost:  viper.GetInt(key("ticket_cost")),
+		MaxTickets:  viper.GetInt(key("max_tickets")),
+		Cooldown:    viper.GetDuration(key("cooldown")) * time.Minute,
+		Prizes:      viper.GetString(key("prizes")),
+		ClaimWindow: viper.GetDuration(key("claim_window")) * time.Minute,
+		TicketItem:  viper.GetString(key("ticket_item")),
+	}
+	if config.MaxTickets <= 0 {
+		config.MaxTickets = defaultMaxTickets
+	}
+	return config
+}
+
+// PrizesFile returns the path of the prizes file of channel. Lock it with LockPrizes before
+// changing it.
+func PrizesFile(channel string) string {
+	return getGiveawayConfig(channel).Prizes
+}
+
+// getGiveaway returns the giveaway of channel together with its settings. If there is none or an
+// error occurs, a message is sent to the chat and ok is false.
+func getGiveaway(t *twitchgo.Twitch, channel, user string) (g database.TwitchGiveaway, config giveawayConfig, ok bool) {
+	g, ok, err := database.GetTwitchGiveaway(channel)
+	if err != nil {
+		log.Printf("Error getting giveaway of channel '%s': %v", channel, err)
+		t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+		return g, config, false
+	}
+	if !ok {
+		t.SendMessagef(channel, lang.GetDefault("twitch.command.generic.not_running"), user)
+		return g, config, false
+	}
+	return g, getGiveawayConfig(channel), true
+}
+
+// HandleCmdGiveaway is the handler for the giveaway command in a twitch chat. It lets the
+// broadcaster start and close the giveaway of their channel. The name of a giveaway is prefixed
+// with the channel and defaults to the current date.
+//
+//	!giveaway start [name]
+//	!giveaway close
+func HandleCmdGiveaway(t *twitchgo.Twitch, channel string, user *twitchgo.User, args []string) {
+	channel, _ = strings.CutPrefix(channel, "#")
+	const tp = tp + "giveaway."
+
+	//only accept broadcaster
+	if channel != user.Nickname {
+		return
+	}
+
+	done, ok := begin()
+	if !ok {
+		return
+	}
+	defer done()
+
+	if len(args) == 0 {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.usage"), user.Nickname)
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "start":
+		g, ok, err := database.GetTwitchGiveaway(channel)
+		if err != nil {
+			log.Printf("Error getting giveaway of channel '%s': %v", channel, err)
+			t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+			return
+		}
+		if ok && g.Open {
+			t.SendMessagef(channel, lang.GetDefault(tp+"msg.already_running"), user.Nickname, g.Prefix)
+			return
+		}
+
+		now := time.Now()
+		// the name is always prefixed with the channel, so it can't continue the giveaway of
+		// another channel or the advent calendar
+		name := now.Format("20060102")
+		if len(args) >= 2 {
+			name = strings.ToLower(args[1])
+		}
+		prefix := fmt.Sprintf("%s-%s", channel, name)
+		if err = database.StartTwitchGiveaway(channel, prefix, now); err != nil {
+			log.Printf("Error starting giveaway '%s' in channel '%s': %v", prefix, channel, err)
+			t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+			return
+		}
+		config := getGiveawayConfig(channel)
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.started"), prefix, config.TicketCost, config.MaxTickets)
+
+		commitment, err := database.GetGiveawayCommitment(prefix)
+		if err != nil {
+			log.Printf("Error getting commitment of giveaway '%s': %v", prefix, err)
+			t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+			return
+		}
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.commitment"), commitment)
+	case "close":
+		ok, err := database.CloseTwitchGiveaway(channel)
+		if err != nil {
+			log.Printf("Error closing giveaway in channel '%s': %v", channel, err)
+			t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+			return
+		}
+		if !ok {
+			t.SendMessagef(channel, lang.GetDefault("twitch.command.generic.not_running"), user.Nickname)
+			return
+		}
+		t.SendMessage(channel, lang.GetDefault(tp+"msg.closed"))
+
+		// publish the final entries, so they can't be changed before the draw
+		g, _, err := database.GetTwitchGiveaway(channel)
+		if err != nil {
+			log.Printf("Error getting giveaway of channel '%s': %v", channel, err)
+			t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+			return
+		}
+		hash, entries, tickets, err := database.GetGiveawayEntriesHash(g.Prefix)
+		if err != nil {
+			log.Printf("Error getting entries hash of giveaway '%s': %v", g.Prefix, err)
+			t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+			return
+		}
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.entries"), entries, tickets, hash)
+	default:
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.usage"), user.Nickname)
+	}
+}
+
+// HandleCmdVerify is the handler for the verify command in a twitch chat. It recomputes the last
+// draw of the channels giveaway from its revealed seed and entries, see database.FairDraw.
+func HandleCmdVerify(t *twitchgo.Twitch, channel string, user *twitchgo.User, args []string) {
+	channel, _ = strings.CutPrefix(channel, "#")
+	const tp = tp + "verify."
+
+	g, _, ok := getGiveaway(t, channel, user.Nickname)
+	if !ok {
+		return
+	}
+
+	draw, ok, err := database.GetLastFairDraw(g.Prefix)
+	if err != nil {
+		log.Printf("Error getting last draw of giveaway '%s': %v", g.Prefix, err)
+		t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+		return
+	}
+	if !ok {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.no_draw"), user.Nickname)
+		return
+	}
+
+	if err = database.VerifyFairDraw(draw); err != nil {
+		log.Printf("Draw %d of giveaway '%s' failed verification: %v", draw.ID, g.Prefix, err)
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.failed"), user.Nickname, err)
+		return
+	}
+	var winners []string
+	for _, w := range draw.Winners {
+		winners = append(winners, w.UserID)
+	}
+	t.SendMessagef(channel, lang.GetDefault(tp+"msg.ok"), user.Nickname, len(draw.Entries), draw.TotalTickets, draw.EntriesHash(), strings.Join(winners, ", "), draw.Commitment)
+	if url := webGiveaway.DrawURL(draw.ID); url != "" {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.entries"), url)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestGetGiveawayConfig(t *testing.T) {
+	viper.Set("event.twitch_giveaway.ticket_cost", 1000)
+	viper.Set("event.twitch_giveaway.cooldown", 15)
+	viper.Set("event.twitch_giveaway.prizes", "prizes.json")
+	viper.Set("event.twitch_giveaway.claim_window", 5)
+	viper.Set("event.twitch_giveaway.channels.foo.ticket_cost", 500)
+	viper.Set("event.twitch_giveaway.channels.foo.max_tickets", 5)
+	viper.Set("event.twitch_giveaway.channels.foo.prizes", "foo.json")
+	viper.Set("event.twitch_giveaway.channels.foo.claim_window", 0)
+	t.Cleanup(func() { viper.Set("event.twitch_giveaway", nil) })
+
+	tests := []struct {
+		channel string
+		want    giveawayConfig
+	}{
+		{"bar", giveawayConfig{TicketCost: 1000, MaxTickets: defaultMaxTickets, Cooldown: 15 * time.Minute, Prizes: "prizes.json", ClaimWindow: 5 * time.Minute}},
+		{"foo", giveawayConfig{TicketCost: 500, MaxTickets: 5, Cooldown: 15 * time.Minute, Prizes: "foo.json"}},
+		{"Foo", giveawayConfig{TicketCost: 500, MaxTickets: 5, Cooldown: 15 * time.Minute, Prizes: "foo.json"}},
+	}
+	for _, tt := range tests {
+		if got := getGiveawayConfig(tt.channel); got != tt.want {
+			t.Errorf("getGiveawayConfig(%s) = %+v, want %+v", tt.channel, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kesuaheli/twitchgo"
+	"github.com/spf13/viper"
+)
+
+// seenUsers caches the last known login of each Twitch user ID, so the database is only written
+// when a user is new or renamed
+var seenUsers = struct {
+	sync.Mutex
+	logins map[string]string
+}{logins: map[string]string{}}
+
+// HandleAny is the handler for all messages from the Twitch chat. Other than the command handlers
+// it gets the tags of a message, which contain the stable user ID. It keeps the login of each user
+// ID up to date and handles the link command, which needs the user ID.
+func HandleAny(t *twitchgo.Twitch, m twitchgo.Message) {
+	if m.Command.Name != twitchgo.MsgCmdPrivmsg || m.Source == nil || m.Tags.UserID == "" || len(m.Command.Arguments) == 0 {
+		return
+	}
+	channel, _ := strings.CutPrefix(m.Command.Arguments[0], "#")
+	seeUser(m.Tags.UserID, m.Source.Nickname)
+
+	args := strings.Fields(m.Command.Data)
+	if len(args) > 0 && strings.EqualFold(args[0], t.Prefix+"link") {
+		handleCmdLink(t, channel, m.Source, m.Tags.UserID, args[1:])
+	}
+}
+
+// seeUser stores login as the current login of the Twitch user with the given ID. The database is
+// only written if login differs from the last one seen in this process.
+func seeUser(id, login string) {
+	seenUsers.Lock()
+	seen := seenUsers.logins[id] == login
+	seenUsers.Unlock()
+	if seen {
+		return
+	}
+
+	oldLogin, err := database.SeenTwitchUser(id, login, time.Now())
+	if err != nil {
+		log.Printf("Error storing Twitch user '%s' (%s): %v", login, id, err)
+		return
+	}
+	if oldLogin != "" {
+		log.Printf("Twitch user %s renamed from '%s' to '%s'", id, oldLogin, login)
+		renamePrizeWinner(oldLogin, login)
+	}
+	seenUsers.Lock()
+	seenUsers.logins[id] = login
+	seenUsers.Unlock()
+}
+
+// renamePrizeWinner replaces oldLogin with newLogin in the prizes files of all joined channels, so
+// a renamed winner can still claim their prize and can't win again.
+func renamePrizeWinner(oldLogin, newLogin string) {
+	renamed := map[string]bool{}
+	for _, channel := range viper.GetStringSlice("twitch.channels") {
+		filename := PrizesFile(channel)
+		if filename == "" || renamed[filepath.Clean(filename)] {
+			continue
+		}
+		renamed[filepath.Clean(filename)] = true
+		if err := renamePrizesFileWinner(filename, oldLogin, newLogin); err != nil {
+			log.Printf("Error renaming winner '%s' to '%s' in prizes file '%s': %v", oldLogin, newLogin, filename, err)
+		}
+	}
+}
+
+// renamePrizesFileWinner is like renamePrizeWinner for the single prizes file filename. A missing
+// file is no error.
+func renamePrizesFileWinner(filename, oldLogin, newLogin string) error {
+	defer LockPrizes(filename)()
+	p, err := database.NewGiveawayPrize(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !p.RenameWinner(oldLogin, newLogin) {
+		return nil
+	}
+	return p.SaveFile()
+}
+
+// handleCmdLink is the handler for the link command in a twitch chat. It links the Twitch user to
+// the Discord user that got the code with '/link twitch'.
+//
+//	!link <code>
+func handleCmdLink(t *twitchgo.Twitch, channel string, user *twitchgo.User, userID string, args []string) {
+	const tp = tp + "link."
+
+	done, ok := begin()
+	if !ok {
+		return
+	}
+	defer done()
+
+	if len(args) == 0 {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.usage"), user.Nickname)
+		return
+	}
+	link, err := database.RedeemAccountLinkCode(args[0], userID, time.Now())
+	if errors.Is(err, database.ErrInvalidLinkCode) {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.invalid"), user.Nickname)
+		return
+	} else if errors.Is(err, database.ErrTwitchLinked) {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.linked_other"), user.Nickname)
+		return
+	} else if err != nil {
+		log.Printf("Error linking Twitch user '%s' (%s): %v", user.Nickname, userID, err)
+		t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+		return
+	}
+	log.Printf("Linked Twitch user '%s' (%s) to Discord user %s", user.Nickname, userID, link.DiscordID)
+	t.SendMessagef(channel, lang.GetDefault(tp+"msg.success"), user.Nickname)
+}
+
+// HandleCmdBirthday is the handler for the birthday command in a twitch chat. It prints the
+// birthday of the Discord user linked to the Twitch user.
+//
+//	!birthday [@user]
+func HandleCmdBirthday(t *twitchgo.Twitch, channel string, source *twitchgo.User, args []string) {
+	channel, _ = strings.CutPrefix(channel, "#")
+	const tp = tp + "birthday."
+
+	login := source.Nickname
+	if len(args) >= 1 {
+		if s, _ := strings.CutPrefix(args[0], "@"); s != "" {
+			login = strings.ToLower(s)
+		}
+	}
+	self := login == source.Nickname
+
+	link, ok, err := database.GetAccountLinkByTwitchLogin(login)
+	if err != nil {
+		log.Printf("Error getting account link of '%s': %v", login, err)
+		t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+		return
+	}
+	if !ok {
+		if self {
+			t.SendMessagef(channel, lang.GetDefault(tp+"msg.not_linked"), source.Nickname)
+		} else {
+			t.SendMessagef(channel, lang.GetDefault(tp+"msg.not_linked.user"), source.Nickname, login)
+		}
+		return
+	}
+
+	b, ok, err := database.GetBirthday(link.DiscordID)
+	if err != nil {
+		log.Printf("Error getting birthday of Discord user %s: %v", link.DiscordID, err)
+		t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+		return
+	}
+	if !ok || !b.Visible {
+		if self {
+			t.SendMessagef(channel, lang.GetDefault(tp+"msg.no_birthday"), source.Nickname)
+		} else {
+			t.SendMessagef(channel, lang.GetDefault(tp+"msg.no_birthday.user"), source.Nickname, login)
+		}
+		return
+	}
+
+	month := lang.GetSliceElement("discord.command.birthday.month", b.Month-1, lang.FallbackLang())
+	if self {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.birthday"), source.Nickname, b.Day, month)
+	} else {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.birthday.user"), source.Nickname, login, b.Day, month)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/database"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestSeeUserRenamesPrizeWinner(t *testing.T) {
+	setupTestDatabase(t)
+	filename := filepath.Join(t.TempDir(), "prizes.json")
+	viper.Set("twitch.channels", []string{"Chan"})
+	viper.Set("event.twitch_giveaway.prizes", filename)
+	t.Cleanup(func() {
+		viper.Set("twitch.channels", nil)
+		viper.Set("event.twitch_giveaway", nil)
+	})
+
+	p, err := database.CreateGiveawayPrize(filename, database.GiveawayPrizeSingle{Name: "first"}, database.GiveawayPrizeSingle{Name: "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := p.Singles()[0], p.Singles()[1]
+	first.SetWinner("foo", time.Now(), 5*time.Minute)
+	first.Channel = "chan"
+	second.Released = []string{"foo"}
+	if err = p.SaveFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	seeUser("1001", "foo")
+	seeUser("1001", "bar")
+
+	p, err = database.NewGiveawayPrize(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prize, ok := p.PendingClaim("chan", "bar")
+	if !ok || prize.Name != "first" {
+		t.Fatalf("PendingClaim(chan, bar) after rename = %v, %v, want first", prize, ok)
+	}
+	if !prize.Claim(time.Now()) {
+		t.Error("Claim() after rename failed")
+	}
+	if !p.HasPrizeWon("bar") || p.HasPrizeWon("foo") {
+		t.Error("HasPrizeWon() after rename doesn't match the new login")
+	}
+	if released := p.Singles()[1].Released; len(released) != 1 || released[0] != "bar" {
+		t.Errorf("Released after rename = %v, want [bar]", released)
+	}
+}
@@ -18,16 +18,14 @@ import (
 	"cake4everybot/data/lang"
 	"cake4everybot/database"
 	"cake4everybot/tools/streamelements"
-	"encoding/json"
+	"errors"
 	"fmt"
 	logger "log"
 	"math/rand"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/kesuaheli/twitchgo"
-	"github.com/spf13/viper"
 )
 
 const tp string = "twitch.command."
@@ -39,6 +37,7 @@ var se *streamelements.Streamelements
 // message.
 func MessageHandler(t *twitchgo.Twitch, channel string, user *twitchgo.User, message string) {
 	log.Printf("<%s@%s> %s", user.Nickname, channel, message)
+	awardChatPoints(strings.TrimPrefix(channel, "#"), user)
 }
 
 // HandleCmdJoin is the handler for a command in a twitch chat. This handler buys a giveaway ticket
@@ -47,7 +46,22 @@ func HandleCmdJoin(t *twitchgo.Twitch, channel string, user *twitchgo.User, args
 	channel, _ = strings.CutPrefix(channel, "#")
 	const tp = tp + "join."
 
-	p, err := database.NewGiveawayPrize(viper.GetString("event.twitch_giveaway.prizes"))
+	done, ok := begin()
+	if !ok {
+		return
+	}
+	defer done()
+
+	g, config, ok := getGiveaway(t, channel, user.Nickname)
+	if !ok {
+		return
+	}
+	if !g.Open {
+		t.SendMessagef(channel, lang.GetDefault("twitch.command.generic.not_running"), user.Nickname)
+		return
+	}
+
+	p, err := database.NewGiveawayPrize(config.Prizes)
 	if err != nil {
 		log.Printf("Error reading prizes file: %v", err)
 		t.SendMessagef(channel, lang.GetDefault("twitch.command.generic.error"))
@@ -61,38 +75,26 @@ func HandleCmdJoin(t *twitchgo.Twitch, channel string, user *twitchgo.User, args
 		t.SendMessagef(channel, lang.GetDefault(tp+"msg.won"), user.Nickname)
 		return
 	}
-	entry := database.GetGiveawayEntry("tw11", user.Nickname)
+	entry := database.GetGiveawayEntry(g.Prefix, database.GiveawayPlatformTwitch, user.Nickname)
 	if entry.UserID == "" {
 		log.Printf("Error getting database giveaway entry: %v", err)
 		t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
 		return
 	}
-	if entry.Weight >= 10 {
-		t.SendMessagef(channel, lang.GetDefault(tp+"msg.max_tickets"), user.Nickname)
+	if entry.Weight >= config.MaxTickets {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.max_tickets"), user.Nickname, config.MaxTickets, config.MaxTickets)
 		return
 	}
 
-	data, err := os.ReadFile(viper.GetString("event.twitch_giveaway.times"))
-	if os.IsNotExist(err) {
-		data = []byte("{}")
-	} else if err != nil {
-		log.Printf("Error reading times file: %v", err)
-		t.SendMessagef(channel, lang.GetDefault("twitch.command.generic.error"))
-		return
-	}
-	var times = map[string]time.Time{}
-	err = json.Unmarshal(data, &times)
+	claimed := time.Now()
+	previous, ok, err := database.ClaimTwitchCooldown(channel, user.Nickname, config.Cooldown, claimed)
 	if err != nil {
-		log.Printf("Error parsing times file: %v", err)
+		log.Printf("Error claiming giveaway cooldown for '%s/%s': %v", channel, user.Nickname, err)
 		t.SendMessagef(channel, lang.GetDefault("twitch.command.generic.error"))
 		return
 	}
-
-	m := viper.GetDuration("event.twitch_giveaway.cooldown")
-	next := times[user.Nickname].Add(m * time.Minute)
-	cooldown := time.Until(next).Round(time.Second)
-
-	if cooldown > time.Second {
+	if !ok {
+		cooldown := time.Until(previous.Add(config.Cooldown)).Round(time.Second)
 		msgs := lang.GetSlice(tp+"msg.cooldown", lang.FallbackLang())
 		var i int
 		if len(msgs) >= 2 {
@@ -104,53 +106,45 @@ func HandleCmdJoin(t *twitchgo.Twitch, channel string, user *twitchgo.User, args
 		t.SendMessagef(channel, msgs[i], user.Nickname, cooldown.String())
 		return
 	}
+	// give the cooldown back if no ticket was bought
+	var bought bool
+	defer func() {
+		if bought {
+			return
+		}
+		if err := database.RestoreTwitchCooldown(channel, user.Nickname, claimed, previous); err != nil {
+			log.Printf("Error restoring giveaway cooldown for '%s/%s': %v", channel, user.Nickname, err)
+		}
+	}()
 
-	seChannel, err := se.GetChannel(channel)
+	points := getPoints(channel)
+	balance, err := points.GetPoints(channel, user.Nickname)
 	if err != nil {
-		log.Printf("Error getting streamelements channel '%s': %v", channel, err)
-		t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
-		return
-	}
-	sePoints, err := se.GetPoints(seChannel.ID, user.Nickname)
-	if err != nil {
-		log.Printf("Error getting streamelements points '%s(%s)/%s' : %v", seChannel.ID, channel, user.Nickname, err)
+		log.Printf("Error getting points of '%s/%s': %v", channel, user.Nickname, err)
 		t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
 		return
 	}
 
-	joinCost := viper.GetInt("event.twitch_giveaway.ticket_cost")
-	if sePoints.Points < joinCost {
-		t.SendMessagef(channel, lang.GetDefault(tp+"msg.too_few_points"), user.Nickname, sePoints.Points, joinCost-sePoints.Points, joinCost)
-		return
-	}
-	entry = database.AddGiveawayWeight("tw11", user.Nickname, 1)
-	if entry.UserID == "" {
-		log.Printf("Error getting database giveaway entry: %v", err)
-		t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+	joinCost := config.TicketCost
+	if balance < joinCost {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.too_few_points"), user.Nickname, balance, joinCost-balance, joinCost)
 		return
 	}
-
-	times[user.Nickname] = time.Now()
-	data, err = json.Marshal(times)
-	if err != nil {
-		log.Printf("Error marshaling times file: %v", err)
-		t.SendMessagef(channel, lang.GetDefault("twitch.command.generic.error"))
+	entry, err = buyTicket(points, channel, user.Nickname, g.Prefix, joinCost, config.MaxTickets)
+	if errors.Is(err, database.ErrMaxTickets) {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.max_tickets"), user.Nickname, config.MaxTickets, config.MaxTickets)
 		return
-	}
-	err = os.WriteFile(viper.GetString("event.twitch_giveaway.times"), data, 0644)
-	if err != nil {
-		log.Printf("Error writing times file: %v", err)
-		t.SendMessagef(channel, lang.GetDefault("twitch.command.generic.error"))
+	} else if errors.Is(err, database.ErrInsufficientPoints) {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.too_few_points"), user.Nickname, balance, joinCost-balance, joinCost)
 		return
-	}
-
-	err = se.AddPoints(seChannel.ID, user.Nickname, -joinCost)
-	if err != nil {
-		log.Printf("Error adding points for '%s(%s)/%s/-%d': %v", seChannel.ID, channel, user.Nickname, joinCost, err)
+	} else if err != nil {
+		log.Printf("Error buying ticket for '%s/%s': %v", channel, user.Nickname, err)
 		t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
 		return
 	}
-	t.SendMessagef(channel, lang.GetDefault(tp+"msg.success"), user.Nickname, joinCost, entry.Weight, sePoints.Points-joinCost)
+	bought = true
+
+	t.SendMessagef(channel, lang.GetDefault(tp+"msg.success"), user.Nickname, joinCost, entry.Weight, config.MaxTickets, balance-joinCost)
 }
 
 // HandleCmdTickets is the handler for the tickets command in a twitch chat. This handler simply
@@ -166,7 +160,12 @@ func HandleCmdTickets(t *twitchgo.Twitch, channel string, source *twitchgo.User,
 		}
 	}
 
-	p, err := database.NewGiveawayPrize(viper.GetString("event.twitch_giveaway.prizes"))
+	g, config, ok := getGiveaway(t, channel, source.Nickname)
+	if !ok {
+		return
+	}
+
+	p, err := database.NewGiveawayPrize(config.Prizes)
 	if err != nil {
 		log.Printf("Error reading prizes file: %v", err)
 		t.SendMessagef(channel, lang.GetDefault("twitch.command.generic.error"))
@@ -181,12 +180,12 @@ func HandleCmdTickets(t *twitchgo.Twitch, channel string, source *twitchgo.User,
 		return
 	}
 
-	entry := database.GetGiveawayEntry("tw11", userID)
-	if entry.Weight >= 10 {
+	entry := database.GetGiveawayEntry(g.Prefix, database.GiveawayPlatformTwitch, userID)
+	if entry.Weight >= config.MaxTickets {
 		if source.Nickname == userID {
-			t.SendMessagef(channel, lang.GetDefault(tp+"msg.max_tickets"), source.Nickname)
+			t.SendMessagef(channel, lang.GetDefault(tp+"msg.max_tickets"), source.Nickname, entry.Weight, config.MaxTickets)
 		} else {
-			t.SendMessagef(channel, lang.GetDefault(tp+"msg.max_tickets.user"), source.Nickname, userID)
+			t.SendMessagef(channel, lang.GetDefault(tp+"msg.max_tickets.user"), source.Nickname, userID, entry.Weight, config.MaxTickets)
 		}
 		return
 	}
@@ -194,7 +193,7 @@ func HandleCmdTickets(t *twitchgo.Twitch, channel string, source *twitchgo.User,
 		if entry.Weight == 0 {
 			t.SendMessagef(channel, lang.GetDefault(tp+"msg.num.0.user"), source.Nickname, userID)
 		} else {
-			t.SendMessagef(channel, lang.GetDefault(tp+"msg.num.user"), source.Nickname, userID, entry.Weight)
+			t.SendMessagef(channel, lang.GetDefault(tp+"msg.num.user"), source.Nickname, userID, entry.Weight, config.MaxTickets)
 		}
 		return
 	}
@@ -202,47 +201,29 @@ func HandleCmdTickets(t *twitchgo.Twitch, channel string, source *twitchgo.User,
 	if entry.Weight == 0 {
 		msg = fmt.Sprintf(lang.GetDefault(tp+"msg.num.0"), source.Nickname)
 	} else {
-		msg = fmt.Sprintf(lang.GetDefault(tp+"msg.num"), source.Nickname, entry.Weight)
+		msg = fmt.Sprintf(lang.GetDefault(tp+"msg.num"), source.Nickname, entry.Weight, config.MaxTickets)
 	}
 
-	var curPoints int
-	seChannel, err := se.GetChannel(channel)
+	curPoints, err := getPoints(channel).GetPoints(channel, userID)
 	if err != nil {
-		log.Printf("Error on getting SE channel: %v", err)
-		goto skipPoints
-	}
-	if sePoints, err := se.GetPoints(seChannel.ID, userID); err != nil {
-		log.Printf("Error on getting SE points: %v", err)
+		log.Printf("Error getting points of '%s/%s': %v", channel, userID, err)
 		goto skipPoints
-	} else {
-		curPoints = sePoints.Points
 	}
 
-	if joinCost := viper.GetInt("event.twitch_giveaway.ticket_cost"); joinCost > curPoints {
+	if joinCost := config.TicketCost; joinCost > curPoints {
 		msg += " " + fmt.Sprintf(lang.GetDefault(tp+"msg.extra.need_points"), joinCost-curPoints)
 	} else {
 		msg += " " + lang.GetDefault(tp+"msg.extra.can_buy")
 	}
 skipPoints:
 
-	data, err := os.ReadFile(viper.GetString("event.twitch_giveaway.times"))
-	if os.IsNotExist(err) {
-		data = []byte("{}")
-	} else if err != nil {
-		log.Printf("Error reading times file: %v", err)
-		t.SendMessagef(channel, lang.GetDefault("twitch.command.generic.error"))
-		return
-	}
-	var times = map[string]time.Time{}
-	err = json.Unmarshal(data, &times)
+	lastUsed, err := database.GetTwitchCooldown(channel, userID)
 	if err != nil {
-		log.Printf("Error parsing times file: %v", err)
+		log.Printf("Error getting giveaway cooldown for '%s/%s': %v", channel, userID, err)
 		t.SendMessagef(channel, lang.GetDefault("twitch.command.generic.error"))
 		return
 	}
-
-	m := viper.GetDuration("event.twitch_giveaway.cooldown")
-	next := times[userID].Add(m * time.Minute)
+	next := lastUsed.Add(config.Cooldown)
 	cooldown := time.Until(next).Round(time.Second)
 
 	if cooldown > 3*time.Second {
@@ -253,17 +234,30 @@ skipPoints:
 }
 
 // HandleCmdDraw is the handler for the draw command in a twitch chat. This handler selects a random
-// winner and removes their tickets.
+// winner for the next prize and removes their tickets. If a claim window is configured, the winner
+// has to claim the prize with !claim, see HandleCmdClaim.
 func HandleCmdDraw(t *twitchgo.Twitch, channel string, user *twitchgo.User, args []string) {
 	channel, _ = strings.CutPrefix(channel, "#")
 	const tp = tp + "draw."
 
+	done, ok := begin()
+	if !ok {
+		return
+	}
+	defer done()
+
 	//only accept broadcaster
 	if channel != user.Nickname {
 		return
 	}
 
-	p, err := database.NewGiveawayPrize(viper.GetString("event.twitch_giveaway.prizes"))
+	g, config, ok := getGiveaway(t, channel, user.Nickname)
+	if !ok {
+		return
+	}
+
+	defer LockPrizes(config.Prizes)()
+	p, err := database.NewGiveawayPrize(config.Prizes)
 	if err != nil {
 		log.Printf("Error reading prizes file: %v", err)
 		t.SendMessagef(channel, lang.GetDefault("twitch.command.generic.error"))
@@ -275,26 +269,13 @@ func HandleCmdDraw(t *twitchgo.Twitch, channel string, user *twitchgo.User, args
 		return
 	}
 
-	winner, totalTickets := database.DrawGiveawayWinner(database.GetAllGiveawayEntries("tw11"))
-	if totalTickets == 0 {
-		t.SendMessagef(channel, lang.GetDefault(tp+"msg.no_entries"), user.Nickname)
-		return
-	}
-
-	t.SendMessagef(channel, lang.GetDefault(tp+"msg.winner"), winner.UserID, prize.Name, winner.Weight, float64(winner.Weight*100)/float64(totalTickets))
-
-	err = database.DeleteGiveawayEntry(winner.UserID)
+	ok, err = drawPrize(t, channel, g, config, &p, prize)
 	if err != nil {
-		log.Printf("Error deleting database giveaway entry: %v", err)
+		log.Printf("Error drawing giveaway '%s': %v", g.Prefix, err)
 		t.SendMessagef(channel, lang.GetDefault("twitch.command.generic.error"))
 		return
 	}
-
-	prize.Winner = winner.UserID
-	err = p.SaveFile()
-	if err != nil {
-		log.Printf("Error saving prizes file: %v", err)
-		t.SendMessagef(channel, lang.GetDefault("twitch.command.generic.error"))
-		return
+	if !ok {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.no_entries"), user.Nickname)
 	}
 }
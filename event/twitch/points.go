@@ -0,0 +1,281 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/database"
+	"cake4everybot/event/scheduler"
+	"cake4everybot/tools/streamelements"
+	webTwitch "cake4everybot/webserver/twitch"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kesuaheli/twitchgo"
+	"github.com/spf13/viper"
+)
+
+const (
+	// pointsStreamElements uses the loyalty points of StreamElements
+	pointsStreamElements = "streamelements"
+	// pointsNative uses the points of the bot itself, see database.AddTwitchPoints
+	pointsNative = "native"
+
+	// seTimeout is the maximum time for all StreamElements requests of a single points operation,
+	// including retries
+	seTimeout = 30 * time.Second
+)
+
+func init() {
+	scheduler.Register("twitch_points_watch", "* * * * *", awardWatchPoints)
+}
+
+// PointsProvider manages the loyalty points users spend on giveaway tickets.
+type PointsProvider interface {
+	// GetPoints returns the current points of user in channel.
+	GetPoints(channel, user string) (int, error)
+	// AddPoints adds amount points to user in channel. A negative amount removes points.
+	AddPoints(channel, user string, amount int) error
+	// Top returns up to limit users with the most points in channel.
+	Top(channel string, limit int) ([]database.TwitchPointsRank, error)
+	// Rank returns the points and the rank of user in channel. The rank is 0 if the user has no
+	// points.
+	Rank(channel, user string) (database.TwitchPointsRank, error)
+}
+
+// sePoints is the PointsProvider for the loyalty points of StreamElements.
+type sePoints struct {
+	se  *streamelements.Streamelements
+	ids *seChannelCache
+}
+
+// channelID returns the StreamElements ID of channel.
+func (p sePoints) channelID(ctx context.Context, channel string) (string, error) {
+	return p.ids.resolve(ctx, p.se, channel)
+}
+
+// GetPoints implements PointsProvider.
+func (p sePoints) GetPoints(channel, user string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), seTimeout)
+	defer cancel()
+	id, err := p.channelID(ctx, channel)
+	if err != nil {
+		return 0, err
+	}
+	up, err := p.se.GetPoints(ctx, id, user)
+	if errors.Is(err, streamelements.ErrNotFound) {
+		// users without any points are unknown to StreamElements
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return up.Points, nil
+}
+
+// AddPoints implements PointsProvider.
+func (p sePoints) AddPoints(channel, user string, amount int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), seTimeout)
+	defer cancel()
+	id, err := p.channelID(ctx, channel)
+	if err != nil {
+		return err
+	}
+	return p.se.AddPoints(ctx, id, user, amount)
+}
+
+// Top implements PointsProvider.
+func (p sePoints) Top(channel string, limit int) ([]database.TwitchPointsRank, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), seTimeout)
+	defer cancel()
+	id, err := p.channelID(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+	lb, err := p.se.GetTopPoints(ctx, id, limit, 0)
+	if err != nil {
+		return nil, err
+	}
+	top := make([]database.TwitchPointsRank, 0, len(lb.Users))
+	for i, u := range lb.Users {
+		top = append(top, database.TwitchPointsRank{Username: u.Username, Points: u.Points, Rank: i + 1})
+	}
+	return top, nil
+}
+
+// Rank implements PointsProvider.
+func (p sePoints) Rank(channel, user string) (database.TwitchPointsRank, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), seTimeout)
+	defer cancel()
+	id, err := p.channelID(ctx, channel)
+	if err != nil {
+		return database.TwitchPointsRank{}, err
+	}
+	ur, err := p.se.GetUserRank(ctx, id, user)
+	if errors.Is(err, streamelements.ErrNotFound) {
+		return database.TwitchPointsRank{Username: user}, nil
+	} else if err != nil {
+		return database.TwitchPointsRank{}, err
+	}
+	return database.TwitchPointsRank{Username: ur.Username, Points: ur.Points, Rank: ur.Rank}, nil
+}
+
+// nativePoints is the PointsProvider for the points of the bot itself. Users get them for
+// chatting and watching, see pointsConfig.
+type nativePoints struct{}
+
+// GetPoints implements PointsProvider.
+func (nativePoints) GetPoints(channel, user string) (int, error) {
+	return database.GetTwitchPoints(channel, user)
+}
+
+// AddPoints implements PointsProvider.
+func (nativePoints) AddPoints(channel, user string, amount int) error {
+	_, err := database.AddTwitchPoints(channel, user, amount, database.TwitchPointsAdjust)
+	return err
+}
+
+// Top implements PointsProvider.
+func (nativePoints) Top(channel string, limit int) ([]database.TwitchPointsRank, error) {
+	return database.GetTwitchPointsTop(channel, limit, 0)
+}
+
+// Rank implements PointsProvider.
+func (nativePoints) Rank(channel, user string) (database.TwitchPointsRank, error) {
+	return database.GetTwitchPointsRank(channel, user)
+}
+
+// pointsConfig holds the points settings of a single Twitch channel
+type pointsConfig struct {
+	// Provider is either pointsStreamElements or pointsNative
+	Provider string
+	// Chat is the amount of native points for a chat message
+	Chat int
+	// ChatCooldown is the time before another chat message is rewarded
+	ChatCooldown time.Duration
+	// Watch is the amount of native points for each WatchInterval in the chat
+	Watch int
+	// WatchInterval is the time a user has to be in the chat to get Watch points
+	WatchInterval time.Duration
+}
+
+// getPointsConfig returns the points settings for channel. Like getGiveawayConfig each setting is
+// read from 'event.twitch_points.channels.<channel>' and falls back to the global one in
+// 'event.twitch_points'.
+func getPointsConfig(channel string) pointsConfig {
+	key := func(name string) string {
+		channelKey := fmt.Sprintf("event.twitch_points.channels.%s.%s", strings.ToLower(channel), name)
+		if viper.IsSet(channelKey) {
+			return channelKey
+		}
+		return "event.twitch_points." + name
+	}
+
+	config := pointsConfig{
+		Provider:      strings.ToLower(viper.GetString(key("provider"))),
+		Chat:          viper.GetInt(key("chat")),
+		ChatCooldown:  viper.GetDuration(key("chat_cooldown")) * time.Minute,
+		Watch:         viper.GetInt(key("watch")),
+		WatchInterval: viper.GetDuration(key("watch_interval")) * time.Minute,
+	}
+	if config.Provider == "" {
+		config.Provider = pointsStreamElements
+	}
+	return config
+}
+
+// getPoints returns the configured PointsProvider of channel.
+func getPoints(channel string) PointsProvider {
+	if getPointsConfig(channel).Provider == pointsNative {
+		return nativePoints{}
+	}
+	return sePoints{se: se, ids: seChannels}
+}
+
+// awardChatPoints gives native points to user for chatting in channel, if configured.
+func awardChatPoints(channel string, user *twitchgo.User) {
+	config := getPointsConfig(channel)
+	if config.Provider != pointsNative || config.Chat <= 0 || user.Nickname == viper.GetString("twitch.name") {
+		return
+	}
+	if _, err := database.AwardTwitchChatPoints(channel, user.Nickname, config.Chat, config.ChatCooldown, time.Now()); err != nil {
+		log.Printf("Error awarding chat points to '%s/%s': %v", channel, user.Nickname, err)
+	}
+}
+
+// HandleJoin is the handler for users joining a twitch chat. It starts counting their watch time
+// for native points.
+func HandleJoin(t *twitchgo.Twitch, channel string, user *twitchgo.User) {
+	channel, _ = strings.CutPrefix(channel, "#")
+	if getPointsConfig(channel).Provider != pointsNative || user.Nickname == viper.GetString("twitch.name") {
+		return
+	}
+	if err := database.JoinTwitchViewer(channel, user.Nickname, time.Now()); err != nil {
+		log.Printf("Error adding viewer '%s/%s': %v", channel, user.Nickname, err)
+	}
+}
+
+// HandlePart is the handler for users leaving a twitch chat. It stops counting their watch time.
+func HandlePart(t *twitchgo.Twitch, channel string, user *twitchgo.User) {
+	channel, _ = strings.CutPrefix(channel, "#")
+	if err := database.PartTwitchViewer(channel, user.Nickname); err != nil {
+		log.Printf("Error removing viewer '%s/%s': %v", channel, user.Nickname, err)
+	}
+}
+
+// checkWatchPoints logs every channel of channels that gives native watch points, but whose streams
+// are not received via EventSub. Watch points are only given while a stream is live, which is only
+// known from the stream.online events of the broadcasters in 'twitch.eventsub.broadcasters'. It
+// returns the number of problems.
+func checkWatchPoints(channels []string) (problems int) {
+	broadcasters := map[string]bool{}
+	if viper.GetString("twitch.clientID") != "" && slices.Contains(viper.GetStringSlice("twitch.eventsub.types"), webTwitch.TypeStreamOnline) {
+		for _, b := range viper.GetStringSlice("twitch.eventsub.broadcasters") {
+			broadcasters[strings.ToLower(b)] = true
+		}
+	}
+	for _, channel := range channels {
+		channel = strings.ToLower(strings.TrimPrefix(channel, "#"))
+		config := getPointsConfig(channel)
+		if config.Provider != pointsNative || config.Watch <= 0 || config.WatchInterval <= 0 || broadcasters[channel] {
+			continue
+		}
+		log.Printf("Error: channel '%s' gives watch points, but doesn't receive stream.online events via 'twitch.eventsub', so it never earns them", channel)
+		problems++
+	}
+	return problems
+}
+
+// awardWatchPoints is a scheduled function to run every minute. It gives native points to all
+// users that were in the chat for the configured interval while the channel is live.
+func awardWatchPoints(t *twitchgo.Twitch) {
+	for _, channel := range viper.GetStringSlice("twitch.channels") {
+		channel = strings.ToLower(channel)
+		config := getPointsConfig(channel)
+		if config.Provider != pointsNative || config.Watch <= 0 || config.WatchInterval <= 0 {
+			continue
+		}
+		n, err := database.AwardTwitchWatchPoints(channel, config.Watch, config.WatchInterval, time.Now())
+		if err != nil {
+			log.Printf("Error awarding watch points in '%s': %v", channel, err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("Awarded %d watch points to %d viewers in '%s'", config.Watch, n, channel)
+		}
+	}
+}
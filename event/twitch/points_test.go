@@ -0,0 +1,128 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/database"
+	"cake4everybot/tools/streamelements"
+	"cake4everybot/tools/streamelements/sefake"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestGetPointsConfig(t *testing.T) {
+	viper.Set("event.twitch_points.chat", 5)
+	viper.Set("event.twitch_points.chat_cooldown", 1)
+	viper.Set("event.twitch_points.channels.foo.provider", "Native")
+	viper.Set("event.twitch_points.channels.foo.watch", 10)
+	viper.Set("event.twitch_points.channels.foo.watch_interval", 10)
+	t.Cleanup(func() { viper.Set("event.twitch_points", nil) })
+
+	tests := []struct {
+		channel      string
+		want         pointsConfig
+		wantProvider PointsProvider
+	}{
+		{"bar", pointsConfig{Provider: pointsStreamElements, Chat: 5, ChatCooldown: time.Minute}, sePoints{}},
+		{"foo", pointsConfig{Provider: pointsNative, Chat: 5, ChatCooldown: time.Minute, Watch: 10, WatchInterval: 10 * time.Minute}, nativePoints{}},
+	}
+	for _, tt := range tests {
+		if got := getPointsConfig(tt.channel); got != tt.want {
+			t.Errorf("getPointsConfig(%s) = %+v, want %+v", tt.channel, got, tt.want)
+		}
+		if got := getPoints(tt.channel); reflect.TypeOf(got) != reflect.TypeOf(tt.wantProvider) {
+			t.Errorf("getPoints(%s) = %T, want %T", tt.channel, got, tt.wantProvider)
+		}
+	}
+}
+
+func TestCheckWatchPoints(t *testing.T) {
+	viper.Set("twitch.clientID", "id")
+	viper.Set("twitch.eventsub.broadcasters", []string{"Foo"})
+	viper.Set("twitch.eventsub.types", []string{"stream.online", "stream.offline"})
+	viper.Set("event.twitch_points.provider", "native")
+	viper.Set("event.twitch_points.watch", 1)
+	viper.Set("event.twitch_points.watch_interval", 10)
+	viper.Set("event.twitch_points.channels.baz.watch", 0)
+	t.Cleanup(func() {
+		viper.Set("twitch.clientID", nil)
+		viper.Set("twitch.eventsub", nil)
+		viper.Set("event.twitch_points", nil)
+	})
+
+	if problems := checkWatchPoints([]string{"foo", "#Bar", "baz"}); problems != 1 {
+		t.Errorf("checkWatchPoints() = %d problems, want 1 for bar", problems)
+	}
+	viper.Set("twitch.eventsub.types", []string{"channel.cheer"})
+	if problems := checkWatchPoints([]string{"foo"}); problems != 1 {
+		t.Errorf("checkWatchPoints() without stream.online = %d problems, want 1", problems)
+	}
+	viper.Set("twitch.eventsub.types", []string{"stream.online"})
+	viper.Set("twitch.clientID", "")
+	if problems := checkWatchPoints([]string{"foo"}); problems != 1 {
+		t.Errorf("checkWatchPoints() without client ID = %d problems, want 1", problems)
+	}
+}
+
+func TestSEPoints(t *testing.T) {
+	s := sefake.New(t, "token")
+	s.AddChannel("1", "foo")
+	s.SetPoints("1", "bar", 100)
+	p := sePoints{se: s.Client(), ids: &seChannelCache{ids: map[string]string{}}}
+
+	if err := p.AddPoints("foo", "bar", -30); err != nil {
+		t.Fatalf("AddPoints() error = %v", err)
+	}
+	if points, err := p.GetPoints("foo", "bar"); err != nil || points != 70 {
+		t.Errorf("GetPoints() = %d, %v, want 70, nil", points, err)
+	}
+	if points, err := p.GetPoints("foo", "baz"); err != nil || points != 0 {
+		t.Errorf("GetPoints() of unknown user = %d, %v, want 0, nil", points, err)
+	}
+	if _, err := p.GetPoints("unknown", "bar"); !errors.Is(err, streamelements.ErrNotFound) {
+		t.Errorf("GetPoints() in unknown channel error = %v, want %v", err, streamelements.ErrNotFound)
+	}
+
+	s.SetPoints("1", "qux", 200)
+	top, err := p.Top("foo", 5)
+	want := []database.TwitchPointsRank{{Username: "qux", Points: 200, Rank: 1}, {Username: "bar", Points: 70, Rank: 2}}
+	if err != nil || !reflect.DeepEqual(top, want) {
+		t.Errorf("Top() = %v, %v, want %v", top, err, want)
+	}
+	if r, err := p.Rank("foo", "bar"); err != nil || r.Rank != 2 {
+		t.Errorf("Rank() = %v, %v, want rank 2", r, err)
+	}
+	if r, err := p.Rank("foo", "baz"); err != nil || r.Rank != 0 {
+		t.Errorf("Rank() of unknown user = %v, %v, want rank 0", r, err)
+	}
+}
+
+func TestPointsRank(t *testing.T) {
+	setupTestDatabase(t)
+	viper.Set("event.twitch_points.channels.foo.provider", "native")
+	t.Cleanup(func() { viper.Set("event.twitch_points", nil) })
+	if _, err := database.AddTwitchPoints("foo", "bar", 50, database.TwitchPointsAdjust); err != nil {
+		t.Fatal(err)
+	}
+
+	want := database.TwitchPointsRank{Username: "bar", Points: 50, Rank: 1}
+	if r, err := PointsRank("Foo", "Bar"); err != nil || r != want {
+		t.Errorf("PointsRank() = %v, %v, want %v", r, err, want)
+	}
+}
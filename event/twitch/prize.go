@@ -0,0 +1,226 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kesuaheli/twitchgo"
+)
+
+// maxPrizeListLength is the maximum length of the prize list in a single chat message
+const maxPrizeListLength = 400
+
+// errPrizeUsage is returned by editPrize when the arguments don't match the action
+var errPrizeUsage = errors.New("invalid arguments")
+
+// prizeLocks guard reading and writing the prizes files, as commands and the scheduled redraws
+// change them concurrently. Channels may share a prizes file, so there is one lock per file.
+var (
+	prizeLocksMu sync.Mutex
+	prizeLocks   = map[string]*sync.Mutex{}
+)
+
+// LockPrizes locks the prizes file filename for a read-modify-write and returns the function to
+// unlock it again.
+func LockPrizes(filename string) (unlock func()) {
+	filename = filepath.Clean(filename)
+	prizeLocksMu.Lock()
+	mu, ok := prizeLocks[filename]
+	if !ok {
+		mu = &sync.Mutex{}
+		prizeLocks[filename] = mu
+	}
+	prizeLocksMu.Unlock()
+
+	mu.Lock()
+	return mu.Unlock
+}
+
+// HandleCmdPrize is the handler for the prize command in a twitch chat. It lets the broadcaster
+// show and edit the prizes of their channel without editing the prizes file by hand. Paths are
+// the positions shown by '!prize list', like 2 or 3.1.
+//
+//	!prize list
+//	!prize add <name>
+//	!prize addto <group path> <name>
+//	!prize move <path> <position>
+//	!prize group <path> <count> [ordered|random]
+//	!prize win <path> <user>
+//	!prize unwin <path>
+//	!prize remove <path>
+func HandleCmdPrize(t *twitchgo.Twitch, channel string, user *twitchgo.User, args []string) {
+	channel, _ = strings.CutPrefix(channel, "#")
+	const tp = tp + "prize."
+
+	//only accept broadcaster
+	if channel != user.Nickname {
+		return
+	}
+
+	done, ok := begin()
+	if !ok {
+		return
+	}
+	defer done()
+
+	if len(args) == 0 {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.usage"), user.Nickname)
+		return
+	}
+
+	filename := getGiveawayConfig(channel).Prizes
+	defer LockPrizes(filename)()
+	p, err := database.NewGiveawayPrize(filename)
+	if errors.Is(err, fs.ErrNotExist) && strings.ToLower(args[0]) == "add" && len(args) >= 2 {
+		// the first prize creates the file
+		name := strings.Join(args[1:], " ")
+		if _, err = database.CreateGiveawayPrize(filename, database.GiveawayPrizeSingle{Name: name}); err != nil {
+			log.Printf("Error creating prizes file '%s': %v", filename, err)
+			t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+			return
+		}
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.added"), user.Nickname, name, "1")
+		return
+	} else if errors.Is(err, fs.ErrNotExist) {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.empty"), user.Nickname)
+		return
+	} else if err != nil {
+		log.Printf("Error reading prizes file: %v", err)
+		t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+		return
+	}
+
+	if strings.ToLower(args[0]) == "list" {
+		list := prizeList(p)
+		if list == "" {
+			t.SendMessagef(channel, lang.GetDefault(tp+"msg.empty"), user.Nickname)
+			return
+		}
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.list"), user.Nickname, list)
+		return
+	}
+
+	key, a, err := editPrize(&p, args)
+	if errors.Is(err, errPrizeUsage) {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.usage"), user.Nickname)
+		return
+	} else if errors.Is(err, database.ErrPrizeAlreadyWon) {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.already_won"), user.Nickname, args[1])
+		return
+	} else if err != nil {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.invalid"), user.Nickname, err)
+		return
+	}
+	if err = p.SaveFile(); err != nil {
+		log.Printf("Error saving prizes file '%s': %v", filename, err)
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.invalid"), user.Nickname, err)
+		return
+	}
+	log.Printf("%s changed prizes of channel '%s': %s", user.Nickname, channel, strings.Join(args, " "))
+	t.SendMessagef(channel, lang.GetDefault(tp+key), append([]any{user.Nickname}, a...)...)
+}
+
+// editPrize applies the prize command in args to p, see HandleCmdPrize. It returns the
+// translation key of the reply and its arguments after the user name. If args don't match the
+// command errPrizeUsage is returned.
+func editPrize(p *database.GiveawayPrize, args []string) (key string, a []any, err error) {
+	action := strings.ToLower(args[0])
+	args = args[1:]
+	switch action {
+	case "add", "addto", "move", "group", "win", "unwin", "remove":
+	default:
+		return "", nil, errPrizeUsage
+	}
+	var path database.PrizePath
+	if len(args) > 0 && action != "add" {
+		if path, err = database.ParsePrizePath(args[0]); err != nil {
+			return "", nil, err
+		}
+	}
+
+	switch {
+	case action == "add" && len(args) >= 1:
+		name := strings.Join(args, " ")
+		path, err = p.AddPrize(database.PrizePath{}, name)
+		return "msg.added", []any{name, path.String()}, err
+	case action == "addto" && len(args) >= 2:
+		name := strings.Join(args[1:], " ")
+		path, err = p.AddPrize(path, name)
+		return "msg.added", []any{name, path.String()}, err
+	case action == "move" && len(args) == 2:
+		position, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "", nil, errPrizeUsage
+		}
+		newPath, err := p.MovePrize(path, position)
+		return "msg.moved", []any{path.String(), newPath.String()}, err
+	case action == "group" && (len(args) == 2 || len(args) == 3):
+		count, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "", nil, errPrizeUsage
+		}
+		sort := database.GiveawayPrizeGroupOrdered
+		if len(args) == 3 {
+			sort = database.GiveawayPrizeGroupSort(strings.ToLower(args[2]))
+		}
+		_, err = p.GroupPrizes(path, count, sort)
+		return "msg.grouped", []any{count, path.String()}, err
+	case action == "win" && len(args) == 2:
+		winner := strings.ToLower(strings.TrimPrefix(args[1], "@"))
+		err = p.SetPrizeWinner(path, winner)
+		return "msg.won", []any{path.String(), winner}, err
+	case action == "unwin" && len(args) == 1:
+		err = p.SetPrizeWinner(path, "")
+		return "msg.unwon", []any{path.String()}, err
+	case action == "remove" && len(args) == 1:
+		err = p.RemovePrize(path)
+		return "msg.removed", []any{path.String()}, err
+	}
+	return "", nil, errPrizeUsage
+}
+
+// prizeList returns all prizes of p in a single line, like '1. Foo | 2. [random] | 2.1. Bar ✓ baz'.
+// It is shortened to fit in a chat message.
+func prizeList(p database.GiveawayPrize) string {
+	var items []string
+	for _, item := range p.Items() {
+		s := fmt.Sprintf("%s. %s", item.Path, item.Name)
+		if item.Group {
+			s = fmt.Sprintf("%s. [%s]", item.Path, item.Sort)
+		} else if item.Winner != "" {
+			s += " ✓ " + item.Winner
+		}
+		items = append(items, s)
+	}
+
+	list := strings.Join(items, " | ")
+	if len(list) > maxPrizeListLength {
+		cut := strings.LastIndex(list[:maxPrizeListLength], " | ")
+		if cut < 0 {
+			cut = maxPrizeListLength
+		}
+		list = list[:cut] + " | …"
+	}
+	return list
+}
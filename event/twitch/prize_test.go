@@ -0,0 +1,68 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/database"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_editPrize(t *testing.T) {
+	p, err := database.CreateGiveawayPrize(filepath.Join(t.TempDir(), "prizes.json"), database.GiveawayPrizeSingle{Name: "Foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args    string
+		wantKey string
+		wantA   []any
+		wantErr error
+	}{
+		{"add Bar Baz", "msg.added", []any{"Bar Baz", "2"}, nil},
+		{"add Qux", "msg.added", []any{"Qux", "3"}, nil},
+		{"group 2 2 random", "msg.grouped", []any{2, "2"}, nil},
+		{"addto 2 Quux", "msg.added", []any{"Quux", "2.3"}, nil},
+		{"move 2 1", "msg.moved", []any{"2", "1"}, nil},
+		{"win 1.1 @SomeUser", "msg.won", []any{"1.1", "someuser"}, nil},
+		{"win 1.1 other", "", nil, database.ErrPrizeAlreadyWon},
+		{"unwin 1.1", "msg.unwon", []any{"1.1"}, nil},
+		{"remove 2", "msg.removed", []any{"2"}, nil},
+		{"remove 5", "", nil, database.ErrInvalidPrizePath},
+		{"move 1", "", nil, errPrizeUsage},
+		{"move 1 x", "", nil, errPrizeUsage},
+		{"foo 1", "", nil, errPrizeUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			key, a, err := editPrize(&p, strings.Fields(tt.args))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("editPrize() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (key != tt.wantKey || !reflect.DeepEqual(a, tt.wantA)) {
+				t.Errorf("editPrize() = %s, %v, want %s, %v", key, a, tt.wantKey, tt.wantA)
+			}
+		})
+	}
+
+	want := "1. [random] | 1.1. Bar Baz | 1.2. Qux | 1.3. Quux"
+	if got := prizeList(p); got != want {
+		t.Errorf("prizeList() = %q, want %q", got, want)
+	}
+}
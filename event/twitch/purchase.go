@@ -0,0 +1,61 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/database"
+	"fmt"
+)
+
+// buyTicket buys a single ticket of the giveaway with prefix for user in channel. The cost is
+// deducted from the users points first and the ticket is only added afterwards. If adding the
+// ticket fails, the points are refunded. Every step is recorded in the purchase ledger.
+//
+// The returned error is database.ErrMaxTickets (possibly wrapped) if the user already had
+// maxTickets, or database.ErrInsufficientPoints if native points are used and the user has too few.
+func buyTicket(points PointsProvider, channel, user, prefix string, cost, maxTickets int) (database.GiveawayEntry, error) {
+	p, err := database.StartTicketPurchase(channel, user, cost)
+	if err != nil {
+		return database.GiveawayEntry{}, fmt.Errorf("start purchase: %v", err)
+	}
+
+	if err = points.AddPoints(channel, user, -cost); err != nil {
+		recordPurchaseStep(p, database.PurchaseDeductFailed, 0, err)
+		return database.GiveawayEntry{}, fmt.Errorf("deduct points: %w", err)
+	}
+	recordPurchaseStep(p, database.PurchasePointsDeducted, -cost, nil)
+
+	entry, err := p.AddTicket(prefix, maxTickets)
+	if err == nil {
+		return entry, nil
+	}
+	recordPurchaseStep(p, database.PurchaseTicketFailed, 0, err)
+
+	if refundErr := points.AddPoints(channel, user, cost); refundErr != nil {
+		log.Printf("Error refunding %d points of purchase '%s' to '%s/%s': %v", cost, p.ID, channel, user, refundErr)
+		recordPurchaseStep(p, database.PurchaseRefundFailed, 0, refundErr)
+	} else {
+		recordPurchaseStep(p, database.PurchaseRefunded, cost, nil)
+	}
+	return database.GiveawayEntry{}, fmt.Errorf("add ticket: %w", err)
+}
+
+// recordPurchaseStep records step of p in the ledger and only logs on errors, as the purchase
+// itself should continue anyway.
+func recordPurchaseStep(p *database.TicketPurchase, step database.PurchaseStep, amount int, err error) {
+	if recordErr := p.Record(step, amount, err); recordErr != nil {
+		log.Printf("Error recording step '%s' of purchase '%s': %v", step, p.ID, recordErr)
+	}
+}
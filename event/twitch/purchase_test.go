@@ -0,0 +1,150 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/database"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// errFakePoints is returned by the failing calls of fakePoints
+var errFakePoints = errors.New("failed")
+
+// fakePoints records all point changes and fails the calls listed in fail
+type fakePoints struct {
+	changes []int
+	fail    map[int]bool
+}
+
+func (f *fakePoints) GetPoints(channel, username string) (int, error) {
+	return 0, nil
+}
+
+func (f *fakePoints) Top(channel string, limit int) ([]database.TwitchPointsRank, error) {
+	return nil, nil
+}
+
+func (f *fakePoints) Rank(channel, username string) (database.TwitchPointsRank, error) {
+	return database.TwitchPointsRank{Username: username}, nil
+}
+
+func (f *fakePoints) AddPoints(channel, username string, amount int) error {
+	call := len(f.changes)
+	f.changes = append(f.changes, amount)
+	if f.fail[call] {
+		return errFakePoints
+	}
+	return nil
+}
+
+func setupTestDatabase(t *testing.T) {
+	t.Helper()
+	s, err := database.NewSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = database.Migrate(s); err != nil {
+		t.Fatal(err)
+	}
+	old := database.GetStore()
+	database.SetStore(s)
+	t.Cleanup(func() {
+		database.SetStore(old)
+		s.Close()
+	})
+}
+
+func TestBuyTicket(t *testing.T) {
+	tests := []struct {
+		name        string
+		tickets     int
+		fail        map[int]bool
+		wantErr     error
+		wantWeight  int
+		wantChanges []int
+		wantSteps   []database.PurchaseStep
+	}{
+		{
+			name:        "success",
+			wantWeight:  1,
+			wantChanges: []int{-100},
+			wantSteps:   []database.PurchaseStep{database.PurchaseStarted, database.PurchasePointsDeducted, database.PurchaseTicketAdded},
+		},
+		{
+			name:        "deduct failed",
+			fail:        map[int]bool{0: true},
+			wantErr:     errFakePoints,
+			wantChanges: []int{-100},
+			wantSteps:   []database.PurchaseStep{database.PurchaseStarted, database.PurchaseDeductFailed},
+		},
+		{
+			name:        "max tickets refunded",
+			tickets:     3,
+			wantErr:     database.ErrMaxTickets,
+			wantWeight:  3,
+			wantChanges: []int{-100, 100},
+			wantSteps:   []database.PurchaseStep{database.PurchaseStarted, database.PurchasePointsDeducted, database.PurchaseTicketFailed, database.PurchaseRefunded},
+		},
+		{
+			name:        "refund failed",
+			tickets:     3,
+			fail:        map[int]bool{1: true},
+			wantErr:     database.ErrMaxTickets,
+			wantWeight:  3,
+			wantChanges: []int{-100, 100},
+			wantSteps:   []database.PurchaseStep{database.PurchaseStarted, database.PurchasePointsDeducted, database.PurchaseTicketFailed, database.PurchaseRefundFailed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDatabase(t)
+			if tt.tickets > 0 {
+				database.AddGiveawayWeight("tw11", database.GiveawayPlatformTwitch, "user", tt.tickets)
+			}
+
+			points := &fakePoints{fail: tt.fail}
+			_, err := buyTicket(points, "channel", "user", "tw11", 100, 3)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("buyTicket() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got := database.GetGiveawayEntry("tw11", database.GiveawayPlatformTwitch, "user").Weight; got != tt.wantWeight {
+				t.Errorf("got weight %d, want %d", got, tt.wantWeight)
+			}
+			if !reflect.DeepEqual(points.changes, tt.wantChanges) {
+				t.Errorf("point changes = %v, want %v", points.changes, tt.wantChanges)
+			}
+
+			var steps []database.PurchaseStep
+			rows, err := database.Query("SELECT step FROM ticket_purchases ORDER BY id")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var step database.PurchaseStep
+				if err = rows.Scan(&step); err != nil {
+					t.Fatal(err)
+				}
+				steps = append(steps, step)
+			}
+			if !reflect.DeepEqual(steps, tt.wantSteps) {
+				t.Errorf("recorded steps = %v, want %v", steps, tt.wantSteps)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"cake4everybot/event/scheduler"
+	"cake4everybot/tools/streamelements"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/kesuaheli/twitchgo"
+	"github.com/spf13/viper"
+)
+
+// redemptionLimit is the maximum number of pending redemptions handled per channel and run
+const redemptionLimit = 100
+
+// errPrizeWon is recorded for redemptions of users that already won a prize
+var errPrizeWon = errors.New("user already won a prize")
+
+func init() {
+	scheduler.Register("twitch_giveaway_redemptions", "* * * * *", redeemTickets)
+}
+
+// redeemedTicket is the result of a single redemption of the ticket item.
+type redeemedTicket struct {
+	User  string
+	Entry database.GiveawayEntry
+	// Err is database.ErrMaxTickets or errPrizeWon, if no ticket was added
+	Err error
+}
+
+// redeemTickets is a scheduled function to run every minute. In each channel with a ticket item it
+// turns the pending redemptions of the item into giveaway tickets.
+func redeemTickets(t *twitchgo.Twitch) {
+	const tp = tp + "redeem."
+
+	for _, channel := range viper.GetStringSlice("twitch.channels") {
+		channel = strings.ToLower(channel)
+		config := getGiveawayConfig(channel)
+		if config.TicketItem == "" || getPointsConfig(channel).Provider != pointsStreamElements {
+			continue
+		}
+
+		done, ok := begin()
+		if !ok {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), seTimeout)
+		tickets, err := redeemChannelTickets(ctx, se, seChannels, channel, config)
+		cancel()
+		done()
+		if err != nil {
+			log.Printf("Error redeeming tickets in '%s': %v", channel, err)
+		}
+
+		for _, r := range tickets {
+			switch {
+			case r.Err == nil:
+				log.Printf("%s redeemed a ticket in channel '%s'", r.User, channel)
+				t.SendMessagef(channel, lang.GetDefault(tp+"msg.success"), r.User, r.Entry.Weight, config.MaxTickets)
+			case errors.Is(r.Err, database.ErrMaxTickets):
+				t.SendMessagef(channel, lang.GetDefault(tp+"msg.max_tickets"), r.User, config.MaxTickets)
+			case errors.Is(r.Err, errPrizeWon):
+				t.SendMessagef(channel, lang.GetDefault(tp+"msg.won"), r.User)
+			}
+		}
+	}
+}
+
+// redeemChannelTickets adds a ticket for each pending redemption of the ticket item in channel, as
+// long as its giveaway is open and it has a prizes file. Added redemptions are completed, rejected ones stay pending, so the
+// broadcaster can refund them in StreamElements. Every redemption is recorded in the purchase
+// ledger and only handled once.
+func redeemChannelTickets(ctx context.Context, se *streamelements.Streamelements, ids *seChannelCache, channel string, config giveawayConfig) ([]redeemedTicket, error) {
+	g, ok, err := database.GetTwitchGiveaway(channel)
+	if err != nil {
+		return nil, fmt.Errorf("get giveaway: %v", err)
+	}
+	if !ok || !g.Open {
+		return nil, nil
+	}
+	p, err := database.NewGiveawayPrize(config.Prizes)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read prizes file: %v", err)
+	}
+
+	id, err := ids.resolve(ctx, se, channel)
+	if err != nil {
+		return nil, err
+	}
+	items, err := se.GetStoreItems(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get store items: %w", err)
+	}
+	var item *streamelements.StoreItem
+	for i := range items {
+		if items[i].ID == config.TicketItem || strings.EqualFold(items[i].Name, config.TicketItem) {
+			item = &items[i]
+			break
+		}
+	}
+	if item == nil {
+		return nil, fmt.Errorf("store item '%s' not found", config.TicketItem)
+	}
+
+	redemptions, err := se.GetRedemptions(ctx, id, true, redemptionLimit, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get redemptions: %w", err)
+	}
+	var tickets []redeemedTicket
+	// oldest first
+	for i := len(redemptions.Docs) - 1; i >= 0; i-- {
+		r := redemptions.Docs[i]
+		if r.Item.ID != item.ID {
+			continue
+		}
+		user := strings.ToLower(r.Redeemer.Username)
+
+		step, handled, err := database.GetPurchaseStep(r.ID)
+		if err != nil {
+			return tickets, fmt.Errorf("get redemption '%s': %v", r.ID, err)
+		}
+		if handled && step == database.PurchaseTicketFailed {
+			continue
+		}
+		if !handled || step != database.PurchaseTicketAdded {
+			ticket, err := redeemTicket(p, r.ID, channel, user, g.Prefix, item.Cost, config.MaxTickets)
+			if err != nil {
+				return tickets, fmt.Errorf("redeem '%s': %v", r.ID, err)
+			}
+			tickets = append(tickets, ticket)
+			if ticket.Err != nil {
+				continue
+			}
+		}
+
+		// a failed completion is retried in the next run, as the redemption is still pending
+		if err = se.CompleteRedemption(ctx, id, r.ID); err != nil {
+			log.Printf("Error completing redemption '%s' of '%s/%s': %v", r.ID, channel, user, err)
+		}
+	}
+	return tickets, nil
+}
+
+// redeemTicket adds a ticket for the redemption with the given ID. If user can't get another
+// ticket, the failed step is recorded and returned in Err of the result instead.
+func redeemTicket(p database.GiveawayPrize, redemptionID, channel, user, prefix string, cost, maxTickets int) (redeemedTicket, error) {
+	purchase, err := database.StartTicketRedemption(redemptionID, channel, user, cost)
+	if err != nil {
+		return redeemedTicket{}, fmt.Errorf("start redemption: %v", err)
+	}
+	ticket := redeemedTicket{User: user}
+	if p.HasPrizeWon(user) {
+		ticket.Err = errPrizeWon
+	} else {
+		ticket.Entry, err = purchase.AddTicket(prefix, maxTickets)
+		if errors.Is(err, database.ErrMaxTickets) {
+			ticket.Err = err
+		} else if err != nil {
+			// not recorded as failed, so it is tried again in the next run
+			return redeemedTicket{}, fmt.Errorf("add ticket: %v", err)
+		}
+	}
+	if ticket.Err != nil {
+		recordPurchaseStep(purchase, database.PurchaseTicketFailed, 0, ticket.Err)
+	}
+	return ticket, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/database"
+	"cake4everybot/tools/streamelements"
+	"cake4everybot/tools/streamelements/sefake"
+	"context"
+	"errors"
+	"maps"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRedeemChannelTickets(t *testing.T) {
+	setupTestDatabase(t)
+	prizes := filepath.Join(t.TempDir(), "prizes.json")
+	if _, err := database.CreateGiveawayPrize(prizes, database.GiveawayPrizeSingle{Name: "Foo"}, database.GiveawayPrizeSingle{Name: "Bar", Winner: "winner"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.StartTwitchGiveaway("chan", "tw11", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	config := giveawayConfig{MaxTickets: 2, Prizes: prizes, TicketItem: "Ticket"}
+
+	s := sefake.New(t, "token")
+	s.AddChannel("1", "chan")
+	s.AddStoreItem("1", streamelements.StoreItem{ID: "ticket", Name: "Ticket", Cost: 100})
+	redeem := func(id, user, item string) {
+		r := streamelements.Redemption{ID: id, Item: streamelements.RedemptionItem{ID: item}}
+		r.Redeemer.Username = user
+		s.AddRedemption("1", r)
+	}
+	redeem("r1", "foo", "ticket")
+	redeem("r2", "foo", "other")
+	redeem("r3", "winner", "ticket")
+	redeem("r4", "foo", "ticket")
+	redeem("r5", "foo", "ticket")
+	se := s.Client()
+	ids := &seChannelCache{ids: map[string]string{}}
+
+	tickets, err := redeemChannelTickets(context.Background(), se, ids, "chan", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantErrs := []error{nil, errPrizeWon, nil, database.ErrMaxTickets}
+	if len(tickets) != len(wantErrs) {
+		t.Fatalf("got %d tickets %+v, want %d", len(tickets), tickets, len(wantErrs))
+	}
+	for i, want := range wantErrs {
+		if !errors.Is(tickets[i].Err, want) || (want == nil && tickets[i].Err != nil) {
+			t.Errorf("ticket %d error = %v, want %v", i, tickets[i].Err, want)
+		}
+	}
+	if got := database.GetGiveawayEntry("tw11", database.GiveawayPlatformTwitch, "foo").Weight; got != 2 {
+		t.Errorf("got weight %d, want 2", got)
+	}
+	completed := map[string]bool{}
+	for _, r := range s.Redemptions("1") {
+		completed[r.ID] = r.Completed
+	}
+	if want := map[string]bool{"r1": true, "r2": false, "r3": false, "r4": true, "r5": false}; !maps.Equal(completed, want) {
+		t.Errorf("got completed %v, want %v", completed, want)
+	}
+
+	// rejected redemptions stay pending, but are only handled once
+	if tickets, err = redeemChannelTickets(context.Background(), se, ids, "chan", config); err != nil || len(tickets) != 0 {
+		t.Errorf("second run = %+v, %v, want no tickets", tickets, err)
+	}
+
+	// channels without a prizes file are skipped
+	redeem("r6", "bar", "ticket")
+	config.Prizes = filepath.Join(t.TempDir(), "missing.json")
+	if tickets, err = redeemChannelTickets(context.Background(), se, ids, "chan", config); err != nil || len(tickets) != 0 {
+		t.Errorf("run without prizes file = %+v, %v, want no tickets", tickets, err)
+	}
+}
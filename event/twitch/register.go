@@ -15,7 +15,13 @@
 package twitch
 
 import (
+	"cake4everybot/database"
 	"cake4everybot/tools/streamelements"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/kesuaheli/twitchgo"
 	"github.com/spf13/viper"
@@ -26,9 +32,63 @@ import (
 func Register(bot *twitchgo.Twitch) {
 	channels := viper.GetStringSlice("twitch.channels")
 	for _, channel := range channels {
+		// the users in the chat are sent again after joining
+		if err := database.ClearTwitchViewers(strings.ToLower(channel)); err != nil {
+			log.Printf("Error clearing viewers of channel '%s': %v", channel, err)
+		}
 		bot.SendCommandf("JOIN #%s", channel)
 	}
 	log.Printf("Channel list set to %v\n", channels)
 
-	se = streamelements.New(viper.GetString("streamelements.token"))
+	importCooldownTimes(viper.GetString("event.twitch_giveaway.times"), channels)
+	checkWatchPoints(channels)
+
+	token := viper.GetString("streamelements.token")
+	se = streamelements.New(token)
+	se.SetBaseURL(viper.GetString("streamelements.url"))
+	if timeout := viper.GetDuration("streamelements.timeout"); timeout > 0 {
+		se.SetTimeout(timeout * time.Second)
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), seStartupTimeout)
+		defer cancel()
+		if problems := checkStreamElements(ctx, se, token, channels); problems > 0 {
+			log.Printf("Found %d problem(s) with StreamElements, see above", problems)
+		}
+	}()
+}
+
+// importCooldownTimes imports the giveaway cooldowns from the old times file at path into the
+// database for each of the given channels. Afterwards the file is renamed, so it is only imported
+// once. A missing file is not an error.
+func importCooldownTimes(path string, channels []string) {
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		log.Printf("Error reading times file: %v", err)
+		return
+	}
+	var times = map[string]time.Time{}
+	if err = json.Unmarshal(data, &times); err != nil {
+		log.Printf("Error parsing times file: %v", err)
+		return
+	}
+
+	for _, channel := range channels {
+		channel, _ = strings.CutPrefix(channel, "#")
+		n, err := database.ImportTwitchCooldowns(channel, times)
+		if err != nil {
+			log.Printf("Error importing times file for channel '%s': %v", channel, err)
+			return
+		}
+		log.Printf("Imported %d giveaway cooldowns for channel '%s'", n, channel)
+	}
+
+	if err = os.Rename(path, path+".imported"); err != nil {
+		log.Printf("Error renaming imported times file: %v", err)
+	}
 }
@@ -0,0 +1,60 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"context"
+	"sync"
+)
+
+var (
+	pending      sync.WaitGroup
+	shutdownMu   sync.RWMutex
+	shuttingDown bool
+)
+
+// begin marks the start of a handler that writes data. It returns false if the bot is shutting
+// down, so the handler should not start anymore. Otherwise the returned function must be called
+// when the handler is done.
+func begin() (done func(), ok bool) {
+	shutdownMu.RLock()
+	defer shutdownMu.RUnlock()
+	if shuttingDown {
+		return nil, false
+	}
+	pending.Add(1)
+	return pending.Done, true
+}
+
+// Shutdown stops accepting new commands and waits for all running handlers to finish their
+// writes, or until ctx is done.
+func Shutdown(ctx context.Context) error {
+	shutdownMu.Lock()
+	shuttingDown = true
+	shutdownMu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		pending.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
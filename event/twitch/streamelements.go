@@ -0,0 +1,118 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/tools/streamelements"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// seStartupTimeout is the maximum time for checking StreamElements on startup
+const seStartupTimeout = time.Minute
+
+// seChannels caches the StreamElements IDs of the joined Twitch channels
+var seChannels = &seChannelCache{ids: map[string]string{}}
+
+// seChannelCache maps Twitch channel names to their StreamElements IDs.
+type seChannelCache struct {
+	mu  sync.RWMutex
+	ids map[string]string
+}
+
+// get returns the cached ID of channel.
+func (c *seChannelCache) get(channel string) (id string, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	id, ok = c.ids[strings.ToLower(channel)]
+	return id, ok
+}
+
+// set caches id as the ID of channel.
+func (c *seChannelCache) set(channel, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ids[strings.ToLower(channel)] = id
+}
+
+// resolve returns the ID of channel. If it isn't cached yet, it is looked up with se and cached.
+func (c *seChannelCache) resolve(ctx context.Context, se *streamelements.Streamelements, channel string) (string, error) {
+	if id, ok := c.get(channel); ok {
+		return id, nil
+	}
+	details, err := se.GetChannel(ctx, channel)
+	if err != nil {
+		return "", fmt.Errorf("get streamelements channel '%s': %w", channel, err)
+	}
+	c.set(channel, details.ID)
+	return details.ID, nil
+}
+
+// checkStreamElements validates the token of se and resolves the IDs of all channels using
+// StreamElements points. Every problem is logged, so a misconfiguration shows up on startup and
+// not only when the first ticket is bought. It returns the number of problems.
+func checkStreamElements(ctx context.Context, se *streamelements.Streamelements, token string, channels []string) (problems int) {
+	var seChannelNames []string
+	for _, channel := range channels {
+		channel = strings.ToLower(strings.TrimPrefix(channel, "#"))
+		if getPointsConfig(channel).Provider == pointsStreamElements {
+			seChannelNames = append(seChannelNames, channel)
+		}
+	}
+	if len(seChannelNames) == 0 {
+		return 0
+	}
+	if token == "" {
+		log.Printf("Error: the channels %v use StreamElements points, but 'streamelements.token' is not set", seChannelNames)
+		return len(seChannelNames)
+	}
+
+	accessible, err := se.GetChannels(ctx)
+	if errors.Is(err, streamelements.ErrUnauthorized) {
+		log.Printf("Error: the StreamElements token is invalid or expired, so the channels %v can't use StreamElements points: %v", seChannelNames, err)
+		return len(seChannelNames)
+	} else if err != nil {
+		// the IDs are resolved when they're first needed
+		log.Printf("Warning: could not check StreamElements on startup: %v", err)
+		return len(seChannelNames)
+	}
+	owned := map[string]string{}
+	for _, c := range accessible {
+		owned[strings.ToLower(c.Username)] = c.ID
+	}
+
+	for _, channel := range seChannelNames {
+		id, ok := owned[channel]
+		if !ok {
+			problems++
+			log.Printf("Error: the StreamElements token has no access to channel '%s', so its points can't be changed. Add the owner of the token as an editor in StreamElements or use native points.", channel)
+			if id, err = seChannels.resolve(ctx, se, channel); err != nil {
+				log.Printf("Error: could not find channel '%s' on StreamElements: %v", channel, err)
+			}
+			continue
+		}
+		if _, err = se.GetChannelDetails(ctx, id); err != nil {
+			problems++
+			log.Printf("Error: the StreamElements token can't access the details of channel '%s' (%s): %v", channel, id, err)
+		}
+		seChannels.set(channel, id)
+		log.Printf("Using StreamElements channel %s for '%s'", id, channel)
+	}
+	return problems
+}
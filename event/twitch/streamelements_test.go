@@ -0,0 +1,66 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/tools/streamelements"
+	"cake4everybot/tools/streamelements/sefake"
+	"context"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestCheckStreamElements(t *testing.T) {
+	viper.Set("event.twitch_points.channels.baz.provider", pointsNative)
+	t.Cleanup(func() { viper.Set("event.twitch_points", nil) })
+	old := seChannels
+	t.Cleanup(func() { seChannels = old })
+
+	s := sefake.New(t, "token")
+	s.AddChannel("1", "foo")
+	s.AddPublicChannel("2", "bar")
+	channels := []string{"#foo", "bar", "baz", "qux"}
+
+	tests := []struct {
+		name         string
+		token        string
+		wantProblems int
+		wantIDs      map[string]string
+	}{
+		{name: "no token", wantProblems: 3, wantIDs: map[string]string{}},
+		{name: "invalid token", token: "wrong", wantProblems: 3, wantIDs: map[string]string{}},
+		{name: "valid token", token: "token", wantProblems: 2, wantIDs: map[string]string{"foo": "1", "bar": "2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seChannels = &seChannelCache{ids: map[string]string{}}
+			se := streamelements.New(tt.token)
+			se.SetBaseURL(s.URL)
+
+			if got := checkStreamElements(context.Background(), se, tt.token, channels); got != tt.wantProblems {
+				t.Errorf("checkStreamElements() = %d problems, want %d", got, tt.wantProblems)
+			}
+			if len(seChannels.ids) != len(tt.wantIDs) {
+				t.Errorf("cached IDs %v, want %v", seChannels.ids, tt.wantIDs)
+			}
+			for channel, want := range tt.wantIDs {
+				if id, ok := seChannels.get(channel); !ok || id != want {
+					t.Errorf("cached ID of '%s' = %s, %v, want %s", channel, id, ok, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"fmt"
+	"strings"
+
+	"github.com/kesuaheli/twitchgo"
+)
+
+// topLimit is the number of users shown by the top command
+const topLimit = 5
+
+// PointsRank returns the points and the rank of user in the Twitch channel, using the configured
+// points of the channel.
+func PointsRank(channel, user string) (database.TwitchPointsRank, error) {
+	channel = strings.ToLower(channel)
+	return getPoints(channel).Rank(channel, strings.ToLower(user))
+}
+
+// PointsTop returns up to limit users with the most points in the Twitch channel, using the
+// configured points of the channel.
+func PointsTop(channel string, limit int) ([]database.TwitchPointsRank, error) {
+	channel = strings.ToLower(channel)
+	return getPoints(channel).Top(channel, limit)
+}
+
+// HandleCmdTop is the handler for the top command in a twitch chat. It prints the users with the
+// most points and the rank of the user.
+func HandleCmdTop(t *twitchgo.Twitch, channel string, user *twitchgo.User, args []string) {
+	channel, _ = strings.CutPrefix(channel, "#")
+	const tp = tp + "top."
+
+	top, err := PointsTop(channel, topLimit)
+	if err != nil {
+		log.Printf("Error getting top points of '%s': %v", channel, err)
+		t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+		return
+	}
+	if len(top) == 0 {
+		t.SendMessagef(channel, lang.GetDefault(tp+"msg.empty"), user.Nickname)
+		return
+	}
+	entries := make([]string, 0, len(top))
+	for _, r := range top {
+		entries = append(entries, fmt.Sprintf(lang.GetDefault(tp+"msg.entry"), r.Rank, r.Username, r.Points))
+	}
+
+	rank, err := PointsRank(channel, user.Nickname)
+	if err != nil {
+		log.Printf("Error getting rank of '%s/%s': %v", channel, user.Nickname, err)
+		t.SendMessage(channel, lang.GetDefault("twitch.command.generic.error"))
+		return
+	}
+	msg := fmt.Sprintf(lang.GetDefault(tp+"msg.top"), user.Nickname, strings.Join(entries, ", "))
+	if rank.Rank == 0 {
+		msg += " " + lang.GetDefault(tp+"msg.no_rank")
+	} else {
+		msg += " " + fmt.Sprintf(lang.GetDefault(tp+"msg.rank"), rank.Rank, rank.Points)
+	}
+	t.SendMessage(channel, msg)
+}
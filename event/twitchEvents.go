@@ -0,0 +1,73 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package event
+
+import (
+	"cake4everybot/event/twitch"
+	"cake4everybot/tools/helix"
+	webTwitch "cake4everybot/webserver/twitch"
+	"context"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/spf13/viper"
+)
+
+// addTwitchEventListeners sets up the handlers for Twitch EventSub notifications, see
+// webTwitch.AddDiscordHandler. Depending on 'twitch.eventsub.transport' the events are received
+// via the webhook of the webserver or via a WebSocket connection.
+func addTwitchEventListeners(ctx context.Context, s *discordgo.Session, webChan chan struct{}) {
+	hx := helix.New(viper.GetString("twitch.clientID"), viper.GetString("twitch.clientSecret"))
+	hx.SetBaseURL(viper.GetString("twitch.helix.url"), viper.GetString("twitch.helix.auth_url"))
+	twitch.SetHelix(ctx, hx)
+
+	webTwitch.SetDiscordSession(s)
+	webTwitch.AddDiscordHandler(twitch.AnnounceOnline)
+	webTwitch.AddDiscordHandler(twitch.AnnounceOffline)
+
+	if viper.GetString("twitch.clientID") == "" {
+		// without subscriptions there are no allowed broadcasters, so every event would be rejected
+		if len(viper.GetStringSlice("twitch.eventsub.broadcasters")) > 0 {
+			log.Fatalln("No twitch clientID set, but it is required for the EventSub subscriptions of 'twitch.eventsub.broadcasters'")
+		}
+		log.Println("No twitch clientID set, skipping EventSub subscriptions")
+		return
+	}
+
+	switch transport := viper.GetString("twitch.eventsub.transport"); transport {
+	case "", "webhook":
+		goTracked(func() {
+			select {
+			case <-webChan:
+			case <-ctx.Done():
+				return
+			}
+			if err := webTwitch.SetupSubscriptions(hx, webTwitch.WebhookTransport()); err != nil {
+				log.Printf("Error on setting up EventSub subscriptions: %v\n", err)
+			}
+		})
+	case "websocket":
+		// websocket subscriptions require a user access token
+		userHx := helix.New(viper.GetString("twitch.clientID"), viper.GetString("twitch.clientSecret"))
+		userHx.SetBaseURL(viper.GetString("twitch.helix.url"), viper.GetString("twitch.helix.auth_url"))
+		userToken := viper.GetString("twitch.eventsub.user_token")
+		if userToken == "" {
+			userToken = viper.GetString("twitch.token")
+		}
+		userHx.SetUserToken(userToken)
+		goTracked(func() { webTwitch.RunWebSocket(ctx, userHx) })
+	default:
+		log.Printf("Unknown EventSub transport '%s'! Use one of 'webhook', 'websocket'\n", transport)
+	}
+}
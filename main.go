@@ -19,6 +19,7 @@ import (
 	logger "log"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"cake4everybot/config"
 	"cake4everybot/database"
@@ -76,7 +77,7 @@ func main() {
 	twitchBot := twitchgo.New(viper.GetString("twitch.name"), viper.GetString("twitch.token"))
 
 	// adding listeners for events
-	event.AddListeners(discordBot, twitchBot, webChan)
+	event.AddListeners(ctx, discordBot, twitchBot, webChan)
 
 	// open connection and login to Discord and Twitch
 	log.Println("Logging in to Discord")
@@ -94,18 +95,28 @@ func main() {
 	defer twitchBot.Close()
 
 	// register all events.
-	err = event.PostRegister(discordBot, twitchBot, viper.GetString("discord.guildID"))
+	err = event.PostRegister(discordBot, twitchBot)
 	if err != nil {
 		log.Printf("Error registering events: %v\n", err)
 	}
 
 	log.Println("Starting webserver...")
 	addr := ":8080"
-	webserver.Run(addr, webChan)
+	webDone := webserver.Run(ctx, addr, webChan)
 
 	// Wait to end the bot
 	log.Println("Press Ctrl+C to exit")
 	<-ctx.Done()
 
-	log.Println("\nGracefully shutting down. Byee")
+	log.Println("\nGracefully shutting down...")
+	<-webDone
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err = event.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error on shutting down events: %v\n", err)
+	}
+
+	// the deferred calls close the Twitch and Discord sessions and the database afterwards
+	log.Println("Byee")
 }
@@ -16,7 +16,9 @@ package adventcalendar
 
 import (
 	"cake4everybot/data/lang"
+	"cake4everybot/database"
 	"cake4everybot/util"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -74,7 +76,15 @@ func (cmd Chat) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		cmd.ReplyHidden("Midnight()")
 		return
 	case "morning":
-		Post(s)
+		loc, err := database.GetGuildLocation(i.GuildID)
+		if err != nil {
+			log.Printf("ERROR: could not get timezone of guild '%s': %+v", i.GuildID, err)
+		}
+		if err = Post(s, i.GuildID, time.Now().In(loc)); err != nil {
+			log.Printf("ERROR: could not post advent calendar in guild '%s': %+v", i.GuildID, err)
+			cmd.ReplyError()
+			return
+		}
 		cmd.ReplyHidden("Post()")
 		return
 	case lang.GetDefault(tp + "option.draw"):
@@ -75,14 +75,18 @@ func (c *Component) handlePost(s *discordgo.Session, ids []string) {
 		return
 	}
 
-	if now := time.Now(); now.Year() != postTime.Year() ||
+	loc, err := database.GetGuildLocation(c.Interaction.GuildID)
+	if err != nil {
+		log.Printf("ERROR: could not get timezone of guild '%s': %+v", c.Interaction.GuildID, err)
+	}
+	if now := time.Now().In(loc); now.Year() != postTime.Year() ||
 		now.Month() != postTime.Month() ||
 		now.Day() != postTime.Day() {
 		c.ReplyHiddenSimpleEmbedf(0xFF0000, lang.GetDefault("module.adventcalendar.enter.invalid"))
 		return
 	}
 
-	entry := database.GetGiveawayEntry("xmas", c.user.ID)
+	entry := database.GetGiveawayEntry("xmas", database.GiveawayPlatformDiscord, c.user.ID)
 	if entry.UserID != c.user.ID {
 		log.Printf("ERROR: getEntry() returned with userID '%s' but want '%s'", entry.UserID, c.user.ID)
 		c.ReplyError()
@@ -93,7 +97,8 @@ func (c *Component) handlePost(s *discordgo.Session, ids []string) {
 		return
 	}
 
-	entry = database.AddGiveawayWeight("xmas", c.user.ID, 1)
+	// store the day of the guild, as it is compared to the day of the button above
+	entry = database.AddGiveawayWeightOn("xmas", database.GiveawayPlatformDiscord, c.user.ID, 1, postTime)
 
 	c.ReplyHiddenSimpleEmbedf(0x00FF00, lang.GetDefault("module.adventcalendar.enter.success"), entry.Weight)
 }
@@ -10,11 +10,17 @@ import (
 )
 
 func (cmd Chat) handleSubcommandDraw() {
-	winner, totalTickets := database.DrawGiveawayWinner(database.GetAllGiveawayEntries("xmas"))
-	if totalTickets == 0 {
+	draw, err := database.DrawGiveawayFair("xmas", 1)
+	if err != nil {
+		log.Printf("ERROR: Could not draw advent calendar giveaway: %v", err)
+		cmd.ReplyError()
+		return
+	}
+	if draw.TotalTickets == 0 {
 		cmd.ReplyHidden(lang.GetDefault(tp + "msg.no_entries.draw"))
 		return
 	}
+	winner := draw.Winners[0]
 
 	member, err := cmd.Session.GuildMember(cmd.Interaction.GuildID, winner.UserID)
 	if err != nil {
@@ -41,7 +47,7 @@ func (cmd Chat) handleSubcommandDraw() {
 			lang.GetDefault(tp+"msg.winner.details"),
 			member.Mention(),
 			winner.Weight,
-			float64(100*winner.Weight)/float64(totalTickets),
+			float64(100*winner.Weight)/float64(draw.TotalTickets),
 		),
 		Thumbnail: &discordgo.MessageEmbedThumbnail{
 			URL: member.AvatarURL(""),
@@ -49,8 +55,11 @@ func (cmd Chat) handleSubcommandDraw() {
 		Color: 0x00A000,
 		Fields: []*discordgo.MessageEmbedField{{
 			Value: fmt.Sprintf(lang.GetDefault(tp+"msg.winner.congratulation"), name),
+		}, {
+			Name:  lang.GetDefault(tp + "msg.winner.fair.title"),
+			Value: fmt.Sprintf(lang.GetDefault(tp+"msg.winner.fair"), draw.Seed, draw.Commitment, draw.EntriesHash(), draw.NextCommitment),
 		}},
 	}
 	util.SetEmbedFooter(cmd.Session, "module.adventcalendar.embed_footer", e)
-	cmd.ReplyEmbed(e)
+	cmd.ReplyFilesEmbed([]*discordgo.File{util.FairDrawFile(draw)}, e)
 }
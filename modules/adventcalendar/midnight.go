@@ -16,6 +16,7 @@ package adventcalendar
 
 import (
 	"cake4everybot/database"
+	"cake4everybot/event/scheduler"
 	"cake4everybot/util"
 	"fmt"
 	"slices"
@@ -24,6 +25,10 @@ import (
 	"github.com/bwmarrin/discordgo"
 )
 
+func init() {
+	scheduler.Register("adventcalendar_midnight", "@midnight", Midnight)
+}
+
 // Midnight is a scheduled function to run everyday at 0:00
 func Midnight(s *discordgo.Session) {
 	t := time.Now()
@@ -16,6 +16,8 @@ package adventcalendar
 
 import (
 	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"cake4everybot/event/scheduler"
 	"cake4everybot/util"
 	"fmt"
 	"os"
@@ -25,28 +27,35 @@ import (
 	"github.com/spf13/viper"
 )
 
-// Post is a scheduled function to run everyday at 8:00
-func Post(s *discordgo.Session) {
-	t := time.Now()
+func init() {
+	scheduler.RegisterGuild("adventcalendar_post", scheduler.Morning, Post)
+}
+
+// Post is a scheduled function to run everyday at 8:00 in the timezone of each guild. t is the
+// scheduled time in the timezone of the guild.
+func Post(s *discordgo.Session, guildID string, t time.Time) error {
 	if t.Month() != 12 || t.Day() > 24 {
-		return
+		return nil
 	}
-	log.Printf("New Post for %s", t.Format("_2. Jan"))
 
-	channels, err := util.GetChannelsFromDatabase(s, "adventcalendar_channel")
+	channelID, err := util.GetChannelFromDatabase(s, guildID, "adventcalendar_channel")
 	if err != nil {
-		log.Printf("ERROR: Could not get advent calendar channel: %+v", err)
-		return
+		return fmt.Errorf("get advent calendar channel of guild '%s': %v", guildID, err)
+	}
+	if channelID == "" {
+		return nil
 	}
+	log.Printf("New Post for %s in guild '%s'", t.Format("_2. Jan"), guildID)
 
-	for _, channelID := range channels {
-		data := postData(t)
-		_, err = s.ChannelMessageSendComplex(channelID, data)
-		if err != nil {
-			log.Printf("Failed to send new post for advent calendar in channel '%s': %+v", channelID, err)
-			return
-		}
+	data := postData(t)
+	if data == nil {
+		return fmt.Errorf("create post for %s", t.Format("_2. Jan"))
 	}
+	_, err = s.ChannelMessageSendComplex(channelID, data)
+	if err != nil {
+		return fmt.Errorf("send new post for advent calendar in channel '%s': %v", channelID, err)
+	}
+	return nil
 }
 
 func postData(t time.Time) *discordgo.MessageSend {
@@ -61,6 +70,11 @@ func postData(t time.Time) *discordgo.MessageSend {
 	}
 	line2 := lang.GetDefault("module.adventcalendar.post.message2")
 	message := fmt.Sprintf("%s\n%s", line1, line2)
+	if commitment, err := database.GetGiveawayCommitment("xmas"); err != nil {
+		log.Printf("ERROR: could not get commitment of the advent calendar giveaway: %+v", err)
+	} else {
+		message += "\n" + fmt.Sprintf(lang.GetDefault("module.adventcalendar.post.commitment"), commitment)
+	}
 
 	components := []discordgo.MessageComponent{
 		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
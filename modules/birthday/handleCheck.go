@@ -16,58 +16,44 @@ package birthday
 
 import (
 	"cake4everybot/data/lang"
-	"cake4everybot/database"
+	"cake4everybot/event/scheduler"
 	"cake4everybot/util"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
-// Check checks if there are any birthdays on the current date (time.Now()), if so announce them
-// in the desired channel.
-func Check(s *discordgo.Session) {
-	var guildID, channelID uint64
-	rows, err := database.Query("SELECT id,birthday_id FROM guilds")
-	if err != nil {
-		log.Printf("Error on getting birthday channel IDs from database: %v\n", err)
-	}
-	defer rows.Close()
+func init() {
+	scheduler.RegisterGuild("birthday_check", scheduler.Morning, Check)
+}
 
-	now := time.Now()
-	birthdays, err := getBirthdaysDate(now.Day(), int(now.Month()))
+// Check checks if there are any birthdays on the date of t, if so announce them in the birthday
+// channel of the given guild. t is the scheduled time in the timezone of the guild.
+func Check(s *discordgo.Session, guildID string, t time.Time) error {
+	birthdays, err := getBirthdaysDate(t.Day(), int(t.Month()))
 	if err != nil {
-		log.Printf("Error on getting todays birthdays from database: %v\n", err)
+		return fmt.Errorf("get todays birthdays from database: %v", err)
 	}
 	e, n := birthdayAnnounceEmbed(s, birthdays)
 	if n <= 0 {
-		return
+		return nil
 	}
 
-	for rows.Next() {
-		err = rows.Scan(&guildID, &channelID)
-		if err != nil {
-			log.Printf("Error on scanning birthday channel ID from database %v\n", err)
-			continue
-		}
-
-		channel, err := s.Channel(fmt.Sprint(channelID))
-		if err != nil {
-			log.Printf("Error on getting birthday channel for id: %v\n", err)
-			return
-		}
-		if channel.GuildID != fmt.Sprint(guildID) {
-			log.Printf("Warning: tried to announce birthdays in channel/%d/%d, but this channel is from guild: '%s'\n", guildID, channelID, channel.GuildID)
-			return
-		}
+	channelID, err := util.GetChannelFromDatabase(s, guildID, "birthday_id")
+	if err != nil {
+		return fmt.Errorf("get birthday channel ID of guild '%s' from database: %v", guildID, err)
+	}
+	if channelID == "" {
+		return nil
+	}
 
-		// announce
-		_, err = s.ChannelMessageSendEmbed(channel.ID, e)
-		if err != nil {
-			log.Printf("Error on sending todays birthday announcement: %s\n", err)
-		}
+	// announce
+	_, err = s.ChannelMessageSendEmbed(channelID, e)
+	if err != nil {
+		return fmt.Errorf("send todays birthday announcement: %v", err)
 	}
+	return nil
 }
 
 // birthdayAnnounceEmbed returns the embed, that contains all birthdays and 'n' as the number of
@@ -310,7 +310,7 @@ func (cmd subcommandSet) handleUpdate(b birthdayEntry, e *discordgo.MessageEmbed
 			key := tp + "msg.set.update.visibility.false"
 			visibility = lang.Get(key, lang.FallbackLang())
 
-			mentionCmd := util.MentionCommand(tp+"base", tp+"option.remove")
+			mentionCmd := util.MentionCommand(cmd.Interaction.GuildID, tp+"base", tp+"option.remove")
 			visibility = fmt.Sprintf(visibility, mentionCmd)
 		}
 		util.AddEmbedField(e,
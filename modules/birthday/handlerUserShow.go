@@ -61,7 +61,7 @@ func (cmd UserShow) handler() {
 	if !hasBDay {
 		if self {
 			format := lang.GetDefault(tp + "msg.no_entry")
-			mentionCmd := util.MentionCommand(tp+"base", tp+"option.set")
+			mentionCmd := util.MentionCommand(cmd.Interaction.GuildID, tp+"base", tp+"option.set")
 			embed.Description = fmt.Sprintf(format, mentionCmd)
 		} else {
 			format := lang.GetDefault(tp + "msg.no_entry.user")
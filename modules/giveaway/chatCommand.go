@@ -0,0 +1,266 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package giveaway
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"cake4everybot/util"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Chat represents the giveaway chat command. It starts giveaways with an entry button, which are
+// drawn automatically when they end, and edits their prizes.
+type Chat struct {
+	giveawayBase
+	ID string
+}
+
+// AppCmd (ApplicationCommand) returns the definition of the chat command
+func (Chat) AppCmd() *discordgo.ApplicationCommand {
+	var (
+		permission int64 = discordgo.PermissionManageServer
+		dm               = false
+		minWinners       = 1.0
+	)
+
+	return &discordgo.ApplicationCommand{
+		Name:                     lang.GetDefault(tp + "base"),
+		NameLocalizations:        util.TranslateLocalization(tp + "base"),
+		Description:              lang.GetDefault(tp + "base.description"),
+		DescriptionLocalizations: util.TranslateLocalization(tp + "base.description"),
+		DefaultMemberPermissions: &permission,
+		DMPermission:             &dm,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:                     discordgo.ApplicationCommandOptionSubCommand,
+				Name:                     lang.GetDefault(tp + "option.create"),
+				NameLocalizations:        *util.TranslateLocalization(tp + "option.create"),
+				Description:              lang.GetDefault(tp + "option.create.description"),
+				DescriptionLocalizations: *util.TranslateLocalization(tp + "option.create.description"),
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:                     discordgo.ApplicationCommandOptionString,
+						Name:                     lang.GetDefault(tp + "option.create.option.prize"),
+						NameLocalizations:        *util.TranslateLocalization(tp + "option.create.option.prize"),
+						Description:              lang.GetDefault(tp + "option.create.option.prize.description"),
+						DescriptionLocalizations: *util.TranslateLocalization(tp + "option.create.option.prize.description"),
+						Required:                 true,
+						MaxLength:                200,
+					},
+					{
+						Type:                     discordgo.ApplicationCommandOptionString,
+						Name:                     lang.GetDefault(tp + "option.create.option.duration"),
+						NameLocalizations:        *util.TranslateLocalization(tp + "option.create.option.duration"),
+						Description:              lang.GetDefault(tp + "option.create.option.duration.description"),
+						DescriptionLocalizations: *util.TranslateLocalization(tp + "option.create.option.duration.description"),
+						Required:                 true,
+						MaxLength:                16,
+					},
+					{
+						Type:                     discordgo.ApplicationCommandOptionInteger,
+						Name:                     lang.GetDefault(tp + "option.create.option.winners"),
+						NameLocalizations:        *util.TranslateLocalization(tp + "option.create.option.winners"),
+						Description:              lang.GetDefault(tp + "option.create.option.winners.description"),
+						DescriptionLocalizations: *util.TranslateLocalization(tp + "option.create.option.winners.description"),
+						MinValue:                 &minWinners,
+						MaxValue:                 maxWinners,
+					},
+					{
+						Type:                     discordgo.ApplicationCommandOptionRole,
+						Name:                     lang.GetDefault(tp + "option.create.option.role"),
+						NameLocalizations:        *util.TranslateLocalization(tp + "option.create.option.role"),
+						Description:              lang.GetDefault(tp + "option.create.option.role.description"),
+						DescriptionLocalizations: *util.TranslateLocalization(tp + "option.create.option.role.description"),
+					},
+				},
+			},
+			{
+				Type:                     discordgo.ApplicationCommandOptionSubCommand,
+				Name:                     lang.GetDefault(tp + "option.end"),
+				NameLocalizations:        *util.TranslateLocalization(tp + "option.end"),
+				Description:              lang.GetDefault(tp + "option.end.description"),
+				DescriptionLocalizations: *util.TranslateLocalization(tp + "option.end.description"),
+				Options: []*discordgo.ApplicationCommandOption{{
+					Type:                     discordgo.ApplicationCommandOptionString,
+					Name:                     lang.GetDefault(tp + "option.end.option.id"),
+					NameLocalizations:        *util.TranslateLocalization(tp + "option.end.option.id"),
+					Description:              lang.GetDefault(tp + "option.end.option.id.description"),
+					DescriptionLocalizations: *util.TranslateLocalization(tp + "option.end.option.id.description"),
+					Required:                 true,
+					MaxLength:                64,
+				}},
+			},
+			prizesAppCmdOption(),
+		},
+	}
+}
+
+// Handle handles the functionality of a command
+func (cmd Chat) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	cmd.InteractionUtil = util.InteractionUtil{Session: s, Interaction: i}
+	cmd.member = i.Member
+	cmd.user = i.User
+	if i.Member != nil {
+		cmd.user = i.Member.User
+	} else if i.User != nil {
+		cmd.member = &discordgo.Member{User: i.User}
+	}
+
+	if !cmd.hasPermission() {
+		cmd.ReplyHiddenSimpleEmbed(0xFF0000, lang.GetDefault(tp+"msg.no_permission"))
+		return
+	}
+
+	subcommand := i.ApplicationCommandData().Options[0]
+	switch subcommand.Name {
+	case lang.GetDefault(tp + "option.create"):
+		cmd.handleSubcommandCreate(subcommand.Options)
+		return
+	case lang.GetDefault(tp + "option.end"):
+		cmd.handleSubcommandEnd(subcommand.Options)
+		return
+	case lang.GetDefault(tp + "option.prizes"):
+		cmd.handleSubcommandGroupPrizes(subcommand.Options)
+		return
+	}
+}
+
+func (cmd Chat) handleSubcommandCreate(options []*discordgo.ApplicationCommandInteractionDataOption) {
+	g := database.DiscordGiveaway{
+		Name:      "dc-" + cmd.Interaction.ID,
+		GuildID:   cmd.Interaction.GuildID,
+		ChannelID: cmd.Interaction.ChannelID,
+		HostID:    cmd.user.ID,
+		Winners:   1,
+	}
+	var duration string
+	for _, o := range options {
+		switch o.Name {
+		case lang.GetDefault(tp + "option.create.option.prize"):
+			g.Prize = strings.TrimSpace(o.StringValue())
+		case lang.GetDefault(tp + "option.create.option.duration"):
+			duration = o.StringValue()
+		case lang.GetDefault(tp + "option.create.option.winners"):
+			g.Winners = int(o.IntValue())
+		case lang.GetDefault(tp + "option.create.option.role"):
+			g.RoleID = o.RoleValue(nil, "").ID
+		}
+	}
+
+	d, err := parseDuration(duration)
+	if err != nil || d < time.Minute || d > maxDuration {
+		cmd.ReplyHiddenSimpleEmbedf(0xFF0000, lang.GetDefault(tp+"msg.invalid_duration"), duration, int(maxDuration.Hours()/24))
+		return
+	}
+	g.EndsAt = time.Now().Add(d).UTC().Truncate(time.Second)
+
+	prizes := make([]database.GiveawayPrizeSingle, g.Winners)
+	for i := range prizes {
+		prizes[i].Name = g.Prize
+	}
+	filename := prizeFile(g.Name)
+	if err = os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		log.Printf("ERROR: could not create prize directory for giveaway '%s': %v", g.Name, err)
+		cmd.ReplyError()
+		return
+	}
+	if _, err = database.CreateGiveawayPrize(filename, prizes...); err != nil {
+		log.Printf("ERROR: could not create prizes of giveaway '%s': %v", g.Name, err)
+		cmd.ReplyError()
+		return
+	}
+
+	commitment, err := database.GetGiveawayCommitment(g.Name)
+	if err != nil {
+		log.Printf("ERROR: could not get commitment of giveaway '%s': %v", g.Name, err)
+		cmd.ReplyError()
+		return
+	}
+
+	// the giveaway is only stored once its message exists, so it never ends without one
+	msg, err := cmd.Session.ChannelMessageSendComplex(g.ChannelID, giveawayMessage(cmd.Session, g, commitment, nil))
+	if err != nil {
+		log.Printf("ERROR: could not send message of giveaway '%s' to channel '%s': %v", g.Name, g.ChannelID, err)
+		os.Remove(filename)
+		cmd.ReplyError()
+		return
+	}
+	g.MessageID = msg.ID
+	if err = database.AddDiscordGiveaway(g); err != nil {
+		log.Printf("ERROR: could not add giveaway '%s': %v", g.Name, err)
+		if err = cmd.Session.ChannelMessageDelete(g.ChannelID, msg.ID); err != nil {
+			log.Printf("Warning: could not delete message of giveaway '%s': %v", g.Name, err)
+		}
+		os.Remove(filename)
+		cmd.ReplyError()
+		return
+	}
+	log.Printf("%s created giveaway '%s' for '%s' in guild %s, ending at %s", cmd.user.Username, g.Name, g.Prize, g.GuildID, g.EndsAt)
+
+	cmd.ReplyHiddenSimpleEmbedf(0x00FF00, lang.GetDefault(tp+"msg.created"), g.Prize, g.Name)
+}
+
+func (cmd Chat) handleSubcommandEnd(options []*discordgo.ApplicationCommandInteractionDataOption) {
+	if len(options) == 0 {
+		cmd.ReplyError()
+		return
+	}
+	name := strings.Trim(strings.TrimSpace(options[0].StringValue()), "`")
+
+	g, ok, err := database.GetDiscordGiveaway(name)
+	if err != nil {
+		log.Printf("ERROR: could not get giveaway '%s': %v", name, err)
+		cmd.ReplyError()
+		return
+	}
+	if !ok || g.GuildID != cmd.Interaction.GuildID {
+		cmd.ReplyHiddenSimpleEmbedf(0xFF0000, lang.GetDefault(tp+"msg.not_found"), name)
+		return
+	}
+	if g.Ended {
+		cmd.ReplyHiddenSimpleEmbed(0xFF0000, lang.GetDefault(tp+"msg.already_ended"))
+		return
+	}
+
+	cmd.ReplyDeferedHidden()
+	ok, err = end(cmd.Session, g)
+	if err != nil {
+		log.Printf("ERROR: could not end giveaway '%s': %v", g.Name, err)
+		cmd.ReplyError()
+		return
+	}
+	if !ok {
+		cmd.ReplyHiddenSimpleEmbed(0xFF0000, lang.GetDefault(tp+"msg.already_ended"))
+		return
+	}
+	log.Printf("%s ended giveaway '%s' early", cmd.user.Username, g.Name)
+	cmd.ReplyHiddenSimpleEmbedf(0x00FF00, lang.GetDefault(tp+"msg.ended"), g.Prize)
+}
+
+// SetID sets the registered command ID for internal uses after uploading to discord
+func (cmd *Chat) SetID(id string) {
+	cmd.ID = id
+}
+
+// GetID gets the registered command ID
+func (cmd Chat) GetID() string {
+	return cmd.ID
+}
@@ -0,0 +1,149 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package giveaway
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"cake4everybot/event/scheduler"
+	"cake4everybot/util"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// prizeMu guards reading and writing the prize files, as the claim button and the scheduled
+// redraws change them concurrently.
+var prizeMu sync.Mutex
+
+func init() {
+	scheduler.Register("giveaway_claims", "* * * * *", CheckClaims)
+}
+
+func (c Component) handleClaim(ids []string) {
+	name := util.ShiftL(ids)
+
+	prizeMu.Lock()
+	defer prizeMu.Unlock()
+
+	p, err := database.NewGiveawayPrize(prizeFile(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		c.ReplyHiddenSimpleEmbed(0xFF0000, lang.GetDefault(tp+"claim.no_claim"))
+		return
+	} else if err != nil {
+		log.Printf("ERROR: could not read prizes of giveaway '%s': %v", name, err)
+		c.ReplyError()
+		return
+	}
+
+	prize, ok := p.PendingClaim("", c.user.ID)
+	if !ok {
+		c.ReplyHiddenSimpleEmbed(0xFF0000, lang.GetDefault(tp+"claim.no_claim"))
+		return
+	}
+	if !prize.Claim(time.Now()) {
+		c.ReplyHiddenSimpleEmbedf(0xFF0000, lang.GetDefault(tp+"claim.too_late"), prize.Name)
+		return
+	}
+	if err = p.SaveFile(); err != nil {
+		log.Printf("ERROR: could not save prizes of giveaway '%s': %v", name, err)
+		c.ReplyError()
+		return
+	}
+	log.Printf("%s claimed '%s' of giveaway '%s'", c.user.Username, prize.Name, name)
+
+	c.ReplyHiddenSimpleEmbedf(0x00FF00, lang.GetDefault(tp+"claim.success"), prize.Name)
+}
+
+// CheckClaims is a scheduled function to run every minute. It releases all prizes of ended
+// giveaways whose winner didn't claim them in time and draws new winners for them.
+func CheckClaims(s *discordgo.Session) {
+	now := time.Now()
+	giveaways, err := database.GetEndedDiscordGiveaways(now.Add(-maxClaimAge))
+	if err != nil {
+		log.Printf("ERROR: could not get ended giveaways: %v", err)
+		return
+	}
+	for _, g := range giveaways {
+		checkClaims(s, g, now)
+	}
+}
+
+// checkClaims is like CheckClaims for the single giveaway g.
+func checkClaims(s *discordgo.Session, g database.DiscordGiveaway, now time.Time) {
+	prizeMu.Lock()
+	defer prizeMu.Unlock()
+
+	p, err := database.NewGiveawayPrize(prizeFile(g.Name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	} else if err != nil {
+		log.Printf("ERROR: could not read prizes of giveaway '%s': %v", g.Name, err)
+		return
+	}
+	expired := p.ExpiredClaims("", now)
+	if len(expired) == 0 {
+		return
+	}
+
+	for _, prize := range expired {
+		oldWinner := prize.Winner
+		prize.Release()
+		log.Printf("'%s' didn't claim '%s' of giveaway '%s' in time", oldWinner, prize.Name, g.Name)
+
+		draw, winners, err := database.DrawGiveawayFairExcluding(g.Name, 1, p.RedrawExclusions(prize))
+		if err != nil {
+			// the unsaved release is dropped, so the prize is redrawn in the next run
+			log.Printf("ERROR: could not redraw '%s' of giveaway '%s': %v", prize.Name, g.Name, err)
+			return
+		}
+
+		data := &discordgo.MessageSend{
+			Content: fmt.Sprintf(lang.GetDefault(tp+"msg.no_redraw"), oldWinner, prize.Name),
+		}
+		if len(winners) > 0 {
+			prize.SetWinner(winners[0].UserID, now, claimWindow())
+			data.Content = fmt.Sprintf(lang.GetDefault(tp+"msg.redraw"), oldWinner, prize.Name, winners[0].UserID)
+			if prize.ClaimDeadline != nil {
+				data.Content += "\n" + fmt.Sprintf(lang.GetDefault(tp+"msg.claim"), prize.ClaimDeadline.Unix())
+				data.Components = claimComponents(g)
+			}
+			e := &discordgo.MessageEmbed{
+				Title:       lang.GetDefault(tp + "msg.fair.title"),
+				Description: fmt.Sprintf(lang.GetDefault(tp+"msg.fair"), draw.Seed, draw.Commitment, draw.TotalTickets, draw.EntriesHash()),
+				Color:       0x00A000,
+			}
+			util.SetEmbedFooter(s, tp+"display", e)
+			data.Embeds = []*discordgo.MessageEmbed{e}
+			data.Files = []*discordgo.File{util.FairDrawFile(draw)}
+		}
+		if g.MessageID != "" {
+			data.Reference = &discordgo.MessageReference{MessageID: g.MessageID, ChannelID: g.ChannelID, GuildID: g.GuildID}
+		}
+
+		// save the new winner before announcing them, so they are never announced twice
+		if err = p.SaveFile(); err != nil {
+			log.Printf("ERROR: could not save prizes of giveaway '%s': %v", g.Name, err)
+			return
+		}
+		if _, err = s.ChannelMessageSendComplex(g.ChannelID, data); err != nil {
+			log.Printf("ERROR: could not announce redraw of giveaway '%s': %v", g.Name, err)
+		}
+	}
+}
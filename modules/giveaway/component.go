@@ -0,0 +1,103 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package giveaway
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"cake4everybot/util"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Component handles the entry button of a giveaway message and the claim button of its winners.
+type Component struct {
+	giveawayBase
+	data discordgo.MessageComponentInteractionData
+}
+
+// Handle handles the functionality of a component.
+func (c Component) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	c.InteractionUtil = util.InteractionUtil{Session: s, Interaction: i}
+	c.member = i.Member
+	c.user = i.User
+	if i.Member != nil {
+		c.user = i.Member.User
+	} else if i.User != nil {
+		c.member = &discordgo.Member{User: i.User}
+	}
+	c.data = i.MessageComponentData()
+
+	ids := strings.Split(c.data.CustomID, ".")
+	// pop the first level identifier
+	util.ShiftL(ids)
+
+	switch util.ShiftL(ids) {
+	case "enter":
+		c.handleEnter(ids)
+		return
+	case "claim":
+		c.handleClaim(ids)
+		return
+	default:
+		log.Printf("Unknown component interaction ID: %s", c.data.CustomID)
+	}
+}
+
+// ID returns the custom ID of the modal to identify the module
+func (Component) ID() string {
+	return "giveaway"
+}
+
+func (c Component) handleEnter(ids []string) {
+	name := util.ShiftL(ids)
+	g, ok, err := database.GetDiscordGiveaway(name)
+	if err != nil {
+		log.Printf("ERROR: could not get giveaway '%s': %v", name, err)
+		c.ReplyError()
+		return
+	}
+	if !ok {
+		log.Printf("ERROR: got entry for unknown giveaway '%s'", name)
+		c.ReplyError()
+		return
+	}
+	if g.Ended || !time.Now().Before(g.EndsAt) {
+		c.ReplyHiddenSimpleEmbed(0xFF0000, lang.GetDefault(tp+"enter.ended"))
+		return
+	}
+	if g.RoleID != "" && !slices.Contains(c.member.Roles, g.RoleID) {
+		c.ReplyHiddenSimpleEmbedf(0xFF0000, lang.GetDefault(tp+"enter.missing_role"), fmt.Sprintf("<@&%s>", g.RoleID))
+		return
+	}
+
+	ok, err = database.EnterGiveaway(name, database.GiveawayPlatformDiscord, c.user.ID)
+	if err != nil {
+		log.Printf("ERROR: could not enter '%s' to giveaway '%s': %v", c.user.ID, name, err)
+		c.ReplyError()
+		return
+	}
+	if !ok {
+		c.ReplyHiddenSimpleEmbed(0xFCB100, lang.GetDefault(tp+"enter.already_entered"))
+		return
+	}
+	log.Printf("%s entered giveaway '%s'", c.user.Username, name)
+
+	c.ReplyHiddenSimpleEmbedf(0x00FF00, lang.GetDefault(tp+"enter.success"), g.Prize)
+}
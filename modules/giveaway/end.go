@@ -0,0 +1,166 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package giveaway
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"cake4everybot/event/scheduler"
+	"cake4everybot/util"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// endMu guards ending and announcing giveaways, as the scheduler and the end command may end the
+// same giveaway concurrently.
+var endMu sync.Mutex
+
+func init() {
+	scheduler.Register("giveaway_end", "* * * * *", EndDue)
+}
+
+// EndDue is a scheduled function to run every minute. It ends all giveaways whose end time has
+// passed and announces their winners. Announcements that failed before are retried.
+func EndDue(s *discordgo.Session) {
+	giveaways, err := database.GetDueDiscordGiveaways(time.Now())
+	if err != nil {
+		log.Printf("ERROR: could not get due giveaways: %v", err)
+		return
+	}
+	for _, g := range giveaways {
+		if _, err = end(s, g); err != nil {
+			log.Printf("ERROR: could not end giveaway '%s': %v", g.Name, err)
+		}
+	}
+}
+
+// end closes g, draws its winners and announces them in the channel of g. The winners get the next
+// prizes from the prize file of g. If g is already ended, but the announcement failed before, only
+// the announcement is repeated. ok is false if the winners of g were already announced, e.g. by a
+// concurrent call.
+func end(s *discordgo.Session, g database.DiscordGiveaway) (ok bool, err error) {
+	endMu.Lock()
+	defer endMu.Unlock()
+
+	g, ok, err = database.GetDiscordGiveaway(g.Name)
+	if err != nil || !ok || g.Announced {
+		return false, err
+	}
+
+	var draw database.FairDraw
+	if !g.Ended {
+		draw, ok, err = database.EndDiscordGiveaway(g.Name, g.Winners)
+		if err != nil || !ok {
+			return false, err
+		}
+		g.Ended = true
+		g.DrawID = draw.ID
+		log.Printf("Ended giveaway '%s' with %d winner(s) out of %d tickets", g.Name, len(draw.Winners), draw.TotalTickets)
+	} else if g.DrawID != 0 {
+		draw, ok, err = database.GetFairDraw(g.DrawID)
+		if err != nil {
+			return false, fmt.Errorf("get draw: %v", err)
+		} else if !ok {
+			return false, fmt.Errorf("draw %d not found", g.DrawID)
+		}
+	}
+
+	deadline := assignPrizes(g, draw.Winners)
+
+	data := &discordgo.MessageSend{
+		Content: fmt.Sprintf(lang.GetDefault(tp+"msg.no_entries"), g.Prize),
+	}
+	if len(draw.Winners) > 0 {
+		data.Content = fmt.Sprintf(lang.GetDefault(tp+"msg.winners"), mentionWinners(draw.Winners), g.Prize)
+		if deadline != nil {
+			data.Content += "\n" + fmt.Sprintf(lang.GetDefault(tp+"msg.claim"), deadline.Unix())
+			data.Components = claimComponents(g)
+		}
+		e := &discordgo.MessageEmbed{
+			Title:       lang.GetDefault(tp + "msg.fair.title"),
+			Description: fmt.Sprintf(lang.GetDefault(tp+"msg.fair"), draw.Seed, draw.Commitment, draw.TotalTickets, draw.EntriesHash()),
+			Color:       0x00A000,
+		}
+		util.SetEmbedFooter(s, tp+"display", e)
+		data.Embeds = []*discordgo.MessageEmbed{e}
+		data.Files = []*discordgo.File{util.FairDrawFile(draw)}
+	}
+	if g.MessageID != "" {
+		data.Reference = &discordgo.MessageReference{MessageID: g.MessageID, ChannelID: g.ChannelID, GuildID: g.GuildID}
+	}
+	if _, err = s.ChannelMessageSendComplex(g.ChannelID, data); err != nil {
+		return true, fmt.Errorf("announce winners: %v", err)
+	}
+	if err = database.SetDiscordGiveawayAnnounced(g.Name); err != nil {
+		return true, fmt.Errorf("set announced: %v", err)
+	}
+
+	if g.MessageID != "" {
+		msg := giveawayMessage(s, g, draw.Commitment, draw.Winners)
+		if _, err = s.ChannelMessageEditComplex(util.MessageComplexEdit(msg, g.ChannelID, g.MessageID)); err != nil {
+			log.Printf("Warning: could not update message of giveaway '%s': %v", g.Name, err)
+		}
+	}
+	return true, nil
+}
+
+// assignPrizes sets the winners of the next available prizes in the prize file of g. Winners that
+// already got a prize, e.g. before a failed announcement, keep it. If a claim window is configured,
+// it returns the deadline until the winners have to claim their prizes. Errors are only logged, as
+// the winners are already stored with the draw.
+func assignPrizes(g database.DiscordGiveaway, winners []database.GiveawayEntry) (deadline *time.Time) {
+	if len(winners) == 0 {
+		return nil
+	}
+
+	prizeMu.Lock()
+	defer prizeMu.Unlock()
+	p, err := database.NewGiveawayPrize(prizeFile(g.Name))
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Warning: giveaway '%s' has no prize file", g.Name)
+		return
+	} else if err != nil {
+		log.Printf("ERROR: could not read prizes of giveaway '%s': %v", g.Name, err)
+		return nil
+	}
+	now := time.Now()
+	for _, w := range winners {
+		if p.HasPrizeWon(w.UserID) {
+			if prize, ok := p.PendingClaim("", w.UserID); ok {
+				deadline = prize.ClaimDeadline
+			}
+			continue
+		}
+		prize, ok := p.GetNextPrize()
+		if !ok {
+			log.Printf("Warning: giveaway '%s' has no prize left for winner '%s'", g.Name, w.UserID)
+			break
+		}
+		prize.SetWinner(w.UserID, now, claimWindow())
+		if prize.ClaimDeadline != nil {
+			deadline = prize.ClaimDeadline
+		}
+	}
+	if err = p.SaveFile(); err != nil {
+		log.Printf("ERROR: could not save prizes of giveaway '%s': %v", g.Name, err)
+		return nil
+	}
+	return deadline
+}
@@ -0,0 +1,161 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package giveaway
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"cake4everybot/util"
+	"fmt"
+	logger "log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Prefix for translation key, i.e.:
+	//   key := tp+"base" // => giveaway
+	tp = "discord.command.giveaway."
+
+	// maxDuration is the longest a giveaway can run
+	maxDuration = 30 * 24 * time.Hour
+	// maxWinners is the highest number of winners of a single giveaway
+	maxWinners = 20
+	// maxClaimAge is how long after its end the claims of a giveaway are still checked
+	maxClaimAge = 30 * 24 * time.Hour
+)
+
+var log = logger.New(logger.Writer(), "[Giveaway] ", logger.LstdFlags|logger.Lmsgprefix)
+
+type giveawayBase struct {
+	util.InteractionUtil
+	member *discordgo.Member
+	user   *discordgo.User
+}
+
+// hasPermission returns whether the member of the current interaction is allowed to manage
+// giveaways in the guild.
+func (gb giveawayBase) hasPermission() bool {
+	return gb.Interaction.GuildID != "" &&
+		gb.member != nil &&
+		gb.member.Permissions&discordgo.PermissionManageServer != 0
+}
+
+// prizeFile returns the path of the prize file of the giveaway with the given name.
+func prizeFile(name string) string {
+	return filepath.Join(viper.GetString("event.giveaway.prizes"), name+".json")
+}
+
+// claimWindow returns the configured time winners have to claim their prizes. If it is 0, no
+// claim is needed.
+func claimWindow() time.Duration {
+	return viper.GetDuration("event.giveaway.claim_window") * time.Minute
+}
+
+// parseDuration is like time.ParseDuration, but additionally accepts days with the unit 'd',
+// like '1d12h'. Days always have 24 hours.
+func parseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	var d time.Duration
+	if days, rest, ok := strings.Cut(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number of days in '%s'", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+		if rest == "" {
+			return d, nil
+		}
+		s = rest
+	}
+	rest, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if rest < 0 {
+		return 0, fmt.Errorf("negative duration '%s'", s)
+	}
+	return d + rest, nil
+}
+
+// giveawayMessage returns the message of g with the entry button. Once g has ended the button is
+// disabled and the winners are listed instead. commitment is the published hash of the seed, see
+// database.FairDraw.
+func giveawayMessage(s *discordgo.Session, g database.DiscordGiveaway, commitment string, winners []database.GiveawayEntry) *discordgo.MessageSend {
+	e := &discordgo.MessageEmbed{
+		Title:       "🎉 " + g.Prize,
+		Description: lang.GetDefault(tp + "embed.description"),
+		Color:       0x5865F2,
+	}
+	if g.Ended {
+		e.Description = lang.GetDefault(tp + "embed.ended")
+		e.Color = 0x808080
+		util.AddEmbedField(e, lang.GetDefault(tp+"embed.ended_at"), fmt.Sprintf("<t:%d:f>", g.EndsAt.Unix()), true)
+		util.AddEmbedField(e, lang.GetDefault(tp+"embed.winners"), mentionWinners(winners), true)
+	} else {
+		util.AddEmbedField(e, lang.GetDefault(tp+"embed.ends"), fmt.Sprintf("<t:%d:R> (<t:%d:f>)", g.EndsAt.Unix(), g.EndsAt.Unix()), true)
+		util.AddEmbedField(e, lang.GetDefault(tp+"embed.winners"), strconv.Itoa(g.Winners), true)
+	}
+	if g.RoleID != "" {
+		util.AddEmbedField(e, lang.GetDefault(tp+"embed.role"), fmt.Sprintf("<@&%s>", g.RoleID), true)
+	}
+	util.AddEmbedField(e, lang.GetDefault(tp+"embed.host"), fmt.Sprintf("<@%s>", g.HostID), true)
+	util.AddEmbedField(e, lang.GetDefault(tp+"embed.id"), fmt.Sprintf("`%s`", g.Name), true)
+	if commitment != "" {
+		util.AddEmbedField(e, lang.GetDefault(tp+"embed.commitment"), fmt.Sprintf(lang.GetDefault(tp+"embed.commitment.value"), commitment), false)
+	}
+	util.SetEmbedFooter(s, tp+"display", e)
+
+	button := util.CreateButtonComponent(
+		fmt.Sprintf("%s.enter.%s", Component{}.ID(), g.Name),
+		lang.GetDefault(tp+"button.enter"),
+		discordgo.PrimaryButton,
+		util.GetConfigComponentEmoji("giveaway"),
+	)
+	button.Disabled = g.Ended
+
+	return &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{e},
+		Components: []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{button}}},
+	}
+}
+
+// claimComponents returns the claim button for the winners of g.
+func claimComponents(g database.DiscordGiveaway) []discordgo.MessageComponent {
+	button := util.CreateButtonComponent(
+		fmt.Sprintf("%s.claim.%s", Component{}.ID(), g.Name),
+		lang.GetDefault(tp+"button.claim"),
+		discordgo.SuccessButton,
+		util.GetConfigComponentEmoji("giveaway.claim"),
+	)
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{button}}}
+}
+
+// mentionWinners returns the mentions of all winners separated by commas.
+func mentionWinners(winners []database.GiveawayEntry) string {
+	if len(winners) == 0 {
+		return lang.GetDefault(tp + "embed.no_winners")
+	}
+	mentions := make([]string, 0, len(winners))
+	for _, w := range winners {
+		mentions = append(mentions, fmt.Sprintf("<@%s>", w.UserID))
+	}
+	return strings.Join(mentions, ", ")
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package giveaway
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_parseDuration(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30m", 30 * time.Minute, false},
+		{"12h", 12 * time.Hour, false},
+		{"1d", 24 * time.Hour, false},
+		{"1d12h", 36 * time.Hour, false},
+		{" 2d30m ", 48*time.Hour + 30*time.Minute, false},
+		{"", 0, true},
+		{"d", 0, true},
+		{"-1d", 0, true},
+		{"1d-1h", 0, true},
+		{"1x", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := parseDuration(tt.s)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseDuration(%q) = %v, %v, want %v, err %v", tt.s, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
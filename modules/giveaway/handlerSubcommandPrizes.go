@@ -0,0 +1,310 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package giveaway
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"cake4everybot/event/twitch"
+	"cake4everybot/util"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/spf13/viper"
+)
+
+// maxTreeLength is the maximum length of a rendered prize tree in an embed
+const maxTreeLength = 3500
+
+// prizesAppCmdOption returns the definition of the prizes subcommand group. It edits the prize
+// file of a giveaway.
+func prizesAppCmdOption() *discordgo.ApplicationCommandOption {
+	var (
+		minValue = 1.0
+		sorts    []*discordgo.ApplicationCommandOptionChoice
+	)
+	for _, sort := range []database.GiveawayPrizeGroupSort{database.GiveawayPrizeGroupOrdered, database.GiveawayPrizeGroupRandom} {
+		sorts = append(sorts, &discordgo.ApplicationCommandOptionChoice{
+			Name:              lang.GetDefault(tp + "option.prizes.sort." + string(sort)),
+			NameLocalizations: *util.TranslateLocalization(tp + "option.prizes.sort." + string(sort)),
+			Value:             string(sort),
+		})
+	}
+
+	id := prizesOption("id", discordgo.ApplicationCommandOptionString, true)
+	id.MaxLength = 64
+	path := prizesOption("path", discordgo.ApplicationCommandOptionString, true)
+	path.MaxLength = 32
+	group := prizesOption("group", discordgo.ApplicationCommandOptionString, false)
+	group.MaxLength = 32
+	name := prizesOption("name", discordgo.ApplicationCommandOptionString, true)
+	name.MaxLength = 200
+	position := prizesOption("position", discordgo.ApplicationCommandOptionInteger, true)
+	position.MinValue = &minValue
+	count := prizesOption("count", discordgo.ApplicationCommandOptionInteger, true)
+	count.MinValue = &minValue
+	sort := prizesOption("sort", discordgo.ApplicationCommandOptionString, false)
+	sort.Choices = sorts
+	user := prizesOption("user", discordgo.ApplicationCommandOptionUser, true)
+
+	return &discordgo.ApplicationCommandOption{
+		Type:                     discordgo.ApplicationCommandOptionSubCommandGroup,
+		Name:                     lang.GetDefault(tp + "option.prizes"),
+		NameLocalizations:        *util.TranslateLocalization(tp + "option.prizes"),
+		Description:              lang.GetDefault(tp + "option.prizes.description"),
+		DescriptionLocalizations: *util.TranslateLocalization(tp + "option.prizes.description"),
+		Options: []*discordgo.ApplicationCommandOption{
+			prizesSubcommand("list", id),
+			prizesSubcommand("add", id, name, group),
+			prizesSubcommand("move", id, path, position),
+			prizesSubcommand("group", id, path, count, sort),
+			prizesSubcommand("win", id, path, user),
+			prizesSubcommand("unwin", id, path),
+			prizesSubcommand("remove", id, path),
+		},
+	}
+}
+
+// prizesSubcommand returns the definition of a subcommand of the prizes subcommand group.
+func prizesSubcommand(name string, options ...*discordgo.ApplicationCommandOption) *discordgo.ApplicationCommandOption {
+	key := tp + "option.prizes." + name
+	return &discordgo.ApplicationCommandOption{
+		Type:                     discordgo.ApplicationCommandOptionSubCommand,
+		Name:                     lang.GetDefault(key),
+		NameLocalizations:        *util.TranslateLocalization(key),
+		Description:              lang.GetDefault(key + ".description"),
+		DescriptionLocalizations: *util.TranslateLocalization(key + ".description"),
+		Options:                  options,
+	}
+}
+
+// prizesOption returns the definition of an option of the prizes subcommands. All subcommands
+// share the same translations for their options.
+func prizesOption(name string, t discordgo.ApplicationCommandOptionType, required bool) *discordgo.ApplicationCommandOption {
+	key := tp + "option.prizes.option." + name
+	return &discordgo.ApplicationCommandOption{
+		Type:                     t,
+		Name:                     lang.GetDefault(key),
+		NameLocalizations:        *util.TranslateLocalization(key),
+		Description:              lang.GetDefault(key + ".description"),
+		DescriptionLocalizations: *util.TranslateLocalization(key + ".description"),
+		Required:                 required,
+	}
+}
+
+func (cmd Chat) handleSubcommandGroupPrizes(options []*discordgo.ApplicationCommandInteractionDataOption) {
+	if len(options) == 0 {
+		cmd.ReplyError()
+		return
+	}
+	subcommand := options[0]
+	args := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(subcommand.Options))
+	for _, o := range subcommand.Options {
+		args[o.Name] = o
+	}
+	arg := func(name string) *discordgo.ApplicationCommandInteractionDataOption {
+		return args[lang.GetDefault(tp+"option.prizes.option."+name)]
+	}
+
+	name := strings.Trim(strings.TrimSpace(arg("id").StringValue()), "`")
+	title := fmt.Sprintf(lang.GetDefault(tp+"msg.prizes.title"), name)
+	// a name like twitch:somechannel refers to the prizes file of the Twitch channel
+	channel, isTwitch := strings.CutPrefix(strings.ToLower(name), "twitch:")
+	var filename string
+	if isTwitch {
+		ok, err := cmd.isBroadcaster(channel)
+		if err != nil {
+			log.Printf("ERROR: could not check broadcaster of Twitch channel '%s': %v", channel, err)
+			cmd.ReplyError()
+			return
+		}
+		if !ok {
+			cmd.ReplyHiddenSimpleEmbedf(0xFF0000, lang.GetDefault(tp+"msg.prizes.not_broadcaster"), channel)
+			return
+		}
+		filename = twitch.PrizesFile(channel)
+		defer twitch.LockPrizes(filename)()
+	} else {
+		g, ok, err := database.GetDiscordGiveaway(name)
+		if err != nil {
+			log.Printf("ERROR: could not get giveaway '%s': %v", name, err)
+			cmd.ReplyError()
+			return
+		}
+		if !ok || g.GuildID != cmd.Interaction.GuildID {
+			cmd.ReplyHiddenSimpleEmbedf(0xFF0000, lang.GetDefault(tp+"msg.not_found"), name)
+			return
+		}
+		filename = prizeFile(name)
+		prizeMu.Lock()
+		defer prizeMu.Unlock()
+	}
+
+	p, err := database.NewGiveawayPrize(filename)
+	if errors.Is(err, fs.ErrNotExist) && isTwitch && subcommand.Name == lang.GetDefault(tp+"option.prizes.add") && arg("group") == nil {
+		// like with '!prize add' the first prize creates the prizes file of a Twitch channel
+		prize := strings.TrimSpace(arg("name").StringValue())
+		if p, err = database.CreateGiveawayPrize(filename, database.GiveawayPrizeSingle{Name: prize}); err != nil {
+			log.Printf("ERROR: could not create prizes file '%s': %v", filename, err)
+			cmd.ReplyHiddenSimpleEmbedf(0xFF0000, lang.GetDefault(tp+"msg.prizes.invalid"), err)
+			return
+		}
+		log.Printf("%s created the prizes of Twitch channel '%s'", cmd.user.Username, channel)
+		cmd.replyPrizes(p, isTwitch, title, fmt.Sprintf(lang.GetDefault(tp+"msg.prizes.added"), prize, "1"))
+		return
+	} else if errors.Is(err, fs.ErrNotExist) {
+		cmd.ReplyHiddenSimpleEmbedf(0xFF0000, lang.GetDefault(tp+"msg.prizes.no_file"), name)
+		return
+	} else if err != nil {
+		log.Printf("ERROR: could not read prizes of giveaway '%s': %v", name, err)
+		cmd.ReplyError()
+		return
+	}
+
+	var path database.PrizePath
+	if o := arg("path"); o != nil {
+		if path, err = database.ParsePrizePath(o.StringValue()); err != nil {
+			cmd.ReplyHiddenSimpleEmbedf(0xFF0000, lang.GetDefault(tp+"msg.prizes.invalid_path"), err)
+			return
+		}
+	}
+
+	var changed string
+	switch subcommand.Name {
+	case lang.GetDefault(tp + "option.prizes.list"):
+		cmd.replyPrizes(p, isTwitch, title, "")
+		return
+	case lang.GetDefault(tp + "option.prizes.add"):
+		var group database.PrizePath
+		if o := arg("group"); o != nil {
+			if group, err = database.ParsePrizePath(o.StringValue()); err != nil {
+				break
+			}
+		}
+		prize := strings.TrimSpace(arg("name").StringValue())
+		if path, err = p.AddPrize(group, prize); err == nil {
+			changed = fmt.Sprintf(lang.GetDefault(tp+"msg.prizes.added"), prize, path)
+		}
+	case lang.GetDefault(tp + "option.prizes.move"):
+		var newPath database.PrizePath
+		if newPath, err = p.MovePrize(path, int(arg("position").IntValue())); err == nil {
+			changed = fmt.Sprintf(lang.GetDefault(tp+"msg.prizes.moved"), path, newPath)
+		}
+	case lang.GetDefault(tp + "option.prizes.group"):
+		sort := database.GiveawayPrizeGroupOrdered
+		if o := arg("sort"); o != nil {
+			sort = database.GiveawayPrizeGroupSort(o.StringValue())
+		}
+		count := int(arg("count").IntValue())
+		if _, err = p.GroupPrizes(path, count, sort); err == nil {
+			changed = fmt.Sprintf(lang.GetDefault(tp+"msg.prizes.grouped"), count, path)
+		}
+	case lang.GetDefault(tp + "option.prizes.win"):
+		user := arg("user").UserValue(nil)
+		winner, mention := user.ID, user.Mention()
+		if isTwitch {
+			// Twitch prizes are won by the login of the linked Twitch account
+			link, ok, err := database.GetAccountLinkByDiscord(user.ID)
+			if err != nil {
+				log.Printf("ERROR: could not get account link of %s: %v", user.ID, err)
+				cmd.ReplyError()
+				return
+			}
+			if !ok || link.TwitchLogin == "" {
+				cmd.ReplyHiddenSimpleEmbedf(0xFF0000, lang.GetDefault(tp+"msg.prizes.not_linked"), user.Mention())
+				return
+			}
+			winner, mention = link.TwitchLogin, link.TwitchLogin
+		}
+		if err = p.SetPrizeWinner(path, winner); err == nil {
+			changed = fmt.Sprintf(lang.GetDefault(tp+"msg.prizes.won"), path, mention)
+		}
+	case lang.GetDefault(tp + "option.prizes.unwin"):
+		if err = p.SetPrizeWinner(path, ""); err == nil {
+			changed = fmt.Sprintf(lang.GetDefault(tp+"msg.prizes.unwon"), path)
+		}
+	case lang.GetDefault(tp + "option.prizes.remove"):
+		if err = p.RemovePrize(path); err == nil {
+			changed = fmt.Sprintf(lang.GetDefault(tp+"msg.prizes.removed"), path)
+		}
+	default:
+		log.Printf("ERROR: got unknown prizes subcommand '%s'", subcommand.Name)
+		cmd.ReplyError()
+		return
+	}
+
+	if errors.Is(err, database.ErrPrizeAlreadyWon) {
+		cmd.ReplyHiddenSimpleEmbedf(0xFF0000, lang.GetDefault(tp+"msg.prizes.already_won"), path)
+		return
+	} else if err != nil {
+		cmd.ReplyHiddenSimpleEmbedf(0xFF0000, lang.GetDefault(tp+"msg.prizes.invalid_path"), err)
+		return
+	}
+	if err = p.SaveFile(); err != nil {
+		log.Printf("ERROR: could not save prizes of giveaway '%s': %v", name, err)
+		cmd.ReplyHiddenSimpleEmbedf(0xFF0000, lang.GetDefault(tp+"msg.prizes.invalid"), err)
+		return
+	}
+	log.Printf("%s changed prizes of giveaway '%s': %s", cmd.user.Username, name, subcommand.Name)
+
+	cmd.replyPrizes(p, isTwitch, title, changed)
+}
+
+// isBroadcaster returns whether channel is a joined Twitch channel and the user of the current
+// interaction linked its Twitch account, see /link.
+func (cmd Chat) isBroadcaster(channel string) (bool, error) {
+	channels := viper.GetStringSlice("twitch.channels")
+	if !slices.ContainsFunc(channels, func(c string) bool { return strings.EqualFold(c, channel) }) {
+		return false, nil
+	}
+	link, ok, err := database.GetAccountLinkByDiscord(cmd.user.ID)
+	if err != nil || !ok {
+		return false, err
+	}
+	return strings.EqualFold(link.TwitchLogin, channel), nil
+}
+
+// replyPrizes replies with the prize tree of p. message is shown above the tree, if set. Winners of
+// Discord giveaways are mentioned, the ones of Twitch prizes shown by their login.
+func (cmd Chat) replyPrizes(p database.GiveawayPrize, isTwitch bool, title, message string) {
+	winner := func(winner string) string { return fmt.Sprintf("<@%s>", winner) }
+	if isTwitch {
+		winner = nil
+	}
+	tree := p.Tree(winner)
+	if len(tree) > maxTreeLength {
+		cut := strings.LastIndexByte(tree[:maxTreeLength], '\n')
+		if cut < 0 {
+			cut = maxTreeLength
+		}
+		tree = tree[:cut] + "\n…"
+	}
+
+	e := &discordgo.MessageEmbed{
+		Title:       title,
+		Description: tree,
+		Color:       0x5865F2,
+	}
+	if message != "" {
+		e.Description = message + "\n\n" + tree
+		e.Color = 0x00FF00
+	}
+	util.SetEmbedFooter(cmd.Session, tp+"display", e)
+	cmd.ReplyHiddenEmbed(e)
+}
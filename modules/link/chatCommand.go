@@ -0,0 +1,164 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package link
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"cake4everybot/util"
+	"fmt"
+	logger "log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Prefix for translation key, i.e.:
+	//   key := tp+"base" // => link
+	tp = "discord.command.link."
+
+	// codeTTL is the time a user has to type the code in the Twitch chat
+	codeTTL = 10 * time.Minute
+)
+
+var log = logger.New(logger.Writer(), "[Link] ", logger.LstdFlags|logger.Lmsgprefix)
+
+// Chat represents the link chat command. It links the Discord user to their Twitch account, so
+// giveaways, points and birthdays can resolve them on both platforms.
+type Chat struct {
+	util.InteractionUtil
+	user *discordgo.User
+	ID   string
+}
+
+// AppCmd (ApplicationCommand) returns the definition of the chat command
+func (Chat) AppCmd() *discordgo.ApplicationCommand {
+	subcommand := func(name string) *discordgo.ApplicationCommandOption {
+		return &discordgo.ApplicationCommandOption{
+			Type:                     discordgo.ApplicationCommandOptionSubCommand,
+			Name:                     lang.GetDefault(tp + "option." + name),
+			NameLocalizations:        *util.TranslateLocalization(tp + "option." + name),
+			Description:              lang.GetDefault(tp + "option." + name + ".description"),
+			DescriptionLocalizations: *util.TranslateLocalization(tp + "option." + name + ".description"),
+		}
+	}
+
+	return &discordgo.ApplicationCommand{
+		Name:                     lang.GetDefault(tp + "base"),
+		NameLocalizations:        util.TranslateLocalization(tp + "base"),
+		Description:              lang.GetDefault(tp + "base.description"),
+		DescriptionLocalizations: util.TranslateLocalization(tp + "base.description"),
+		Options: []*discordgo.ApplicationCommandOption{
+			subcommand("twitch"),
+			subcommand("show"),
+			subcommand("remove"),
+		},
+	}
+}
+
+// Handle handles the functionality of a command
+func (cmd Chat) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	cmd.InteractionUtil = util.InteractionUtil{Session: s, Interaction: i}
+	cmd.user = i.User
+	if i.Member != nil {
+		cmd.user = i.Member.User
+	}
+
+	subcommand := i.ApplicationCommandData().Options[0]
+	switch subcommand.Name {
+	case lang.GetDefault(tp + "option.twitch"):
+		cmd.handleSubcommandTwitch()
+	case lang.GetDefault(tp + "option.show"):
+		cmd.handleSubcommandShow()
+	case lang.GetDefault(tp + "option.remove"):
+		cmd.handleSubcommandRemove()
+	}
+}
+
+func (cmd Chat) handleSubcommandTwitch() {
+	code, err := database.CreateAccountLinkCode(cmd.user.ID, time.Now(), codeTTL)
+	if err != nil {
+		log.Printf("ERROR: could not create link code for %s: %v", cmd.user.ID, err)
+		cmd.ReplyError()
+		return
+	}
+
+	var channels []string
+	for _, channel := range viper.GetStringSlice("twitch.channels") {
+		channels = append(channels, fmt.Sprintf("[%s](https://twitch.tv/%s)", channel, strings.ToLower(channel)))
+	}
+	msg := fmt.Sprintf(lang.GetDefault(tp+"msg.code"), code, strings.Join(channels, ", "), time.Now().Add(codeTTL).Unix())
+
+	link, ok, err := database.GetAccountLinkByDiscord(cmd.user.ID)
+	if err != nil {
+		log.Printf("ERROR: could not get account link of %s: %v", cmd.user.ID, err)
+		cmd.ReplyError()
+		return
+	}
+	if ok {
+		msg += "\n\n" + fmt.Sprintf(lang.GetDefault(tp+"msg.code.relink"), twitchName(link))
+	}
+	cmd.ReplyHiddenSimpleEmbed(0x9146FF, msg)
+}
+
+func (cmd Chat) handleSubcommandShow() {
+	link, ok, err := database.GetAccountLinkByDiscord(cmd.user.ID)
+	if err != nil {
+		log.Printf("ERROR: could not get account link of %s: %v", cmd.user.ID, err)
+		cmd.ReplyError()
+		return
+	}
+	if !ok {
+		cmd.ReplyHiddenSimpleEmbed(0xFF0000, lang.GetDefault(tp+"msg.not_linked"))
+		return
+	}
+	cmd.ReplyHiddenSimpleEmbedf(0x9146FF, lang.GetDefault(tp+"msg.linked"), twitchName(link), link.LinkedAt.Unix())
+}
+
+func (cmd Chat) handleSubcommandRemove() {
+	ok, err := database.DeleteAccountLink(cmd.user.ID)
+	if err != nil {
+		log.Printf("ERROR: could not remove account link of %s: %v", cmd.user.ID, err)
+		cmd.ReplyError()
+		return
+	}
+	if !ok {
+		cmd.ReplyHiddenSimpleEmbed(0xFF0000, lang.GetDefault(tp+"msg.not_linked"))
+		return
+	}
+	log.Printf("%s removed the link to their Twitch account", cmd.user.Username)
+	cmd.ReplyHiddenSimpleEmbed(0x00FF00, lang.GetDefault(tp+"msg.removed"))
+}
+
+// twitchName returns the Twitch login of link, or its ID if the login is unknown.
+func twitchName(link database.AccountLink) string {
+	if link.TwitchLogin == "" {
+		return link.TwitchID
+	}
+	return fmt.Sprintf("[%s](https://twitch.tv/%s)", link.TwitchLogin, link.TwitchLogin)
+}
+
+// SetID sets the registered command ID for internal uses after uploading to discord
+func (cmd *Chat) SetID(id string) {
+	cmd.ID = id
+}
+
+// GetID gets the registered command ID
+func (cmd Chat) GetID() string {
+	return cmd.ID
+}
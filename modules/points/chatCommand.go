@@ -0,0 +1,157 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package points
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"cake4everybot/event/twitch"
+	"cake4everybot/util"
+	"fmt"
+	logger "log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Prefix for translation key, i.e.:
+	//   key := tp+"base" // => points
+	tp = "discord.command.points."
+)
+
+var log = logger.New(logger.Writer(), "[Points] ", logger.LstdFlags|logger.Lmsgprefix)
+
+// Chat represents the points chat command. It shows the points and the rank of a Twitch user in
+// one of the joined Twitch channels.
+type Chat struct {
+	util.InteractionUtil
+	user *discordgo.User
+	ID   string
+}
+
+// AppCmd (ApplicationCommand) returns the definition of the chat command
+func (Chat) AppCmd() *discordgo.ApplicationCommand {
+	channels := viper.GetStringSlice("twitch.channels")
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(channels))
+	for _, channel := range channels {
+		if len(choices) == 25 {
+			break
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  channel,
+			Value: strings.ToLower(channel),
+		})
+	}
+
+	return &discordgo.ApplicationCommand{
+		Name:                     lang.GetDefault(tp + "base"),
+		NameLocalizations:        util.TranslateLocalization(tp + "base"),
+		Description:              lang.GetDefault(tp + "base.description"),
+		DescriptionLocalizations: util.TranslateLocalization(tp + "base.description"),
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:                     discordgo.ApplicationCommandOptionString,
+				Name:                     lang.GetDefault(tp + "option.user"),
+				NameLocalizations:        *util.TranslateLocalization(tp + "option.user"),
+				Description:              lang.GetDefault(tp + "option.user.description"),
+				DescriptionLocalizations: *util.TranslateLocalization(tp + "option.user.description"),
+				MaxLength:                25,
+			},
+			{
+				Type:                     discordgo.ApplicationCommandOptionString,
+				Name:                     lang.GetDefault(tp + "option.channel"),
+				NameLocalizations:        *util.TranslateLocalization(tp + "option.channel"),
+				Description:              lang.GetDefault(tp + "option.channel.description"),
+				DescriptionLocalizations: *util.TranslateLocalization(tp + "option.channel.description"),
+				Choices:                  choices,
+			},
+		},
+	}
+}
+
+// Handle handles the functionality of a command
+func (cmd Chat) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	cmd.InteractionUtil = util.InteractionUtil{Session: s, Interaction: i}
+	cmd.user = i.User
+	if i.Member != nil {
+		cmd.user = i.Member.User
+	}
+
+	var user, channel string
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case lang.GetDefault(tp + "option.user"):
+			user = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(option.StringValue()), "@"))
+		case lang.GetDefault(tp + "option.channel"):
+			channel = option.StringValue()
+		}
+	}
+	if channel == "" {
+		channels := viper.GetStringSlice("twitch.channels")
+		if len(channels) == 0 {
+			cmd.ReplyHiddenSimpleEmbed(0xFF0000, lang.GetDefault(tp+"msg.no_channel"))
+			return
+		}
+		channel = strings.ToLower(channels[0])
+	}
+	if user == "" {
+		// default to the Twitch account linked with /link
+		link, ok, err := database.GetAccountLinkByDiscord(cmd.user.ID)
+		if err != nil {
+			log.Printf("ERROR: could not get account link of %s: %v", cmd.user.ID, err)
+			cmd.ReplyError()
+			return
+		}
+		if !ok || link.TwitchLogin == "" {
+			cmd.ReplyHiddenSimpleEmbed(0xFF0000, lang.GetDefault(tp+"msg.no_user"))
+			return
+		}
+		user = link.TwitchLogin
+	}
+
+	// StreamElements might take longer than discord waits for a response
+	cmd.ReplyDefered()
+	rank, err := twitch.PointsRank(channel, user)
+	if err != nil {
+		log.Printf("ERROR: could not get points of '%s/%s': %v", channel, user, err)
+		cmd.ReplyError()
+		return
+	}
+
+	e := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf(lang.GetDefault(tp+"msg.title"), user, channel),
+		Color: 0x9146FF,
+	}
+	if rank.Rank == 0 {
+		e.Description = fmt.Sprintf(lang.GetDefault(tp+"msg.no_points"), user)
+	} else {
+		util.AddEmbedField(e, lang.GetDefault(tp+"msg.points"), fmt.Sprint(rank.Points), true)
+		util.AddEmbedField(e, lang.GetDefault(tp+"msg.rank"), fmt.Sprintf("#%d", rank.Rank), true)
+	}
+	util.SetEmbedFooter(s, tp+"display", e)
+	cmd.ReplyEmbed(e)
+}
+
+// SetID sets the registered command ID for internal uses after uploading to discord
+func (cmd *Chat) SetID(id string) {
+	cmd.ID = id
+}
+
+// GetID gets the registered command ID
+func (cmd Chat) GetID() string {
+	return cmd.ID
+}
@@ -0,0 +1,191 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"cake4everybot/util"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Chat represents the settings chat command. It shows and edits the per-guild settings, like the
+// announcement channels.
+type Chat struct {
+	settingsBase
+	ID string
+}
+
+// AppCmd (ApplicationCommand) returns the definition of the chat command
+func (Chat) AppCmd() *discordgo.ApplicationCommand {
+	var (
+		permission int64 = discordgo.PermissionManageServer
+		dm               = false
+		choices          = make([]*discordgo.ApplicationCommandOptionChoice, 0, len(settings))
+	)
+	for _, s := range settings {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:              lang.GetDefault(tp + "setting." + s.column),
+			NameLocalizations: *util.TranslateLocalization(tp + "setting." + s.column),
+			Value:             s.column,
+		})
+	}
+
+	return &discordgo.ApplicationCommand{
+		Name:                     lang.GetDefault(tp + "base"),
+		NameLocalizations:        util.TranslateLocalization(tp + "base"),
+		Description:              lang.GetDefault(tp + "base.description"),
+		DescriptionLocalizations: util.TranslateLocalization(tp + "base.description"),
+		DefaultMemberPermissions: &permission,
+		DMPermission:             &dm,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:                     discordgo.ApplicationCommandOptionSubCommand,
+				Name:                     lang.GetDefault(tp + "option.show"),
+				NameLocalizations:        *util.TranslateLocalization(tp + "option.show"),
+				Description:              lang.GetDefault(tp + "option.show.description"),
+				DescriptionLocalizations: *util.TranslateLocalization(tp + "option.show.description"),
+			},
+			{
+				Type:                     discordgo.ApplicationCommandOptionSubCommand,
+				Name:                     lang.GetDefault(tp + "option.edit"),
+				NameLocalizations:        *util.TranslateLocalization(tp + "option.edit"),
+				Description:              lang.GetDefault(tp + "option.edit.description"),
+				DescriptionLocalizations: *util.TranslateLocalization(tp + "option.edit.description"),
+				Options: []*discordgo.ApplicationCommandOption{{
+					Type:                     discordgo.ApplicationCommandOptionString,
+					Name:                     lang.GetDefault(tp + "option.edit.option.setting"),
+					NameLocalizations:        *util.TranslateLocalization(tp + "option.edit.option.setting"),
+					Description:              lang.GetDefault(tp + "option.edit.option.setting.description"),
+					DescriptionLocalizations: *util.TranslateLocalization(tp + "option.edit.option.setting.description"),
+					Required:                 true,
+					Choices:                  choices,
+				}},
+			},
+			{
+				Type:                     discordgo.ApplicationCommandOptionSubCommand,
+				Name:                     lang.GetDefault(tp + "option.timezone"),
+				NameLocalizations:        *util.TranslateLocalization(tp + "option.timezone"),
+				Description:              lang.GetDefault(tp + "option.timezone.description"),
+				DescriptionLocalizations: *util.TranslateLocalization(tp + "option.timezone.description"),
+				Options: []*discordgo.ApplicationCommandOption{{
+					Type:                     discordgo.ApplicationCommandOptionString,
+					Name:                     lang.GetDefault(tp + "option.timezone.option.zone"),
+					NameLocalizations:        *util.TranslateLocalization(tp + "option.timezone.option.zone"),
+					Description:              lang.GetDefault(tp + "option.timezone.option.zone.description"),
+					DescriptionLocalizations: *util.TranslateLocalization(tp + "option.timezone.option.zone.description"),
+					MaxLength:                64,
+				}},
+			},
+		},
+	}
+}
+
+// Handle handles the functionality of a command
+func (cmd Chat) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	cmd.InteractionUtil = util.InteractionUtil{Session: s, Interaction: i}
+	cmd.member = i.Member
+	cmd.user = i.User
+	if i.Member != nil {
+		cmd.user = i.Member.User
+	} else if i.User != nil {
+		cmd.member = &discordgo.Member{User: i.User}
+	}
+
+	if !cmd.hasPermission() {
+		cmd.ReplyHiddenSimpleEmbed(0xFF0000, lang.GetDefault(tp+"msg.no_permission"))
+		return
+	}
+
+	subcommand := i.ApplicationCommandData().Options[0]
+	switch subcommand.Name {
+	case lang.GetDefault(tp + "option.show"):
+		cmd.handleSubcommandShow()
+		return
+	case lang.GetDefault(tp + "option.edit"):
+		cmd.handleSubcommandEdit(subcommand.Options)
+		return
+	case lang.GetDefault(tp + "option.timezone"):
+		cmd.handleSubcommandTimezone(subcommand.Options)
+		return
+	}
+}
+
+func (cmd Chat) handleSubcommandShow() {
+	e, err := cmd.settingsEmbed()
+	if err != nil {
+		log.Printf("ERROR: could not get settings of guild %s: %v", cmd.Interaction.GuildID, err)
+		cmd.ReplyError()
+		return
+	}
+	cmd.ReplyHiddenEmbed(e)
+}
+
+func (cmd Chat) handleSubcommandEdit(options []*discordgo.ApplicationCommandInteractionDataOption) {
+	if len(options) == 0 {
+		cmd.ReplyError()
+		return
+	}
+	s, ok := getSetting(options[0].StringValue())
+	if !ok {
+		log.Printf("ERROR: got unknown setting '%s'", options[0].StringValue())
+		cmd.ReplyError()
+		return
+	}
+
+	cmd.ReplyComponentsHiddenSimpleEmbed(cmd.editComponents(s), 0x5865F2, fmt.Sprintf(lang.GetDefault(tp+"msg.edit"), s.name()))
+}
+
+func (cmd Chat) handleSubcommandTimezone(options []*discordgo.ApplicationCommandInteractionDataOption) {
+	var name string
+	if len(options) > 0 {
+		name = strings.TrimSpace(options[0].StringValue())
+	}
+
+	if name != "" {
+		if _, err := time.LoadLocation(name); err != nil {
+			cmd.ReplyHiddenSimpleEmbedf(0xFF0000, lang.GetDefault(tp+"msg.invalid_timezone"), name)
+			return
+		}
+	}
+
+	err := database.SetGuildTimezone(cmd.Interaction.GuildID, name)
+	if err != nil {
+		log.Printf("ERROR: could not set timezone of guild %s to '%s': %v", cmd.Interaction.GuildID, name, err)
+		cmd.ReplyError()
+		return
+	}
+
+	settingName := lang.GetDefault(tp + "setting.timezone")
+	if name == "" {
+		cmd.ReplyHiddenSimpleEmbedf(0x00FF00, lang.GetDefault(tp+"msg.reset"), settingName)
+		return
+	}
+	cmd.ReplyHiddenSimpleEmbedf(0x00FF00, lang.GetDefault(tp+"msg.updated"), settingName, fmt.Sprintf("`%s`", name))
+}
+
+// SetID sets the registered command ID for internal uses after uploading to discord
+func (cmd *Chat) SetID(id string) {
+	cmd.ID = id
+}
+
+// GetID gets the registered command ID
+func (cmd Chat) GetID() string {
+	return cmd.ID
+}
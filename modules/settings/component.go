@@ -0,0 +1,146 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"cake4everybot/util"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Component handles the select menus and buttons of the settings command.
+type Component struct {
+	settingsBase
+	data discordgo.MessageComponentInteractionData
+}
+
+// Handle handles the functionality of a component.
+func (c Component) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	c.InteractionUtil = util.InteractionUtil{Session: s, Interaction: i}
+	c.member = i.Member
+	c.user = i.User
+	if i.Member != nil {
+		c.user = i.Member.User
+	} else if i.User != nil {
+		c.member = &discordgo.Member{User: i.User}
+	}
+	c.data = i.MessageComponentData()
+
+	if !c.hasPermission() {
+		c.ReplyHiddenSimpleEmbed(0xFF0000, lang.GetDefault(tp+"msg.no_permission"))
+		return
+	}
+
+	ids := strings.Split(c.data.CustomID, ".")
+	// pop the first level identifier
+	util.ShiftL(ids)
+
+	switch util.ShiftL(ids) {
+	case "set":
+		c.handleSet(ids)
+		return
+	case "reset":
+		c.handleReset(ids)
+		return
+	default:
+		log.Printf("Unknown component interaction ID: %s", c.data.CustomID)
+	}
+}
+
+// ID returns the custom ID of the modal to identify the module
+func (Component) ID() string {
+	return "settings"
+}
+
+func (c Component) handleSet(ids []string) {
+	s, ok := getSetting(util.ShiftL(ids))
+	if !ok || len(c.data.Values) != 1 {
+		log.Printf("ERROR: invalid settings component '%s' with values %v", c.data.CustomID, c.data.Values)
+		c.ReplyError()
+		return
+	}
+	id := c.data.Values[0]
+
+	if s.role {
+		if !c.isGuildRole(id) {
+			c.ReplyHiddenSimpleEmbed(0xFF0000, lang.GetDefault(tp+"msg.invalid_role"))
+			return
+		}
+	} else if !c.isGuildChannel(id) {
+		c.ReplyHiddenSimpleEmbed(0xFF0000, lang.GetDefault(tp+"msg.invalid_channel"))
+		return
+	}
+
+	err := database.SetGuildSetting(c.Interaction.GuildID, s.column, id)
+	if err != nil {
+		log.Printf("ERROR: could not set '%s' of guild %s to '%s': %v", s.column, c.Interaction.GuildID, id, err)
+		c.ReplyError()
+		return
+	}
+	log.Printf("%s set '%s' of guild %s to '%s'", c.user.Username, s.column, c.Interaction.GuildID, id)
+
+	c.ReplyComponentsHiddenSimpleEmbedUpdatef(c.editComponents(s), 0x00FF00, lang.GetDefault(tp+"msg.updated"), s.name(), s.mention(id))
+}
+
+func (c Component) handleReset(ids []string) {
+	s, ok := getSetting(util.ShiftL(ids))
+	if !ok {
+		log.Printf("ERROR: invalid settings component '%s'", c.data.CustomID)
+		c.ReplyError()
+		return
+	}
+
+	err := database.SetGuildSetting(c.Interaction.GuildID, s.column, "")
+	if err != nil {
+		log.Printf("ERROR: could not reset '%s' of guild %s: %v", s.column, c.Interaction.GuildID, err)
+		c.ReplyError()
+		return
+	}
+	log.Printf("%s reset '%s' of guild %s", c.user.Username, s.column, c.Interaction.GuildID)
+
+	c.ReplyComponentsHiddenSimpleEmbedUpdate(c.editComponents(s), 0xFCB100, fmt.Sprintf(lang.GetDefault(tp+"msg.reset"), s.name()))
+}
+
+// isGuildChannel returns whether the given channel belongs to the guild of the interaction.
+func (c Component) isGuildChannel(channelID string) bool {
+	channel, err := c.Session.Channel(channelID)
+	if err != nil {
+		log.Printf("Warning: could not get channel '%s': %v", channelID, err)
+		return false
+	}
+	return channel.GuildID == c.Interaction.GuildID
+}
+
+// isGuildRole returns whether the given role belongs to the guild of the interaction.
+func (c Component) isGuildRole(roleID string) bool {
+	if _, err := c.Session.State.Role(c.Interaction.GuildID, roleID); err == nil {
+		return true
+	}
+	roles, err := c.Session.GuildRoles(c.Interaction.GuildID)
+	if err != nil {
+		log.Printf("Warning: could not get roles of guild '%s': %v", c.Interaction.GuildID, err)
+		return false
+	}
+	for _, r := range roles {
+		if r.ID == roleID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"cake4everybot/data/lang"
+	"cake4everybot/database"
+	"cake4everybot/util"
+	"fmt"
+	logger "log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// Prefix for translation key, i.e.:
+	//   key := tp+"base" // => settings
+	tp = "discord.command.settings."
+)
+
+var log = logger.New(logger.Writer(), "[Settings] ", logger.LstdFlags|logger.Lmsgprefix)
+
+type settingsBase struct {
+	util.InteractionUtil
+	member *discordgo.Member
+	user   *discordgo.User
+}
+
+// setting describes a single per-guild setting, which is stored in a column of the guilds table.
+type setting struct {
+	// The column in the guilds table
+	column string
+	// Whether the setting holds a role ID. Otherwise it holds a channel ID.
+	role bool
+	// The allowed channel types, only used when role is false
+	channelTypes []discordgo.ChannelType
+}
+
+// settings is the list of all settings that can be changed by the settings command. The order is
+// the same as shown to the user.
+var settings = []setting{
+	{column: "birthday_id", channelTypes: textChannelTypes},
+	{column: "youtube_channel", channelTypes: textChannelTypes},
+	{column: "youtube_role", role: true},
+	{column: "twitch_channel", channelTypes: textChannelTypes},
+	{column: "twitch_role", role: true},
+	{column: "adventcalendar_channel", channelTypes: textChannelTypes},
+	{column: "log_channel", channelTypes: textChannelTypes},
+	{column: "no_mic_id", channelTypes: textChannelTypes},
+}
+
+var textChannelTypes = []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews}
+
+// getSetting returns the setting for the given column
+func getSetting(column string) (setting, bool) {
+	for _, s := range settings {
+		if s.column == column {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// name returns the translated display name of the setting
+func (s setting) name() string {
+	return lang.GetDefault(tp + "setting." + s.column)
+}
+
+// mention formats the given id as a channel or role mention
+func (s setting) mention(id string) string {
+	if id == "" {
+		return lang.GetDefault(tp + "msg.not_set")
+	}
+	if s.role {
+		return fmt.Sprintf("<@&%s>", id)
+	}
+	return fmt.Sprintf("<#%s>", id)
+}
+
+// hasPermission returns whether the member of the current interaction is allowed to change the
+// settings of the guild.
+func (sb settingsBase) hasPermission() bool {
+	return sb.Interaction.GuildID != "" &&
+		sb.member != nil &&
+		sb.member.Permissions&discordgo.PermissionManageServer != 0
+}
+
+// settingsEmbed returns an embed containing the current values of all settings of the guild.
+func (sb settingsBase) settingsEmbed() (*discordgo.MessageEmbed, error) {
+	values, err := database.GetGuildSettings(sb.Interaction.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &discordgo.MessageEmbed{
+		Title: lang.GetDefault(tp + "msg.title"),
+		Color: 0x5865F2,
+	}
+	for _, s := range settings {
+		util.AddEmbedField(e, s.name(), s.mention(values[s.column]), true)
+	}
+
+	timezone, err := database.GetGuildTimezone(sb.Interaction.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	if timezone == "" {
+		timezone = fmt.Sprintf(lang.GetDefault(tp+"msg.timezone_default"), time.Local.String())
+	} else {
+		timezone = fmt.Sprintf("`%s`", timezone)
+	}
+	util.AddEmbedField(e, lang.GetDefault(tp+"setting.timezone"), timezone, true)
+	util.SetEmbedFooter(sb.Session, tp+"display", e)
+	return e, nil
+}
+
+// editComponents returns the select menu and reset button to change the given setting.
+func (sb settingsBase) editComponents(s setting) []discordgo.MessageComponent {
+	id := fmt.Sprintf("%s.set.%s", Component{}.ID(), s.column)
+	var menu discordgo.SelectMenu
+	if s.role {
+		menu = util.CreateRoleSelectMenuComponent(id, lang.GetDefault(tp+"msg.edit.placeholder.role"), 1, 1)
+	} else {
+		menu = util.CreateChannelSelectMenuComponent(id, lang.GetDefault(tp+"msg.edit.placeholder.channel"), 1, 1, s.channelTypes...)
+	}
+
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{menu}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			util.CreateButtonComponent(
+				fmt.Sprintf("%s.reset.%s", Component{}.ID(), s.column),
+				lang.GetDefault(tp+"msg.edit.reset"),
+				discordgo.DangerButton,
+				nil,
+			),
+		}},
+	}
+}
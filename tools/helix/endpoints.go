@@ -0,0 +1,141 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helix
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// GetStreams returns the live streams of the given users. Users that are offline are not included.
+// At most 100 user IDs are allowed.
+func (h *Helix) GetStreams(userIDs ...string) ([]Stream, error) {
+	query := url.Values{"user_id": userIDs}
+	query.Set("first", "100")
+
+	var streams []Stream
+	err := h.getData("/streams", query, &streams)
+	return streams, err
+}
+
+// GetUsers returns the users with the given IDs. At most 100 user IDs are allowed.
+func (h *Helix) GetUsers(userIDs ...string) ([]User, error) {
+	var users []User
+	err := h.getData("/users", url.Values{"id": userIDs}, &users)
+	return users, err
+}
+
+// GetUsersByLogin returns the users with the given login names. At most 100 logins are allowed.
+func (h *Helix) GetUsersByLogin(logins ...string) ([]User, error) {
+	var users []User
+	err := h.getData("/users", url.Values{"login": logins}, &users)
+	return users, err
+}
+
+// GetEventSubSubscriptions returns all EventSub subscriptions of the application.
+func (h *Helix) GetEventSubSubscriptions() ([]EventSubSubscription, error) {
+	var (
+		subscriptions []EventSubSubscription
+		cursor        string
+	)
+	for {
+		query := url.Values{}
+		if cursor != "" {
+			query.Set("after", cursor)
+		}
+
+		r, err := h.doReq(http.MethodGet, "/eventsub/subscriptions", query, nil)
+		if err != nil {
+			return subscriptions, err
+		}
+		data, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return subscriptions, err
+		}
+		if r.StatusCode != http.StatusOK {
+			return subscriptions, fmt.Errorf("wrong status code, expected 200 but got %d! Response data: %s", r.StatusCode, string(data))
+		}
+
+		var page struct {
+			Data       []EventSubSubscription `json:"data"`
+			Pagination struct {
+				Cursor string `json:"cursor"`
+			} `json:"pagination"`
+		}
+		if err = json.Unmarshal(data, &page); err != nil {
+			return subscriptions, err
+		}
+		subscriptions = append(subscriptions, page.Data...)
+		if page.Pagination.Cursor == "" || len(page.Data) == 0 {
+			return subscriptions, nil
+		}
+		cursor = page.Pagination.Cursor
+	}
+}
+
+// CreateEventSubSubscription creates a new EventSub subscription. For webhooks Twitch sends a
+// verification request to the callback before this returns. The created subscription is returned.
+func (h *Helix) CreateEventSubSubscription(sub EventSubSubscription) (*EventSubSubscription, error) {
+	body, err := json.Marshal(sub)
+	if err != nil {
+		return nil, err
+	}
+	r, err := h.doReq(http.MethodPost, "/eventsub/subscriptions", nil, body)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusAccepted {
+		return nil, fmt.Errorf("wrong status code, expected 202 but got %d! Response data: %s", r.StatusCode, string(data))
+	}
+
+	var created struct {
+		Data []EventSubSubscription `json:"data"`
+	}
+	if err = json.Unmarshal(data, &created); err != nil {
+		return nil, err
+	}
+	if len(created.Data) == 0 {
+		return nil, fmt.Errorf("no subscription in response: %s", string(data))
+	}
+	return &created.Data[0], nil
+}
+
+// DeleteEventSubSubscription deletes the EventSub subscription with the given ID.
+func (h *Helix) DeleteEventSubSubscription(id string) error {
+	r, err := h.doReq(http.MethodDelete, "/eventsub/subscriptions", url.Values{"id": {id}}, nil)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("wrong status code, expected 204 but got %d! But also failed to read data: %v", r.StatusCode, err)
+	}
+	return fmt.Errorf("wrong status code, expected 204 but got %d! Response data: %s", r.StatusCode, string(data))
+}
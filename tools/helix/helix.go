@@ -0,0 +1,175 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the base URL of the Twitch Helix API.
+	DefaultBaseURL = "https://api.twitch.tv/helix"
+	// DefaultAuthURL is the base URL of the Twitch OAuth server.
+	DefaultAuthURL = "https://id.twitch.tv/oauth2"
+)
+
+// Helix is the base type for communication with the Twitch Helix API. It authenticates with an app
+// access token, which is requested and renewed automatically using the client credentials, or with
+// a fixed user access token (see SetUserToken).
+type Helix struct {
+	c            *http.Client
+	clientID     string
+	clientSecret string
+	baseURL      string
+	authURL      string
+
+	tokenMux     sync.Mutex
+	token        string
+	tokenExpires time.Time
+	userToken    string
+}
+
+// New returns a new Helix API connection with the given application credentials.
+func New(clientID, clientSecret string) *Helix {
+	return &Helix{
+		c:            &http.Client{Timeout: 10 * time.Second},
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		baseURL:      DefaultBaseURL,
+		authURL:      DefaultAuthURL,
+	}
+}
+
+// SetBaseURL changes the URLs used for the API and the OAuth server, e.g. to use a mock server in
+// tests. Empty values keep the current URL.
+func (h *Helix) SetBaseURL(baseURL, authURL string) {
+	if baseURL != "" {
+		h.baseURL = baseURL
+	}
+	if authURL != "" {
+		h.authURL = authURL
+	}
+}
+
+// SetUserToken makes h authenticate with the given user access token instead of an app access
+// token. Some endpoints, like creating websocket EventSub subscriptions, require a user token. It
+// must belong to the client ID of h. The "oauth:" prefix of chat tokens is removed.
+func (h *Helix) SetUserToken(token string) {
+	h.tokenMux.Lock()
+	defer h.tokenMux.Unlock()
+	h.userToken = strings.TrimPrefix(token, "oauth:")
+}
+
+// accessToken returns the user token, if set. Otherwise it returns a valid app access token,
+// requesting a new one if needed.
+func (h *Helix) accessToken(renew bool) (string, error) {
+	h.tokenMux.Lock()
+	defer h.tokenMux.Unlock()
+	if h.userToken != "" {
+		return h.userToken, nil
+	}
+	if !renew && h.token != "" && time.Until(h.tokenExpires) > time.Minute {
+		return h.token, nil
+	}
+
+	form := url.Values{}
+	form.Set("client_id", h.clientID)
+	form.Set("client_secret", h.clientSecret)
+	form.Set("grant_type", "client_credentials")
+	r, err := h.c.PostForm(h.authURL+"/token", form)
+	if err != nil {
+		return "", err
+	}
+	defer r.Body.Close()
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	if r.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("wrong status code on requesting app token, expected 200 but got %d! Response data: %s", r.StatusCode, string(data))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err = json.Unmarshal(data, &token); err != nil {
+		return "", err
+	}
+	h.token = token.AccessToken
+	h.tokenExpires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return h.token, nil
+}
+
+// doReq makes a new request with the given properties and parameters. When the token was rejected
+// it is renewed and the request is sent once more.
+func (h *Helix) doReq(method, path string, query url.Values, body []byte) (*http.Response, error) {
+	for renew := false; ; renew = true {
+		token, err := h.accessToken(renew)
+		if err != nil {
+			return nil, fmt.Errorf("get access token: %v", err)
+		}
+
+		reqURL := h.baseURL + path
+		if len(query) > 0 {
+			reqURL += "?" + query.Encode()
+		}
+		req, err := http.NewRequest(method, reqURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Client-Id", h.clientID)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		r, err := h.c.Do(req)
+		if err != nil || r.StatusCode != http.StatusUnauthorized || renew {
+			return r, err
+		}
+		r.Body.Close()
+	}
+}
+
+// getData makes a GET request to path and unmarshals the 'data' field of the response into v.
+func (h *Helix) getData(path string, query url.Values, v any) error {
+	r, err := h.doReq(http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("wrong status code, expected 200 but got %d! Response data: %s", r.StatusCode, string(data))
+	}
+
+	return json.Unmarshal(data, &struct {
+		Data any `json:"data"`
+	}{Data: v})
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helix
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stream is a live stream as returned by the '/streams' endpoint.
+type Stream struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id"`
+	UserLogin    string    `json:"user_login"`
+	UserName     string    `json:"user_name"`
+	GameID       string    `json:"game_id"`
+	GameName     string    `json:"game_name"`
+	Type         string    `json:"type"`
+	Title        string    `json:"title"`
+	Tags         []string  `json:"tags"`
+	ViewerCount  int       `json:"viewer_count"`
+	StartedAt    time.Time `json:"started_at"`
+	Language     string    `json:"language"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	IsMature     bool      `json:"is_mature"`
+}
+
+// Thumbnail returns the thumbnail URL of s in the given size.
+func (s Stream) Thumbnail(width, height int) string {
+	r := strings.NewReplacer("{width}", strconv.Itoa(width), "{height}", strconv.Itoa(height))
+	return r.Replace(s.ThumbnailURL)
+}
+
+// User is a Twitch user as returned by the '/users' endpoint.
+type User struct {
+	ID              string    `json:"id"`
+	Login           string    `json:"login"`
+	DisplayName     string    `json:"display_name"`
+	Type            string    `json:"type"`
+	BroadcasterType string    `json:"broadcaster_type"`
+	Description     string    `json:"description"`
+	ProfileImageURL string    `json:"profile_image_url"`
+	OfflineImageURL string    `json:"offline_image_url"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// EventSubSubscription is a subscription to an EventSub event as returned by the
+// '/eventsub/subscriptions' endpoint.
+type EventSubSubscription struct {
+	ID      string `json:"id,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Type    string `json:"type"`
+	Version string `json:"version"`
+	// Condition contains the parameters under which the event fires, like
+	// "broadcaster_user_id":"12345".
+	Condition map[string]string `json:"condition"`
+	Transport EventSubTransport `json:"transport"`
+	CreatedAt time.Time         `json:"created_at,omitempty"`
+	Cost      int               `json:"cost,omitempty"`
+}
+
+// EventSubTransport describes how the notifications of an EventSubSubscription are delivered.
+type EventSubTransport struct {
+	// Method is either "webhook" or "websocket".
+	Method string `json:"method"`
+	// Callback is the URL of the webhook. Only when Method is "webhook".
+	Callback string `json:"callback,omitempty"`
+	// Secret is used to sign the notifications. It is only set when creating a webhook
+	// subscription and never returned.
+	Secret string `json:"secret,omitempty"`
+	// SessionID is the ID of the websocket session. Only when Method is "websocket".
+	SessionID string `json:"session_id,omitempty"`
+}
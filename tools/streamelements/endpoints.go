@@ -1,32 +1,19 @@
 package streamelements
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 )
 
 // GetChannels returns a list of all channels the current user has access to. The current user is
 // defined by the used bearer token.
-func (se *Streamelements) GetChannels() ([]*Channel1, error) {
+func (se *Streamelements) GetChannels(ctx context.Context) ([]*Channel1, error) {
 	var channels []*Channel1 = make([]*Channel1, 0)
-
-	r, err := se.doReq(http.MethodGet, "/users/channels", []byte{}, nil)
-	if err != nil {
-		return channels, err
-	}
-
-	data, err := io.ReadAll(r.Body)
-	if err != nil {
-		return channels, err
-	}
-
-	if r.StatusCode != 200 {
-		return channels, fmt.Errorf("wrong status code, expected 200 but got %d! Response data: %s", r.StatusCode, string(data))
-	}
-
-	err = json.Unmarshal(data, &channels)
+	err := se.getJSON(ctx, "/users/channels", &channels)
 	return channels, err
 }
 
@@ -35,116 +22,160 @@ func (se *Streamelements) GetChannels() ([]*Channel1, error) {
 //
 // NOTE: Some documentation is missing from streamelements. It appears that this endpoint only
 // only returns the current users channel.
-func (se *Streamelements) GetChannelDetails(channelID string) (*ChannelDetails, error) {
-	r, err := se.doReq(
-		http.MethodGet,
-		fmt.Sprintf("/channels/%s/details", channelID),
-		[]byte{},
-		nil,
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	data, err := io.ReadAll(r.Body)
-	if err != nil {
+func (se *Streamelements) GetChannelDetails(ctx context.Context, channelID string) (*ChannelDetails, error) {
+	c := &ChannelDetails{}
+	if err := se.getJSON(ctx, fmt.Sprintf("/channels/%s/details", url.PathEscape(channelID)), c); err != nil {
 		return nil, err
 	}
-
-	if r.StatusCode != 200 {
-		return nil, fmt.Errorf("wrong status code, expected 200 but got %d! Response data: %s", r.StatusCode, string(data))
-	}
-
-	c := &ChannelDetails{}
-	err = json.Unmarshal(data, c)
-	return c, err
+	return c, nil
 }
 
 // GetChannel returns basic details for the given channel. This endpoint does not requiere access to
 // the requested channel.
 //
 // "channel" can be either a streamelements channel ID or the username of a channel.
-func (se *Streamelements) GetChannel(channel string) (*SimpleChannelDetails, error) {
-	r, err := se.doReq(
-		http.MethodGet,
-		fmt.Sprintf("/channels/%s", channel),
-		[]byte{},
-		nil,
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	data, err := io.ReadAll(r.Body)
-	if err != nil {
+func (se *Streamelements) GetChannel(ctx context.Context, channel string) (*SimpleChannelDetails, error) {
+	c := &SimpleChannelDetails{}
+	if err := se.getJSON(ctx, fmt.Sprintf("/channels/%s", url.PathEscape(channel)), c); err != nil {
 		return nil, err
 	}
-
-	if r.StatusCode != 200 {
-		return nil, fmt.Errorf("wrong status code, expected 200 but got %d! Response data: %s", r.StatusCode, string(data))
-	}
-
-	c := &SimpleChannelDetails{}
-	err = json.Unmarshal(data, c)
-	return c, err
+	return c, nil
 }
 
 // AddPoints modifies (add or remove) the streamelements points for a user. When amount == 0
-// AddPoints is a no-op.
+// AddPoints is a no-op. As it is not idempotent, it is not retried after server errors.
 //
 //	channelID // the streamelements ID of the channel to add the points to
 //	username  // the username to modify
 //	amount    // the amount to modify, amount > 0 adds the points, amount < 0 removes the points.
-func (se *Streamelements) AddPoints(channelID, username string, amount int) error {
+func (se *Streamelements) AddPoints(ctx context.Context, channelID, username string, amount int) error {
 	if amount == 0 {
 		return nil
 	}
-	r, err := se.doReq(
+	_, err := se.doReq(
+		ctx,
 		http.MethodPut,
-		fmt.Sprintf("/points/%s/%s/%d", channelID, username, amount),
+		fmt.Sprintf("/points/%s/%s/%d", url.PathEscape(channelID), url.PathEscape(username), amount),
 		[]byte("{}"),
 		map[string]string{"Content-Type": "application/json"},
 	)
-	if err != nil {
-		return err
-	}
+	return err
+}
 
-	if r.StatusCode == 200 {
-		return nil
+// GetPoints returns the current streamelements points for a user.
+//
+//	channelID // the streamelements ID of the channel to get the points from
+//	username  // the username to fetch
+func (se *Streamelements) GetPoints(ctx context.Context, channelID, username string) (*UserPoints, error) {
+	up := &UserPoints{}
+	if err := se.getJSON(ctx, fmt.Sprintf("/points/%s/%s", url.PathEscape(channelID), url.PathEscape(username)), up); err != nil {
+		return nil, err
 	}
+	return up, nil
+}
 
-	data, err := io.ReadAll(r.Body)
-	if err != nil {
-		return fmt.Errorf("wrong status code, expected 200 but got %d! But also failed to read data: %v", r.StatusCode, err)
+// GetTopPoints returns the users with the most points in a channel, starting at offset.
+//
+//	channelID // the streamelements ID of the channel
+//	limit     // the maximum number of users, at most 1000
+//	offset    // the number of users to skip
+func (se *Streamelements) GetTopPoints(ctx context.Context, channelID string, limit, offset int) (*Leaderboard, error) {
+	return se.getLeaderboard(ctx, channelID, "top", limit, offset)
+}
+
+// GetTopWatchtime returns the users with the most watch time in a channel, starting at offset. The
+// watch time is in Minutes of each entry.
+//
+//	channelID // the streamelements ID of the channel
+//	limit     // the maximum number of users, at most 1000
+//	offset    // the number of users to skip
+func (se *Streamelements) GetTopWatchtime(ctx context.Context, channelID string, limit, offset int) (*Leaderboard, error) {
+	return se.getLeaderboard(ctx, channelID, "watchtime", limit, offset)
+}
+
+// getLeaderboard returns the leaderboard with the given name, see GetTopPoints.
+func (se *Streamelements) getLeaderboard(ctx context.Context, channelID, name string, limit, offset int) (*Leaderboard, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+	lb := &Leaderboard{}
+	if err := se.getJSON(ctx, fmt.Sprintf("/points/%s/%s?%s", url.PathEscape(channelID), name, query.Encode()), lb); err != nil {
+		return nil, err
 	}
-	return fmt.Errorf("wrong status code, expected 200 but got %d! Response data: %s", r.StatusCode, string(data))
+	return lb, nil
 }
 
-// GetPoints returns the current streamelements points for a user.
+// GetUserRank returns the points and the rank of a user in a channel.
 //
-//	channelID // the streamelements ID of the channel to get the points from
+//	channelID // the streamelements ID of the channel
 //	username  // the username to fetch
-func (se *Streamelements) GetPoints(channelID, username string) (*UserPoints, error) {
-	r, err := se.doReq(
-		http.MethodGet,
-		fmt.Sprintf("/points/%s/%s", channelID, username),
-		[]byte{},
-		nil,
-	)
-	if err != nil {
+func (se *Streamelements) GetUserRank(ctx context.Context, channelID, username string) (*UserRank, error) {
+	ur := &UserRank{}
+	if err := se.getJSON(ctx, fmt.Sprintf("/points/%s/%s/rank", url.PathEscape(channelID), url.PathEscape(username)), ur); err != nil {
 		return nil, err
 	}
+	return ur, nil
+}
 
-	data, err := io.ReadAll(r.Body)
-	if err != nil {
+// UpdatePointsBulk changes the points of many users in a channel at once. With BulkPointsAdd the
+// Current points of each user are added, with BulkPointsSet they replace the points. Like
+// AddPoints it is not retried after server errors.
+func (se *Streamelements) UpdatePointsBulk(ctx context.Context, channelID string, mode BulkPointsMode, users []BulkPointsUser) (*BulkPointsResult, error) {
+	body := struct {
+		Mode  BulkPointsMode   `json:"mode"`
+		Users []BulkPointsUser `json:"users"`
+	}{mode, users}
+	res := &BulkPointsResult{}
+	if err := se.sendJSON(ctx, http.MethodPut, fmt.Sprintf("/points/%s", url.PathEscape(channelID)), body, res); err != nil {
 		return nil, err
 	}
+	return res, nil
+}
+
+// GetStoreItems returns all items of the loyalty store of a channel.
+func (se *Streamelements) GetStoreItems(ctx context.Context, channelID string) ([]StoreItem, error) {
+	items := make([]StoreItem, 0)
+	err := se.getJSON(ctx, fmt.Sprintf("/store/%s/items", url.PathEscape(channelID)), &items)
+	return items, err
+}
 
-	if r.StatusCode != 200 {
-		return nil, fmt.Errorf("wrong status code, expected 200 but got %d! Response data: %v", r.StatusCode, string(data))
+// GetRedemptions returns the redemptions of store items in a channel, newest first.
+//
+//	channelID // the streamelements ID of the channel
+//	pending   // only return redemptions that are not completed yet
+//	limit     // the maximum number of redemptions
+//	offset    // the number of redemptions to skip
+func (se *Streamelements) GetRedemptions(ctx context.Context, channelID string, pending bool, limit, offset int) (*Redemptions, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+	if pending {
+		query.Set("pending", "true")
+	}
+	r := &Redemptions{}
+	if err := se.getJSON(ctx, fmt.Sprintf("/store/%s/redemptions?%s", url.PathEscape(channelID), query.Encode()), r); err != nil {
+		return nil, err
 	}
+	return r, nil
+}
 
-	up := &UserPoints{}
-	err = json.Unmarshal(data, up)
-	return up, err
+// CompleteRedemption marks a redemption in a channel as completed.
+func (se *Streamelements) CompleteRedemption(ctx context.Context, channelID, redemptionID string) error {
+	return se.sendJSON(ctx, http.MethodPut,
+		fmt.Sprintf("/store/%s/redemptions/%s", url.PathEscape(channelID), url.PathEscape(redemptionID)),
+		map[string]bool{"completed": true}, nil)
+}
+
+// GetActivities returns the activity feed of a channel filtered by q, newest first.
+func (se *Streamelements) GetActivities(ctx context.Context, channelID string, q ActivityQuery) ([]Activity, error) {
+	query := url.Values{}
+	query.Set("after", q.After.UTC().Format(time.RFC3339))
+	query.Set("before", q.Before.UTC().Format(time.RFC3339))
+	query.Set("limit", strconv.Itoa(q.Limit))
+	for _, t := range q.Types {
+		query.Add("types", t)
+	}
+	activities := make([]Activity, 0)
+	err := se.getJSON(ctx, fmt.Sprintf("/activities/%s?%s", url.PathEscape(channelID), query.Encode()), &activities)
+	return activities, err
 }
@@ -0,0 +1,88 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streamelements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrUnauthorized is returned when the token is invalid or expired (401).
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden is returned when the token has no access to the requested channel (403).
+	ErrForbidden = errors.New("forbidden")
+	// ErrNotFound is returned when the requested channel or user doesn't exist (404).
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited is returned when too many requests were made (429).
+	ErrRateLimited = errors.New("rate limited")
+	// ErrServer is returned when StreamElements failed to handle the request (5xx).
+	ErrServer = errors.New("server error")
+)
+
+// APIError is the error for a response of the StreamElements API with a status code other than
+// 2xx. Use errors.Is with one of the Err variables to check for a specific kind of error.
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	// Message is the error message of StreamElements. If the response wasn't a JSON error, it is
+	// the raw response body.
+	Message string
+}
+
+// newAPIError returns the APIError for a response with the given status code and body.
+func newAPIError(method, path string, statusCode int, body []byte) *APIError {
+	e := &APIError{Method: method, Path: path, StatusCode: statusCode, Message: string(body)}
+	var data struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &data) == nil && (data.Message != "" || data.Error != "") {
+		e.Message = data.Message
+		if e.Message == "" {
+			e.Message = data.Error
+		}
+	}
+	return e
+}
+
+// Error implements error.
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("streamelements: %s %s: %d %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Unwrap returns the Err variable matching the status code of e, if any.
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= 500:
+		return ErrServer
+	}
+	return nil
+}
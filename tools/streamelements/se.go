@@ -2,8 +2,30 @@ package streamelements
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the base URL of the StreamElements API.
+	DefaultBaseURL = "https://api.streamelements.com/kappa/v2"
+	// DefaultTimeout is the default timeout of a single request.
+	DefaultTimeout = 10 * time.Second
+
+	// defaultRetries is how often requests are retried by default, see SetRetries
+	defaultRetries = 3
+	// defaultBackoff is the default backoff before the first retry, see SetRetries
+	defaultBackoff = 500 * time.Millisecond
+	// defaultRateLimitWait is the time to wait after a 429 without a reset header
+	defaultRateLimitWait = time.Second
 )
 
 // New returns a new Streamelements API connection with the given bearer token.
@@ -12,16 +34,137 @@ func New(token string) *Streamelements {
 		token = "Bearer " + token
 	}
 	se := &Streamelements{
-		c:     &http.Client{},
-		token: token,
+		c:       &http.Client{Timeout: DefaultTimeout},
+		token:   token,
+		baseURL: DefaultBaseURL,
+		retries: defaultRetries,
+		backoff: defaultBackoff,
 	}
 	return se
 }
 
-// doReq makes a new request with the given properties and parameters.
-func (se *Streamelements) doReq(method, path string, body []byte, header map[string]string) (*http.Response, error) {
-	const baseURL = "https://api.streamelements.com/kappa/v2"
-	req, err := http.NewRequest(method, baseURL+path, bytes.NewReader(body))
+// SetBaseURL changes the URL used for the API, e.g. to use a fake server in tests. An empty value
+// keeps the current URL.
+func (se *Streamelements) SetBaseURL(baseURL string) {
+	if baseURL != "" {
+		se.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// SetTimeout changes the timeout of a single request. A timeout of 0 means no timeout, but the
+// context of a request is still respected.
+func (se *Streamelements) SetTimeout(timeout time.Duration) {
+	se.c.Timeout = timeout
+}
+
+// SetRetries changes how often a request is retried after a temporary error and the backoff
+// before the first retry, which doubles with each further retry. Only idempotent requests are
+// retried after server or network errors, but every request is retried when rate limited.
+func (se *Streamelements) SetRetries(retries int, backoff time.Duration) {
+	se.retries = retries
+	se.backoff = backoff
+}
+
+// rateLimiter keeps track of the rate limit StreamElements reports in its response headers.
+type rateLimiter struct {
+	mu        sync.Mutex
+	remaining int
+	reset     time.Time
+}
+
+// wait blocks until the rate limit allows another request or ctx is done.
+func (rl *rateLimiter) wait(ctx context.Context) error {
+	rl.mu.Lock()
+	var d time.Duration
+	if rl.remaining <= 0 && !rl.reset.IsZero() {
+		d = time.Until(rl.reset)
+	}
+	if d <= 0 {
+		rl.mu.Unlock()
+		return nil
+	}
+	rl.mu.Unlock()
+	return sleep(ctx, d)
+}
+
+// update stores the rate limit of the response headers h. tooMany is whether the request was
+// rejected because of the rate limit.
+func (rl *rateLimiter) update(h http.Header, tooMany bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		// no rate limit headers, so only a 429 stops further requests
+		remaining = 1
+	}
+	reset := time.Time{}
+	if ms, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		reset = time.UnixMilli(ms)
+	}
+	if seconds, err := strconv.Atoi(h.Get("Retry-After")); err == nil {
+		reset = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	if tooMany {
+		remaining = 0
+		if !reset.After(time.Now()) {
+			reset = time.Now().Add(defaultRateLimitWait)
+		}
+	}
+	rl.remaining, rl.reset = remaining, reset
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// doReq makes a new request with the given properties and parameters and returns the response
+// body. A response other than 2xx is returned as *APIError.
+//
+// Before each request doReq waits for the rate limit. Rate limited requests are always retried,
+// while server and network errors are only retried for GET requests, as others might have been
+// applied already.
+func (se *Streamelements) doReq(ctx context.Context, method, path string, body []byte, header map[string]string) ([]byte, error) {
+	idempotent := method == http.MethodGet
+	backoff := se.backoff
+	for attempt := 0; ; attempt++ {
+		if err := se.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		data, err := se.do(ctx, method, path, body, header)
+		if err == nil || attempt >= se.retries || ctx.Err() != nil {
+			return data, err
+		}
+		var apiErr *APIError
+		switch {
+		case errors.Is(err, ErrRateLimited):
+			// the limiter waits for the reset
+			continue
+		case !idempotent:
+			return data, err
+		case errors.As(err, &apiErr) && !errors.Is(err, ErrServer):
+			return data, err
+		}
+
+		if err := sleep(ctx, backoff); err != nil {
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// do makes a single request, see doReq.
+func (se *Streamelements) do(ctx context.Context, method, path string, body []byte, header map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, se.baseURL+path, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
@@ -30,6 +173,50 @@ func (se *Streamelements) doReq(method, path string, body []byte, header map[str
 		req.Header.Set(k, v)
 	}
 	req.Header.Set("Authorization", se.token)
+	req.Header.Set("Accept", "application/json")
 
-	return se.c.Do(req)
+	r, err := se.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	se.limiter.update(r.Header, r.StatusCode == http.StatusTooManyRequests)
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		return nil, newAPIError(method, path, r.StatusCode, data)
+	}
+	return data, nil
+}
+
+// sendJSON makes a request to path with body encoded as JSON and unmarshals the response into v,
+// if v is not nil.
+func (se *Streamelements) sendJSON(ctx context.Context, method, path string, body, v any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	data, err = se.doReq(ctx, method, path, data, map[string]string{"Content-Type": "application/json"})
+	if err != nil || v == nil {
+		return err
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decode response of %s %s: %v", method, path, err)
+	}
+	return nil
+}
+
+// getJSON makes a GET request to path and unmarshals the response into v.
+func (se *Streamelements) getJSON(ctx context.Context, path string, v any) error {
+	data, err := se.doReq(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decode response of GET %s: %v", path, err)
+	}
+	return nil
 }
@@ -0,0 +1,210 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streamelements_test
+
+import (
+	"cake4everybot/tools/streamelements"
+	"cake4everybot/tools/streamelements/sefake"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPoints(t *testing.T) {
+	s := sefake.New(t, "token")
+	s.AddChannel("1", "foo")
+	s.SetPoints("1", "bar", 100)
+	se := s.Client()
+	ctx := context.Background()
+
+	c, err := se.GetChannel(ctx, "foo")
+	if err != nil || c.ID != "1" {
+		t.Fatalf("GetChannel() = %+v, %v, want ID 1", c, err)
+	}
+	if err = se.AddPoints(ctx, c.ID, "bar", -30); err != nil {
+		t.Fatalf("AddPoints() error = %v", err)
+	}
+	if up, err := se.GetPoints(ctx, c.ID, "bar"); err != nil || up.Points != 70 {
+		t.Errorf("GetPoints() = %+v, %v, want 70 points", up, err)
+	}
+	if _, err = se.GetPoints(ctx, c.ID, "baz"); !errors.Is(err, streamelements.ErrNotFound) {
+		t.Errorf("GetPoints() of unknown user error = %v, want %v", err, streamelements.ErrNotFound)
+	}
+	var apiErr *streamelements.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "user not found" {
+		t.Errorf("GetPoints() of unknown user error = %#v, want APIError with message", err)
+	}
+
+	wrong := streamelements.New("wrong")
+	wrong.SetBaseURL(s.URL)
+	if _, err = wrong.GetChannels(ctx); !errors.Is(err, streamelements.ErrUnauthorized) {
+		t.Errorf("GetChannels() with wrong token error = %v, want %v", err, streamelements.ErrUnauthorized)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		count        int
+		put          bool
+		wantErr      error
+		wantRequests int
+	}{
+		{name: "server error retried", status: http.StatusBadGateway, count: 2, wantRequests: 3},
+		{name: "server error gives up", status: http.StatusInternalServerError, count: 5, wantErr: streamelements.ErrServer, wantRequests: 4},
+		{name: "rate limit retried", status: http.StatusTooManyRequests, count: 1, wantRequests: 2},
+		{name: "client error not retried", status: http.StatusForbidden, count: 1, wantErr: streamelements.ErrForbidden, wantRequests: 1},
+		{name: "put not retried", status: http.StatusBadGateway, count: 1, put: true, wantErr: streamelements.ErrServer, wantRequests: 1},
+		{name: "put rate limit retried", status: http.StatusTooManyRequests, count: 1, put: true, wantRequests: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := sefake.New(t, "token")
+			s.AddChannel("1", "foo")
+			s.SetPoints("1", "bar", 100)
+			s.Fail("/points/", tt.status, tt.count)
+			se := s.Client()
+
+			var err error
+			if tt.put {
+				err = se.AddPoints(context.Background(), "1", "bar", 10)
+			} else {
+				_, err = se.GetPoints(context.Background(), "1", "bar")
+			}
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+			if got := len(s.Requests()); got != tt.wantRequests {
+				t.Errorf("got %d requests %v, want %d", got, s.Requests(), tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	s := sefake.New(t, "token")
+	s.AddChannel("1", "foo")
+	s.Fail("/channels/", http.StatusTooManyRequests, 1)
+	se := s.Client()
+	se.SetRetries(0, 0)
+
+	if _, err := se.GetChannel(context.Background(), "foo"); !errors.Is(err, streamelements.ErrRateLimited) {
+		t.Fatalf("GetChannel() error = %v, want %v", err, streamelements.ErrRateLimited)
+	}
+	// the next request waits for the reset of the rate limit, so it isn't sent with a canceled
+	// context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := se.GetChannel(ctx, "foo"); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetChannel() with canceled context error = %v, want %v", err, context.Canceled)
+	}
+	if c, err := se.GetChannel(context.Background(), "foo"); err != nil || c.ID != "1" {
+		t.Errorf("GetChannel() after reset = %+v, %v", c, err)
+	}
+	if want := []string{"GET /channels/foo", "GET /channels/foo"}; !reflect.DeepEqual(s.Requests(), want) {
+		t.Errorf("got requests %v, want %v", s.Requests(), want)
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	s := sefake.New(t, "token")
+	s.AddChannel("1", "foo")
+	s.SetPoints("1", "a", 10)
+	s.SetPoints("1", "b", 30)
+	s.SetPoints("1", "c", 20)
+	s.SetWatchtime("1", "a", 600)
+	se := s.Client()
+	ctx := context.Background()
+
+	lb, err := se.GetTopPoints(ctx, "1", 2, 0)
+	want := &streamelements.Leaderboard{Total: 3, Users: []streamelements.LeaderboardEntry{{Username: "b", Points: 30}, {Username: "c", Points: 20}}}
+	if err != nil || !reflect.DeepEqual(lb, want) {
+		t.Errorf("GetTopPoints() = %+v, %v, want %+v", lb, err, want)
+	}
+	lb, err = se.GetTopWatchtime(ctx, "1", 10, 0)
+	want = &streamelements.Leaderboard{Total: 1, Users: []streamelements.LeaderboardEntry{{Username: "a", Minutes: 600}}}
+	if err != nil || !reflect.DeepEqual(lb, want) {
+		t.Errorf("GetTopWatchtime() = %+v, %v, want %+v", lb, err, want)
+	}
+
+	res, err := se.UpdatePointsBulk(ctx, "1", streamelements.BulkPointsAdd, []streamelements.BulkPointsUser{{Username: "a", Current: 25}, {Username: "d", Current: 5}})
+	if err != nil || res.Users != 2 {
+		t.Fatalf("UpdatePointsBulk() = %+v, %v, want 2 users", res, err)
+	}
+	if ur, err := se.GetUserRank(ctx, "1", "a"); err != nil || ur.Points != 35 || ur.Rank != 1 {
+		t.Errorf("GetUserRank() = %+v, %v, want 35 points on rank 1", ur, err)
+	}
+	if _, err = se.GetUserRank(ctx, "1", "e"); !errors.Is(err, streamelements.ErrNotFound) {
+		t.Errorf("GetUserRank() of unknown user error = %v, want %v", err, streamelements.ErrNotFound)
+	}
+}
+
+func TestRedemptions(t *testing.T) {
+	s := sefake.New(t, "token")
+	s.AddChannel("1", "foo")
+	s.AddStoreItem("1", streamelements.StoreItem{ID: "item", Name: "Ticket", Cost: 100, Enabled: true})
+	s.AddRedemption("1", streamelements.Redemption{ID: "r1", Item: streamelements.RedemptionItem{ID: "item"}, Completed: true})
+	s.AddRedemption("1", streamelements.Redemption{ID: "r2", Item: streamelements.RedemptionItem{ID: "item"}})
+	se := s.Client()
+	ctx := context.Background()
+
+	items, err := se.GetStoreItems(ctx, "1")
+	if err != nil || len(items) != 1 || items[0].Name != "Ticket" || items[0].ChannelID != "1" {
+		t.Errorf("GetStoreItems() = %+v, %v, want the ticket", items, err)
+	}
+	r, err := se.GetRedemptions(ctx, "1", true, 10, 0)
+	if err != nil || r.Total != 1 || r.Docs[0].ID != "r2" || r.Docs[0].Item.ID != "item" {
+		t.Fatalf("GetRedemptions(pending) = %+v, %v, want r2", r, err)
+	}
+	if err = se.CompleteRedemption(ctx, "1", "r2"); err != nil {
+		t.Fatalf("CompleteRedemption() error = %v", err)
+	}
+	if r, err = se.GetRedemptions(ctx, "1", true, 10, 0); err != nil || r.Total != 0 {
+		t.Errorf("GetRedemptions(pending) after completing = %+v, %v, want none", r, err)
+	}
+	if r, err = se.GetRedemptions(ctx, "1", false, 1, 1); err != nil || r.Total != 2 || len(r.Docs) != 1 || r.Docs[0].ID != "r1" {
+		t.Errorf("GetRedemptions(limit 1, offset 1) = %+v, %v, want r1 of 2", r, err)
+	}
+
+	var ri streamelements.RedemptionItem
+	if err = json.Unmarshal([]byte(`{"_id":"item","name":"Ticket","cost":100}`), &ri); err != nil || ri.Name != "Ticket" {
+		t.Errorf("unmarshal item object = %+v, %v", ri, err)
+	}
+}
+
+func TestActivities(t *testing.T) {
+	s := sefake.New(t, "token")
+	s.AddChannel("1", "foo")
+	now := time.Now().UTC().Truncate(time.Second)
+	s.AddActivity("1", streamelements.Activity{ID: "old", Type: "follow", CreatedAt: now.Add(-48 * time.Hour)})
+	s.AddActivity("1", streamelements.Activity{ID: "follow", Type: "follow", CreatedAt: now.Add(-time.Hour)})
+	s.AddActivity("1", streamelements.Activity{ID: "tip", Type: "tip", CreatedAt: now.Add(-time.Minute), Data: streamelements.ActivityData{Username: "bar", Amount: 2.5}})
+	se := s.Client()
+
+	q := streamelements.ActivityQuery{After: now.Add(-24 * time.Hour), Before: now, Limit: 10}
+	activities, err := se.GetActivities(context.Background(), "1", q)
+	if err != nil || len(activities) != 2 || activities[0].ID != "tip" || activities[0].Data.Amount != 2.5 {
+		t.Errorf("GetActivities() = %+v, %v, want tip and follow", activities, err)
+	}
+	q.Types = []string{"follow"}
+	if activities, err = se.GetActivities(context.Background(), "1", q); err != nil || len(activities) != 1 || activities[0].ID != "follow" {
+		t.Errorf("GetActivities(follow) = %+v, %v, want follow", activities, err)
+	}
+}
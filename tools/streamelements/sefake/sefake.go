@@ -0,0 +1,428 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sefake provides an in-memory fake of the StreamElements API for tests.
+package sefake
+
+import (
+	"cake4everybot/tools/streamelements"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server is a fake StreamElements API. It knows channels and the points of their users and can be
+// told to fail requests. Requests need the token the server was created with.
+type Server struct {
+	*httptest.Server
+	token string
+
+	mu       sync.Mutex
+	channels []streamelements.ChannelDetails
+	// public are the IDs of channels the token has no access to
+	public      map[string]bool
+	points      map[string]map[string]int
+	watchtime   map[string]map[string]int
+	items       map[string][]streamelements.StoreItem
+	redemptions map[string][]streamelements.Redemption
+	activities  map[string][]streamelements.Activity
+	failures    []failure
+	requests    []string
+}
+
+// failure is a status code to answer the next matching requests with
+type failure struct {
+	prefix     string
+	statusCode int
+	count      int
+}
+
+// New starts a new fake server accepting the given token. It is closed when the test ends.
+func New(tb testing.TB, token string) *Server {
+	s := &Server{
+		token:       token,
+		public:      map[string]bool{},
+		points:      map[string]map[string]int{},
+		watchtime:   map[string]map[string]int{},
+		items:       map[string][]streamelements.StoreItem{},
+		redemptions: map[string][]streamelements.Redemption{},
+		activities:  map[string][]streamelements.Activity{},
+	}
+	s.Server = httptest.NewServer(s)
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Client returns a client connected to s. It retries without backoff, so tests stay fast.
+func (s *Server) Client() *streamelements.Streamelements {
+	se := streamelements.New(s.token)
+	se.SetBaseURL(s.URL)
+	se.SetRetries(3, time.Millisecond)
+	return se
+}
+
+// AddChannel adds a channel with the given StreamElements ID and username. The token of s has
+// access to it.
+func (s *Server) AddChannel(id, username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := streamelements.ChannelDetails{}
+	c.ID, c.Username, c.DisplayName, c.Provider = id, username, username, "twitch"
+	s.channels = append(s.channels, c)
+}
+
+// AddPublicChannel is like AddChannel, but the token of s has no access to the channel. So its
+// details can't be read and it isn't listed in the channels of the user.
+func (s *Server) AddPublicChannel(id, username string) {
+	s.AddChannel(id, username)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.public[id] = true
+}
+
+// SetPoints sets the points of user in the channel with the given ID.
+func (s *Server) SetPoints(channelID, user string, points int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.points[channelID] == nil {
+		s.points[channelID] = map[string]int{}
+	}
+	s.points[channelID][strings.ToLower(user)] = points
+}
+
+// Points returns the points of user in the channel with the given ID.
+func (s *Server) Points(channelID, user string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.points[channelID][strings.ToLower(user)]
+}
+
+// SetWatchtime sets the watch time of user in the channel with the given ID in minutes.
+func (s *Server) SetWatchtime(channelID, user string, minutes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.watchtime[channelID] == nil {
+		s.watchtime[channelID] = map[string]int{}
+	}
+	s.watchtime[channelID][strings.ToLower(user)] = minutes
+}
+
+// AddStoreItem adds item to the store of the channel with the given ID.
+func (s *Server) AddStoreItem(channelID string, item streamelements.StoreItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item.ChannelID = channelID
+	s.items[channelID] = append(s.items[channelID], item)
+}
+
+// AddRedemption adds r to the redemptions of the channel with the given ID. Redemptions are
+// returned newest first, so the last added one comes first.
+func (s *Server) AddRedemption(channelID string, r streamelements.Redemption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r.ChannelID = channelID
+	s.redemptions[channelID] = append([]streamelements.Redemption{r}, s.redemptions[channelID]...)
+}
+
+// Redemptions returns all redemptions of the channel with the given ID, newest first.
+func (s *Server) Redemptions(channelID string) []streamelements.Redemption {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]streamelements.Redemption{}, s.redemptions[channelID]...)
+}
+
+// AddActivity adds a to the activity feed of the channel with the given ID. Like redemptions the
+// last added activity comes first.
+func (s *Server) AddActivity(channelID string, a streamelements.Activity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a.ChannelID = channelID
+	s.activities[channelID] = append([]streamelements.Activity{a}, s.activities[channelID]...)
+}
+
+// Fail answers the next count requests whose path starts with prefix with statusCode. A 429 also
+// sets the rate limit headers.
+func (s *Server) Fail(prefix string, statusCode, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{prefix: prefix, statusCode: statusCode, count: count})
+}
+
+// Requests returns all requests received so far, like 'GET /points/1/foo'.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	writeError := func(status int, message string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]any{"statusCode": status, "error": http.StatusText(status), "message": message})
+	}
+	writeJSON := func(v any) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+s.token {
+		writeError(http.StatusUnauthorized, "invalid token")
+		return
+	}
+	for i := range s.failures {
+		f := &s.failures[i]
+		if f.count <= 0 || !strings.HasPrefix(r.URL.Path, f.prefix) {
+			continue
+		}
+		f.count--
+		if f.statusCode == http.StatusTooManyRequests {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(100*time.Millisecond).UnixMilli(), 10))
+		}
+		writeError(f.statusCode, "injected failure")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "users" && parts[1] == "channels":
+		channels := make([]streamelements.Channel1, 0, len(s.channels))
+		for _, c := range s.channels {
+			if !s.public[c.ID] {
+				channels = append(channels, streamelements.Channel1{SimpleChannelDetails: c.SimpleChannelDetails, Role: "owner"})
+			}
+		}
+		writeJSON(channels)
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "channels" && parts[2] == "details":
+		c, ok := s.channel(parts[1])
+		if !ok || s.public[c.ID] {
+			writeError(http.StatusForbidden, "no access to channel")
+			return
+		}
+		writeJSON(c)
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "channels":
+		c, ok := s.channel(parts[1])
+		if !ok {
+			writeError(http.StatusNotFound, "channel not found")
+			return
+		}
+		writeJSON(c.SimpleChannelDetails)
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "points" && (parts[2] == "top" || parts[2] == "watchtime"):
+		if _, ok := s.channel(parts[1]); !ok {
+			writeError(http.StatusNotFound, "channel not found")
+			return
+		}
+		values := s.points[parts[1]]
+		if parts[2] == "watchtime" {
+			values = s.watchtime[parts[1]]
+		}
+		users := sortedUsers(values)
+		lb := streamelements.Leaderboard{Total: len(users), Users: []streamelements.LeaderboardEntry{}}
+		for _, user := range page(users, r) {
+			entry := streamelements.LeaderboardEntry{Username: user}
+			if parts[2] == "watchtime" {
+				entry.Minutes = values[user]
+			} else {
+				entry.Points = values[user]
+			}
+			lb.Users = append(lb.Users, entry)
+		}
+		writeJSON(lb)
+	case r.Method == http.MethodGet && len(parts) == 4 && parts[0] == "points" && parts[3] == "rank":
+		if _, ok := s.channel(parts[1]); !ok {
+			writeError(http.StatusNotFound, "channel not found")
+			return
+		}
+		user := strings.ToLower(parts[2])
+		points, ok := s.points[parts[1]][user]
+		if !ok {
+			writeError(http.StatusNotFound, "user not found")
+			return
+		}
+		rank := slices.Index(sortedUsers(s.points[parts[1]]), user) + 1
+		writeJSON(streamelements.UserRank{ChannelID: parts[1], Username: user, Points: points, Rank: rank})
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "points":
+		if _, ok := s.channel(parts[1]); !ok {
+			writeError(http.StatusNotFound, "channel not found")
+			return
+		}
+		points, ok := s.points[parts[1]][strings.ToLower(parts[2])]
+		if !ok {
+			writeError(http.StatusNotFound, "user not found")
+			return
+		}
+		writeJSON(streamelements.UserPoints{ChannelID: parts[1], Username: strings.ToLower(parts[2]), Points: points, PointsAlltime: points})
+	case r.Method == http.MethodPut && len(parts) == 4 && parts[0] == "points":
+		if _, ok := s.channel(parts[1]); !ok {
+			writeError(http.StatusNotFound, "channel not found")
+			return
+		}
+		if s.public[parts[1]] {
+			writeError(http.StatusForbidden, "no access to channel")
+			return
+		}
+		amount, err := strconv.Atoi(parts[3])
+		if err != nil {
+			writeError(http.StatusBadRequest, "invalid amount")
+			return
+		}
+		if s.points[parts[1]] == nil {
+			s.points[parts[1]] = map[string]int{}
+		}
+		user := strings.ToLower(parts[2])
+		s.points[parts[1]][user] = max(0, s.points[parts[1]][user]+amount)
+		writeJSON(map[string]any{"channel": parts[1], "username": user, "amount": amount, "newAmount": s.points[parts[1]][user]})
+	case r.Method == http.MethodPut && len(parts) == 2 && parts[0] == "points":
+		if _, ok := s.channel(parts[1]); !ok {
+			writeError(http.StatusNotFound, "channel not found")
+			return
+		}
+		if s.public[parts[1]] {
+			writeError(http.StatusForbidden, "no access to channel")
+			return
+		}
+		var body struct {
+			Mode  streamelements.BulkPointsMode   `json:"mode"`
+			Users []streamelements.BulkPointsUser `json:"users"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || (body.Mode != streamelements.BulkPointsAdd && body.Mode != streamelements.BulkPointsSet) {
+			writeError(http.StatusBadRequest, "invalid body")
+			return
+		}
+		if s.points[parts[1]] == nil {
+			s.points[parts[1]] = map[string]int{}
+		}
+		for _, u := range body.Users {
+			user := strings.ToLower(u.Username)
+			if body.Mode == streamelements.BulkPointsAdd {
+				s.points[parts[1]][user] = max(0, s.points[parts[1]][user]+u.Current)
+			} else {
+				s.points[parts[1]][user] = max(0, u.Current)
+			}
+		}
+		writeJSON(streamelements.BulkPointsResult{ChannelID: parts[1], Mode: string(body.Mode), Users: len(body.Users)})
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "store" && parts[2] == "items":
+		if _, ok := s.channel(parts[1]); !ok {
+			writeError(http.StatusNotFound, "channel not found")
+			return
+		}
+		writeJSON(append([]streamelements.StoreItem{}, s.items[parts[1]]...))
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "store" && parts[2] == "redemptions":
+		if _, ok := s.channel(parts[1]); !ok {
+			writeError(http.StatusNotFound, "channel not found")
+			return
+		}
+		var redemptions []streamelements.Redemption
+		for _, rd := range s.redemptions[parts[1]] {
+			if r.URL.Query().Get("pending") != "true" || !rd.Completed {
+				redemptions = append(redemptions, rd)
+			}
+		}
+		writeJSON(streamelements.Redemptions{Total: len(redemptions), Docs: append([]streamelements.Redemption{}, page(redemptions, r)...)})
+	case r.Method == http.MethodPut && len(parts) == 4 && parts[0] == "store" && parts[2] == "redemptions":
+		if s.public[parts[1]] {
+			writeError(http.StatusForbidden, "no access to channel")
+			return
+		}
+		i := slices.IndexFunc(s.redemptions[parts[1]], func(rd streamelements.Redemption) bool { return rd.ID == parts[3] })
+		if i < 0 {
+			writeError(http.StatusNotFound, "redemption not found")
+			return
+		}
+		var body struct {
+			Completed bool `json:"completed"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(http.StatusBadRequest, "invalid body")
+			return
+		}
+		s.redemptions[parts[1]][i].Completed = body.Completed
+		writeJSON(s.redemptions[parts[1]][i])
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "activities":
+		if _, ok := s.channel(parts[1]); !ok {
+			writeError(http.StatusNotFound, "channel not found")
+			return
+		}
+		query := r.URL.Query()
+		after, err1 := time.Parse(time.RFC3339, query.Get("after"))
+		before, err2 := time.Parse(time.RFC3339, query.Get("before"))
+		if err1 != nil || err2 != nil {
+			writeError(http.StatusBadRequest, "after and before are required")
+			return
+		}
+		activities := []streamelements.Activity{}
+		for _, a := range s.activities[parts[1]] {
+			if a.CreatedAt.Before(after) || a.CreatedAt.After(before) {
+				continue
+			}
+			if types := query["types"]; len(types) > 0 && !slices.Contains(types, a.Type) {
+				continue
+			}
+			activities = append(activities, a)
+		}
+		if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 && limit < len(activities) {
+			activities = activities[:limit]
+		}
+		writeJSON(activities)
+	default:
+		writeError(http.StatusNotFound, "not found")
+	}
+}
+
+// channel returns the channel with the given ID or username.
+func (s *Server) channel(idOrName string) (streamelements.ChannelDetails, bool) {
+	for _, c := range s.channels {
+		if c.ID == idOrName || strings.EqualFold(c.Username, idOrName) {
+			return c, true
+		}
+	}
+	return streamelements.ChannelDetails{}, false
+}
+
+// sortedUsers returns the users of values sorted by their value, highest first.
+func sortedUsers(values map[string]int) []string {
+	users := make([]string, 0, len(values))
+	for user := range values {
+		users = append(users, user)
+	}
+	slices.SortFunc(users, func(a, b string) int {
+		if values[a] != values[b] {
+			return values[b] - values[a]
+		}
+		return strings.Compare(a, b)
+	})
+	return users
+}
+
+// page returns the part of s selected by the limit and offset query parameters of r.
+func page[S ~[]E, E any](s S, r *http.Request) S {
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	s = s[min(max(offset, 0), len(s)):]
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit >= 0 && limit < len(s) {
+		s = s[:limit]
+	}
+	return s
+}
@@ -1,14 +1,19 @@
 package streamelements
 
 import (
+	"encoding/json"
 	"net/http"
 	"time"
 )
 
 // Streamelements is the base type for communication with the streamelements API.
 type Streamelements struct {
-	c     *http.Client
-	token string
+	c       *http.Client
+	token   string
+	baseURL string
+	retries int
+	backoff time.Duration
+	limiter rateLimiter
 }
 
 // CurrentUserChannel represents the return type for the '/channels/me' endpoint.
@@ -108,3 +113,142 @@ type UserPoints struct {
 	Watchtime     int    `json:"watchtime"`
 	Rank          int    `json:"rank"`
 }
+
+// Leaderboard represents the return type of the '/points/{channel}/top' and
+// '/points/{channel}/watchtime' endpoints.
+type Leaderboard struct {
+	Total int                `json:"_total"`
+	Users []LeaderboardEntry `json:"users"`
+}
+
+// LeaderboardEntry is a single user of a Leaderboard. Depending on the leaderboard either Points
+// or Minutes is set.
+type LeaderboardEntry struct {
+	Username string `json:"username"`
+	Points   int    `json:"points,omitempty"`
+	Minutes  int    `json:"minutes,omitempty"`
+}
+
+// UserRank represents the return type of the '/points/{channel}/{user}/rank' endpoint.
+type UserRank struct {
+	ChannelID string `json:"channel"`
+	Username  string `json:"username"`
+	Points    int    `json:"points"`
+	Rank      int    `json:"rank"`
+}
+
+// BulkPointsMode is the mode of a bulk points update, see Streamelements.UpdatePointsBulk.
+type BulkPointsMode string
+
+const (
+	// BulkPointsAdd adds the points to the current points of each user
+	BulkPointsAdd BulkPointsMode = "add"
+	// BulkPointsSet sets the points of each user
+	BulkPointsSet BulkPointsMode = "set"
+)
+
+// BulkPointsUser is a single user of a bulk points update.
+type BulkPointsUser struct {
+	Username string `json:"username"`
+	Current  int    `json:"current"`
+}
+
+// BulkPointsResult represents the return type of the bulk '/points/{channel}' endpoint.
+type BulkPointsResult struct {
+	ChannelID string `json:"channel"`
+	Mode      string `json:"mode"`
+	Users     int    `json:"users"`
+}
+
+// StoreItem represents an item of the loyalty store of a channel.
+type StoreItem struct {
+	ID          string            `json:"_id"`
+	ChannelID   string            `json:"channel"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Type        string            `json:"type"`
+	Cost        int               `json:"cost"`
+	Enabled     bool              `json:"enabled"`
+	Quantity    StoreItemQuantity `json:"quantity"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+}
+
+// StoreItemQuantity is the stock of a StoreItem. A Total of -1 means unlimited.
+type StoreItemQuantity struct {
+	Total   int `json:"total"`
+	Current int `json:"current"`
+}
+
+// Redemptions represents the return type of the '/store/{channel}/redemptions' endpoint.
+type Redemptions struct {
+	Total int          `json:"_total"`
+	Docs  []Redemption `json:"docs"`
+}
+
+// Redemption is a single redemption of a StoreItem by a user.
+type Redemption struct {
+	ID        string         `json:"_id"`
+	ChannelID string         `json:"channel"`
+	Redeemer  User13         `json:"redeemer"`
+	Item      RedemptionItem `json:"item"`
+	Input     []string       `json:"input"`
+	Completed bool           `json:"completed"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// RedemptionItem is the item of a Redemption. StreamElements sends either only the ID of the item
+// or the whole item, so Name might be empty.
+type RedemptionItem struct {
+	ID   string `json:"_id"`
+	Name string `json:"name"`
+	Cost int    `json:"cost"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts an item ID as well as an item object.
+func (ri *RedemptionItem) UnmarshalJSON(data []byte) error {
+	var id string
+	if err := json.Unmarshal(data, &id); err == nil {
+		*ri = RedemptionItem{ID: id}
+		return nil
+	}
+	type item RedemptionItem
+	return json.Unmarshal(data, (*item)(ri))
+}
+
+// Activity represents a single event of the activity feed of a channel, like a follow, a tip or a
+// subscription.
+type Activity struct {
+	ID        string       `json:"_id"`
+	ChannelID string       `json:"channel"`
+	Type      string       `json:"type"`
+	Provider  string       `json:"provider"`
+	Flagged   bool         `json:"flagged"`
+	Data      ActivityData `json:"data"`
+	CreatedAt time.Time    `json:"createdAt"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+}
+
+// ActivityData holds the details of an Activity. Which fields are set depends on the type.
+type ActivityData struct {
+	Username    string  `json:"username"`
+	DisplayName string  `json:"displayName"`
+	ProviderID  string  `json:"providerId"`
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
+	Message     string  `json:"message"`
+	Tier        string  `json:"tier"`
+	Gifted      bool    `json:"gifted"`
+}
+
+// ActivityQuery filters the activity feed, see Streamelements.GetActivities.
+type ActivityQuery struct {
+	// After and Before limit the time of the activities. Both are required by StreamElements.
+	After, Before time.Time
+	// Limit is the maximum number of activities
+	Limit int
+	// Types only returns activities of these types, like 'follow', 'tip' or 'subscriber'. Empty
+	// returns all types.
+	Types []string
+}
@@ -15,7 +15,9 @@
 package util
 
 import (
+	"bytes"
 	"fmt"
+	"sync"
 
 	"cake4everybot/data/lang"
 	"cake4everybot/database"
@@ -24,12 +26,22 @@ import (
 	"github.com/spf13/viper"
 )
 
-var commandIDMap map[string]string
+// commandIDMap maps a guild ID to a map from command names to their registered ID. Global
+// commands are stored under an empty guild ID.
+var commandIDMap = make(map[string]map[string]string)
+var commandIDMutex sync.RWMutex
 
-// SetCommandMap sets the map from command names to ther registered ID.
+// SetCommandMap sets the map from command names to ther registered ID for the given guild. Use an
+// empty guildID for global commands. A nil map removes the guild.
 // TODO: move the original command.CommandMap in a seperate Package to avoid this.
-func SetCommandMap(m map[string]string) {
-	commandIDMap = m
+func SetCommandMap(guildID string, m map[string]string) {
+	commandIDMutex.Lock()
+	defer commandIDMutex.Unlock()
+	if m == nil {
+		delete(commandIDMap, guildID)
+		return
+	}
+	commandIDMap[guildID] = m
 }
 
 // AuthoredEmbed returns a new Embed with an author and footer set.
@@ -97,11 +109,32 @@ func AddReplyHiddenField(e *discordgo.MessageEmbed) {
 	)
 }
 
-// MentionCommand returns the mention string for a slashcommand
-func MentionCommand(base string, subcommand ...string) string {
-	cBase := lang.GetDefault(base)
+// CommandID returns the registered ID of the command with the given name in the given guild. If
+// the command is not registered in that guild, the ID of the global command is returned.
+func CommandID(guildID, name string) string {
+	commandIDMutex.RLock()
+	defer commandIDMutex.RUnlock()
+	if id := commandIDMap[guildID][name]; id != "" {
+		return id
+	}
+	return commandIDMap[""][name]
+}
 
-	cID := commandIDMap[cBase]
+// FairDrawFile returns the transcript of the draw d as file to attach to a message, so anyone can
+// recompute its winners. See database.FairDraw.Transcript.
+func FairDrawFile(d database.FairDraw) *discordgo.File {
+	return &discordgo.File{
+		Name:        fmt.Sprintf("draw-%d.txt", d.ID),
+		ContentType: "text/plain",
+		Reader:      bytes.NewReader(d.Transcript()),
+	}
+}
+
+// MentionCommand returns the mention string for a slashcommand in the given guild. If the command
+// is not registered in that guild, the global command is used.
+func MentionCommand(guildID, base string, subcommand ...string) string {
+	cBase := lang.GetDefault(base)
+	cID := CommandID(guildID, cBase)
 	if cID == "" {
 		return ""
 	}
@@ -152,6 +185,31 @@ func GetChannelsFromDatabase(s *discordgo.Session, channelName string) (map[stri
 	return IDMap, nil
 }
 
+// GetChannelFromDatabase returns the channel ID of the given channel column for a single guild.
+// The returned ID is empty if the channel is not set or invalid.
+func GetChannelFromDatabase(s *discordgo.Session, guildID, channelName string) (string, error) {
+	settings, err := database.GetGuildSettings(guildID, channelName)
+	if err != nil {
+		return "", err
+	}
+	channelID := settings[channelName]
+	if channelID == "" {
+		return "", nil
+	}
+
+	// validate channel
+	channel, err := s.Channel(channelID)
+	if err != nil {
+		log.Printf("Warning: could not get %s channel for id '%s: %+v\n", channelName, channelID, err)
+		return "", nil
+	}
+	if channel.GuildID != guildID {
+		log.Printf("Warning: tried to get %s channel (from channel/%s/%s), but this channel is from guild: '%s'\n", channelName, guildID, channelID, channel.GuildID)
+		return "", nil
+	}
+	return channelID, nil
+}
+
 // GetConfigComponentEmoji returns a configured [discordgo.ComponentEmoji] for the given name.
 func GetConfigComponentEmoji(name string) *discordgo.ComponentEmoji {
 	e := GetConfigEmoji(name)
@@ -178,6 +178,14 @@ func (i *InteractionUtil) ReplyEmbed(embeds ...*discordgo.MessageEmbed) {
 	i.respond()
 }
 
+// ReplyFilesEmbed is like ReplyEmbed but also attaches the given files.
+func (i *InteractionUtil) ReplyFilesEmbed(files []*discordgo.File, embeds ...*discordgo.MessageEmbed) {
+	i.respondMessage(false, false)
+	i.response.Data.Embeds = embeds
+	i.response.Data.Files = files
+	i.respond()
+}
+
 // ReplyEmbedUpdate is like ReplyEmbed but make for an update for components.
 func (i *InteractionUtil) ReplyEmbedUpdate(embeds ...*discordgo.MessageEmbed) {
 	if !i.respondMessage(true, false) {
@@ -15,7 +15,9 @@
 package util
 
 import (
+	"context"
 	logger "log"
+	"time"
 )
 
 var log = logger.New(logger.Writer(), "[Util] ", logger.LstdFlags|logger.Lmsgprefix)
@@ -75,3 +77,16 @@ func ShiftL[T any](s []T, t ...T) (first T) {
 	}
 	return first
 }
+
+// Sleep pauses the current goroutine for at least the duration d. It returns false if ctx is done
+// before.
+func Sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package giveaway
+
+import (
+	"cake4everybot/database"
+	"fmt"
+	logger "log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+)
+
+var log = logger.New(logger.Writer(), "[WebGiveaway] ", logger.LstdFlags|logger.Lmsgprefix)
+
+// DrawURL returns the public URL of the transcript of the draw with the given ID, served by
+// HandleDraw. It is empty if no 'webserver.url' is configured.
+func DrawURL(id int64) string {
+	base := strings.TrimSuffix(viper.GetString("webserver.url"), "/")
+	if base == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/api/giveaway/draws/%d", base, id)
+}
+
+// HandleDraw is the HTTP/GET handler that publishes the transcript of a draw, see
+// database.FairDraw.Transcript. With it anyone can recompute the winners of the draw.
+func HandleDraw(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	d, ok, err := database.GetFairDraw(id)
+	if err != nil {
+		log.Printf("Error getting draw %d: %v", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(d.Transcript())
+}
@@ -15,8 +15,10 @@
 package webserver
 
 import (
+	"cake4everybot/webserver/giveaway"
 	"cake4everybot/webserver/twitch"
 	"cake4everybot/webserver/youtube"
+	"context"
 	logger "log"
 	"net/http"
 	"time"
@@ -27,6 +29,9 @@ import (
 
 var log = logger.New(logger.Writer(), "[WebServer] ", logger.LstdFlags|logger.Lmsgprefix)
 
+// shutdownTimeout is the maximum time to wait for running requests on shutdown
+const shutdownTimeout = 10 * time.Second
+
 func initHTTP() http.Handler {
 	r := mux.NewRouter()
 	r.Use(Logger)
@@ -35,36 +40,64 @@ func initHTTP() http.Handler {
 
 	r.HandleFunc("/favicon.ico", favicon)
 	r.HandleFunc("/api/twitch_pubsub", twitch.HandlePost).Methods(http.MethodPost)
+	r.HandleFunc("/api/twitch_pubsub/status", twitch.HandleStatus).Methods(http.MethodGet)
+	r.HandleFunc("/api/giveaway/draws/{id:[0-9]+}", giveaway.HandleDraw).Methods(http.MethodGet)
 	r.HandleFunc("/api/yt_pubsubhubbub/", youtube.HandleGet).Methods("GET")
 	r.HandleFunc("/api/yt_pubsubhubbub/", youtube.HandlePost).Methods("POST")
 
 	return r
 }
 
-// Run starts the webserver at the given address
-func Run(addr string, webChan chan struct{}) {
-	handler := initHTTP()
-
-	var err error
+// Run starts the webserver at the given address. When ctx is done, the server stops accepting new
+// connections and waits up to shutdownTimeout for running requests and Twitch event handlers. The
+// returned channel is closed after the server has stopped.
+func Run(ctx context.Context, addr string, webChan chan struct{}) <-chan struct{} {
+	server := &http.Server{
+		Addr:    addr,
+		Handler: initHTTP(),
+	}
+	done := make(chan struct{})
+	failed := make(chan struct{})
 
 	go func() {
-		err = http.ListenAndServe(addr, handler)
-		if err != nil {
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
 			log.Printf("Webserver ended with error: %v\n", err)
+			close(failed)
 		} else {
 			log.Println("Webserver ended!")
 		}
 	}()
 
 	go func() {
-		time.Sleep(3 * time.Second)
-		if err != nil {
+		select {
+		case <-time.After(3 * time.Second):
+			log.Printf("Started webserver under %s\n", addr)
+			close(webChan)
+		case <-failed:
+		case <-ctx.Done():
+		}
+	}()
+
+	go func() {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+		case <-failed:
 			return
 		}
 
-		log.Printf("Started webserver under %s\n", addr)
-		close(webChan)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error on shutting down webserver: %v\n", err)
+		}
+		if err := twitch.WaitDispatched(shutdownCtx); err != nil {
+			log.Printf("Error on waiting for Twitch event handlers: %v\n", err)
+		}
 	}()
+
+	return done
 }
 
 func favicon(w http.ResponseWriter, r *http.Request) {
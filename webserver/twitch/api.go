@@ -9,6 +9,7 @@ import (
 	logger "log"
 	"net/http"
 	"slices"
+	"sync"
 	"time"
 
 	"github.com/spf13/viper"
@@ -23,15 +24,17 @@ type RawEvent struct {
 	// Subscription contains the informations this event is about.
 	Subscription Subscription `json:"subscription"`
 
-	// Event is the actual event.
+	// Event is the actual event. Use Dispatch to parse it into its typed event according to the
+	// Subscription.Type.
 	//
 	// It is not set in a webhook callback verification.
-	Event interface{} `json:"event"`
+	Event json.RawMessage `json:"event"`
 }
 
 var (
-	log          = logger.New(logger.Writer(), "[WebTwitch] ", logger.LstdFlags|logger.Lmsgprefix)
-	lastMessages = make([]string, 10)
+	log             = logger.New(logger.Writer(), "[WebTwitch] ", logger.LstdFlags|logger.Lmsgprefix)
+	lastMessages    = make([]string, 10)
+	lastMessagesMux sync.Mutex
 )
 
 // HandlePost is the HTTP/POST handler for the Twitch PubSub endpoint.
@@ -66,7 +69,11 @@ func HandlePost(w http.ResponseWriter, r *http.Request) {
 		handleVerification(w, r, rEvent)
 		return
 	case "notification":
-		log.Printf("Event notification: %+v", rEvent)
+		// respond to twitch before handling the event, so slow handlers don't cause retries
+		goDispatch(rEvent.Subscription, rEvent.Event)
+	case "revocation":
+		// Twitch only expects an acknowledgement, the subscription is removed already
+		log.Printf("Twitch revoked '%s v%s' for broadcaster '%s': %s", rEvent.Subscription.Type, rEvent.Subscription.Version, conditionBroadcaster(rEvent.Subscription.Condition), rEvent.Subscription.Status)
 	default:
 		log.Printf("Unknown message type '%s'", messageType)
 		w.WriteHeader(http.StatusBadRequest)
@@ -100,15 +107,25 @@ func verifyTwitchMessage(header http.Header, body []byte) bool {
 		return false
 	}
 
-	if time.Until(t) > -10*time.Minute {
+	// reject messages older than 10 minutes to prevent replay attacks
+	if time.Since(t) > 10*time.Minute {
+		log.Printf("Declined message '%s' with old timestamp '%s'", msgID, msgTime)
 		return false
 	}
 
+	return !seenMessage(msgID)
+}
+
+// seenMessage reports whether a message with the given ID was received already. Twitch may send
+// the same message multiple times. Otherwise the ID is remembered.
+func seenMessage(msgID string) bool {
+	lastMessagesMux.Lock()
+	defer lastMessagesMux.Unlock()
 	if slices.Contains(lastMessages, msgID) {
-		return false
+		return true
 	}
 	lastMessages = append(lastMessages[1:], msgID)
-	return true
+	return false
 }
 
 func handleVerification(w http.ResponseWriter, r *http.Request, rEvent RawEvent) {
@@ -116,8 +133,8 @@ func handleVerification(w http.ResponseWriter, r *http.Request, rEvent RawEvent)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	broadcaster := rEvent.Subscription.Condition["broadcaster_user_id"]
-	if broadcaster != "404257324" {
+	broadcaster := conditionBroadcaster(rEvent.Subscription.Condition)
+	if !isAllowedBroadcaster(broadcaster) {
 		log.Printf("Declined verification for broadcaster '%s'!", broadcaster)
 		w.WriteHeader(http.StatusConflict)
 		w.Write([]byte("{\"conflict\":\"that broadcaster is not allowed\"}"))
@@ -0,0 +1,158 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/spf13/viper"
+)
+
+const testSecret = "s3cr3t"
+
+func newNotification(t *testing.T, msgID string, timestamp time.Time, body string) *http.Request {
+	t.Helper()
+	msgTime := timestamp.Format(time.RFC3339)
+	h := hmac.New(sha256.New, []byte(testSecret))
+	h.Write([]byte(msgID + msgTime + body))
+
+	r := httptest.NewRequest(http.MethodPost, "/api/twitch_pubsub", strings.NewReader(body))
+	r.Header.Set("Twitch-Eventsub-Message-Id", msgID)
+	r.Header.Set("Twitch-Eventsub-Message-Timestamp", msgTime)
+	r.Header.Set("Twitch-Eventsub-Message-Signature", "sha256="+hex.EncodeToString(h.Sum(nil)))
+	r.Header.Set("Twitch-Eventsub-Message-Type", "notification")
+	return r
+}
+
+// resetDiscordHandlers removes the handlers added during the test when it finished.
+func resetDiscordHandlers(t *testing.T) {
+	dcHandlersMux.Lock()
+	old := dcHandlers
+	dcHandlersMux.Unlock()
+	t.Cleanup(func() {
+		dcHandlersMux.Lock()
+		dcHandlers = old
+		dcHandlersMux.Unlock()
+	})
+}
+
+func TestHandlePostNotification(t *testing.T) {
+	viper.Set("twitch.webhookSecret", testSecret)
+	resetDiscordHandlers(t)
+
+	received := make(chan *StreamOnlineEvent, 1)
+	AddDiscordHandler(func(s *discordgo.Session, e *StreamOnlineEvent) { received <- e })
+	AddDiscordHandler(func(s *discordgo.Session, e *StreamOfflineEvent) {
+		t.Error("offline handler called for online event")
+	})
+
+	body := `{"subscription":{"id":"f1c2a387","type":"stream.online","version":"1","condition":{"broadcaster_user_id":"1337"}},` +
+		`"event":{"id":"9001","broadcaster_user_id":"1337","broadcaster_user_login":"cool_user","broadcaster_user_name":"Cool_User","type":"live","started_at":"2020-10-11T10:11:12.123Z"}}`
+
+	w := httptest.NewRecorder()
+	HandlePost(w, newNotification(t, "msg-online", time.Now(), body))
+	if w.Code != http.StatusOK {
+		t.Fatalf("HandlePost() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	select {
+	case e := <-received:
+		if e.ID != "9001" || e.BroadcasterUserLogin != "cool_user" || e.Type != "live" {
+			t.Errorf("got event %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler was not called")
+	}
+
+	// the same message again is a duplicate
+	w = httptest.NewRecorder()
+	HandlePost(w, newNotification(t, "msg-online", time.Now(), body))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("HandlePost() duplicate status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestWaitDispatched(t *testing.T) {
+	resetDiscordHandlers(t)
+	t.Cleanup(func() {
+		dispatchMux.Lock()
+		dispatchClosed = false
+		dispatchMux.Unlock()
+	})
+
+	release := make(chan struct{})
+	calls := make(chan struct{}, 2)
+	AddDiscordHandler(func(s *discordgo.Session, e *StreamOnlineEvent) {
+		calls <- struct{}{}
+		<-release
+	})
+	goDispatch(Subscription{Type: TypeStreamOnline}, []byte(`{}`))
+	<-calls
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := WaitDispatched(ctx); err == nil {
+		t.Error("WaitDispatched() returned while a handler was running")
+	}
+
+	// no more events are dispatched while shutting down
+	goDispatch(Subscription{Type: TypeStreamOnline}, []byte(`{}`))
+	close(release)
+	if err := WaitDispatched(context.Background()); err != nil {
+		t.Errorf("WaitDispatched() error = %v", err)
+	}
+	if len(calls) != 0 {
+		t.Error("event was dispatched after WaitDispatched()")
+	}
+}
+
+func TestHandlePostRevocation(t *testing.T) {
+	viper.Set("twitch.webhookSecret", testSecret)
+
+	body := `{"subscription":{"id":"f1c2a387","status":"authorization_revoked","type":"stream.online","version":"1","condition":{"broadcaster_user_id":"1337"}}}`
+	r := newNotification(t, "msg-revoked", time.Now(), body)
+	r.Header.Set("Twitch-Eventsub-Message-Type", "revocation")
+
+	w := httptest.NewRecorder()
+	HandlePost(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("HandlePost() status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestHandlePostOldTimestamp(t *testing.T) {
+	viper.Set("twitch.webhookSecret", testSecret)
+
+	w := httptest.NewRecorder()
+	HandlePost(w, newNotification(t, "msg-old", time.Now().Add(-11*time.Minute), `{}`))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("HandlePost() status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestDispatchUnknownType(t *testing.T) {
+	if err := Dispatch(Subscription{Type: "channel.unknown"}, []byte(`{}`)); err == nil {
+		t.Error("Dispatch() expected error for unknown type")
+	}
+}
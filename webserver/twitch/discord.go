@@ -0,0 +1,168 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	dcSession     *discordgo.Session
+	dcHandlers    []interface{}
+	dcHandlersMux sync.RWMutex
+
+	// dispatching tracks the handlers started by goDispatch, see WaitDispatched
+	dispatching    sync.WaitGroup
+	dispatchMux    sync.RWMutex
+	dispatchClosed bool
+)
+
+// SetDiscordSession sets the discord.Session to use for calling event handlers.
+func SetDiscordSession(s *discordgo.Session) {
+	dcSession = s
+}
+
+// AddDiscordHandler adds a handler for a typed EventSub event. The type of handler decides which
+// event it receives. It must be one of
+//
+//	func(*discordgo.Session, *StreamOnlineEvent)
+//	func(*discordgo.Session, *StreamOfflineEvent)
+//	func(*discordgo.Session, *ChannelFollowEvent)
+//	func(*discordgo.Session, *ChannelSubscribeEvent)
+//	func(*discordgo.Session, *ChannelCheerEvent)
+//	func(*discordgo.Session, *ChannelRaidEvent)
+//	func(*discordgo.Session, *ChannelPointsRedemptionEvent)
+//
+// Otherwise AddDiscordHandler panics.
+func AddDiscordHandler(handler interface{}) {
+	switch handler.(type) {
+	case func(*discordgo.Session, *StreamOnlineEvent),
+		func(*discordgo.Session, *StreamOfflineEvent),
+		func(*discordgo.Session, *ChannelFollowEvent),
+		func(*discordgo.Session, *ChannelSubscribeEvent),
+		func(*discordgo.Session, *ChannelCheerEvent),
+		func(*discordgo.Session, *ChannelRaidEvent),
+		func(*discordgo.Session, *ChannelPointsRedemptionEvent):
+	default:
+		panic(fmt.Sprintf("twitch: unsupported event handler type %T", handler))
+	}
+
+	dcHandlersMux.Lock()
+	defer dcHandlersMux.Unlock()
+	dcHandlers = append(dcHandlers, handler)
+}
+
+// Dispatch parses the raw event of a notification for the given subscription and calls all
+// handlers of its type. It returns an error if the event could not be parsed or its type is not
+// supported.
+func Dispatch(subscription Subscription, rawEvent json.RawMessage) error {
+	var event interface{}
+	switch subscription.Type {
+	case TypeStreamOnline:
+		event = &StreamOnlineEvent{}
+	case TypeStreamOffline:
+		event = &StreamOfflineEvent{}
+	case TypeChannelFollow:
+		event = &ChannelFollowEvent{}
+	case TypeChannelSubscribe:
+		event = &ChannelSubscribeEvent{}
+	case TypeChannelCheer:
+		event = &ChannelCheerEvent{}
+	case TypeChannelRaid:
+		event = &ChannelRaidEvent{}
+	case TypeChannelPointsRedemption:
+		event = &ChannelPointsRedemptionEvent{}
+	default:
+		return fmt.Errorf("unsupported event type '%s'", subscription.Type)
+	}
+	if err := json.Unmarshal(rawEvent, event); err != nil {
+		return fmt.Errorf("parse '%s' event: %v", subscription.Type, err)
+	}
+
+	dcHandlersMux.RLock()
+	handlers := dcHandlers
+	dcHandlersMux.RUnlock()
+
+	for _, h := range handlers {
+		_ = callHandler[StreamOnlineEvent](h, event) ||
+			callHandler[StreamOfflineEvent](h, event) ||
+			callHandler[ChannelFollowEvent](h, event) ||
+			callHandler[ChannelSubscribeEvent](h, event) ||
+			callHandler[ChannelCheerEvent](h, event) ||
+			callHandler[ChannelRaidEvent](h, event) ||
+			callHandler[ChannelPointsRedemptionEvent](h, event)
+	}
+	return nil
+}
+
+// goDispatch calls Dispatch in a new goroutine, so slow handlers don't block receiving further
+// events. Once WaitDispatched was called, events are dropped.
+func goDispatch(subscription Subscription, rawEvent json.RawMessage) {
+	dispatchMux.RLock()
+	defer dispatchMux.RUnlock()
+	if dispatchClosed {
+		log.Printf("Dropping '%s' event while shutting down", subscription.Type)
+		return
+	}
+
+	dispatching.Add(1)
+	go func() {
+		defer dispatching.Done()
+		if err := Dispatch(subscription, rawEvent); err != nil {
+			log.Printf("Error on dispatching '%s' event: %v", subscription.Type, err)
+		}
+	}()
+}
+
+// WaitDispatched stops dispatching new events and waits for the running event handlers to return,
+// or until ctx is done.
+func WaitDispatched(ctx context.Context) error {
+	dispatchMux.Lock()
+	dispatchClosed = true
+	dispatchMux.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		dispatching.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// callHandler calls handler with event, if both match the event type T. It reports whether the
+// handler was called.
+func callHandler[T any](handler, event interface{}) bool {
+	f, ok := handler.(func(*discordgo.Session, *T))
+	if !ok {
+		return false
+	}
+	e, ok := event.(*T)
+	if !ok {
+		return false
+	}
+	f(dcSession, e)
+	return true
+}
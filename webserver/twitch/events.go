@@ -0,0 +1,119 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"time"
+)
+
+// The EventSub subscription types that are parsed into typed events.
+const (
+	TypeStreamOnline            = "stream.online"
+	TypeStreamOffline           = "stream.offline"
+	TypeChannelFollow           = "channel.follow"
+	TypeChannelSubscribe        = "channel.subscribe"
+	TypeChannelCheer            = "channel.cheer"
+	TypeChannelRaid             = "channel.raid"
+	TypeChannelPointsRedemption = "channel.channel_points_custom_reward_redemption.add"
+)
+
+// Broadcaster holds the fields that identify the broadcaster of a channel an event is about.
+type Broadcaster struct {
+	BroadcasterUserID    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+}
+
+// EventUser holds the fields that identify the user who triggered an event. They are empty for
+// anonymous events.
+type EventUser struct {
+	UserID    string `json:"user_id"`
+	UserLogin string `json:"user_login"`
+	UserName  string `json:"user_name"`
+}
+
+// StreamOnlineEvent is sent when the broadcaster starts a stream.
+type StreamOnlineEvent struct {
+	Broadcaster
+	// ID is the ID of the stream.
+	ID string `json:"id"`
+	// Type is the stream type, one of "live", "playlist", "watch_party", "premiere" or "rerun".
+	Type      string    `json:"type"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// StreamOfflineEvent is sent when the broadcaster stops a stream.
+type StreamOfflineEvent struct {
+	Broadcaster
+}
+
+// ChannelFollowEvent is sent when a user follows the channel.
+type ChannelFollowEvent struct {
+	Broadcaster
+	EventUser
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// ChannelSubscribeEvent is sent when a user subscribes to the channel. Resubscriptions are not
+// included.
+type ChannelSubscribeEvent struct {
+	Broadcaster
+	EventUser
+	// Tier is the subscription tier, one of "1000", "2000" or "3000".
+	Tier   string `json:"tier"`
+	IsGift bool   `json:"is_gift"`
+}
+
+// ChannelCheerEvent is sent when a user cheers bits in the channel.
+type ChannelCheerEvent struct {
+	Broadcaster
+	// EventUser is empty if IsAnonymous is true.
+	EventUser
+	IsAnonymous bool   `json:"is_anonymous"`
+	Message     string `json:"message"`
+	Bits        int    `json:"bits"`
+}
+
+// ChannelRaidEvent is sent when a broadcaster raids another channel.
+type ChannelRaidEvent struct {
+	FromBroadcasterUserID    string `json:"from_broadcaster_user_id"`
+	FromBroadcasterUserLogin string `json:"from_broadcaster_user_login"`
+	FromBroadcasterUserName  string `json:"from_broadcaster_user_name"`
+	ToBroadcasterUserID      string `json:"to_broadcaster_user_id"`
+	ToBroadcasterUserLogin   string `json:"to_broadcaster_user_login"`
+	ToBroadcasterUserName    string `json:"to_broadcaster_user_name"`
+	Viewers                  int    `json:"viewers"`
+}
+
+// ChannelPointsRedemptionEvent is sent when a user redeems a custom channel points reward.
+type ChannelPointsRedemptionEvent struct {
+	Broadcaster
+	EventUser
+	// ID is the ID of the redemption.
+	ID        string `json:"id"`
+	UserInput string `json:"user_input"`
+	// Status is one of "unknown", "unfulfilled", "fulfilled" or "canceled".
+	Status     string              `json:"status"`
+	Reward     ChannelPointsReward `json:"reward"`
+	RedeemedAt time.Time           `json:"redeemed_at"`
+}
+
+// ChannelPointsReward is the reward of a ChannelPointsRedemptionEvent.
+type ChannelPointsReward struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Cost   int    `json:"cost"`
+	Prompt string `json:"prompt"`
+}
@@ -0,0 +1,223 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/tools/helix"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// subscriptionType describes how to subscribe to an EventSub type for a broadcaster.
+type subscriptionType struct {
+	version   string
+	condition func(broadcasterID string) map[string]string
+}
+
+func broadcasterCondition(broadcasterID string) map[string]string {
+	return map[string]string{"broadcaster_user_id": broadcasterID}
+}
+
+// subscriptionTypes are all EventSub types that can be configured in 'twitch.eventsub.types'.
+var subscriptionTypes = map[string]subscriptionType{
+	TypeStreamOnline:     {"1", broadcasterCondition},
+	TypeStreamOffline:    {"1", broadcasterCondition},
+	TypeChannelSubscribe: {"1", broadcasterCondition},
+	TypeChannelCheer:     {"1", broadcasterCondition},
+	TypeChannelFollow: {"2", func(id string) map[string]string {
+		return map[string]string{"broadcaster_user_id": id, "moderator_user_id": id}
+	}},
+	TypeChannelRaid: {"1", func(id string) map[string]string {
+		return map[string]string{"to_broadcaster_user_id": id}
+	}},
+	TypeChannelPointsRedemption: {"1", broadcasterCondition},
+}
+
+var (
+	subscriptionsMux    sync.RWMutex
+	allowedBroadcasters = make(map[string]bool)
+	subscriptions       []helix.EventSubSubscription
+)
+
+// isAllowedBroadcaster reports whether events of the given broadcaster ID are accepted.
+func isAllowedBroadcaster(broadcasterID string) bool {
+	subscriptionsMux.RLock()
+	defer subscriptionsMux.RUnlock()
+	return allowedBroadcasters[broadcasterID]
+}
+
+// conditionBroadcaster returns the broadcaster ID a subscription condition is about.
+func conditionBroadcaster(condition map[string]string) string {
+	if id := condition["broadcaster_user_id"]; id != "" {
+		return id
+	}
+	return condition["to_broadcaster_user_id"]
+}
+
+// WebhookTransport returns the transport for webhook subscriptions to the configured
+// 'twitch.eventsub.callback'.
+func WebhookTransport() helix.EventSubTransport {
+	return helix.EventSubTransport{
+		Method:   "webhook",
+		Callback: viper.GetString("twitch.eventsub.callback"),
+		Secret:   viper.GetString("twitch.webhookSecret"),
+	}
+}
+
+// sameTransport reports whether the subscription transport a delivers to the same target as b.
+func sameTransport(a, b helix.EventSubTransport) bool {
+	if a.Method != b.Method {
+		return false
+	}
+	if a.Method == "websocket" {
+		return a.SessionID == b.SessionID
+	}
+	return a.Callback == b.Callback
+}
+
+// SetupSubscriptions reads the allowed broadcasters from 'twitch.eventsub.broadcasters' and makes
+// sure there is exactly one enabled subscription for each of them and each type in
+// 'twitch.eventsub.types', delivered via the given transport. Subscriptions with the same method
+// that failed, were revoked or are no longer configured are deleted.
+//
+// Webhook subscriptions are verified by Twitch with a request to the webserver, so it must be
+// running already.
+func SetupSubscriptions(hx *helix.Helix, transport helix.EventSubTransport) error {
+	logins := viper.GetStringSlice("twitch.eventsub.broadcasters")
+	types := viper.GetStringSlice("twitch.eventsub.types")
+
+	var users []helix.User
+	if len(logins) > 0 {
+		var err error
+		users, err = hx.GetUsersByLogin(logins...)
+		if err != nil {
+			return fmt.Errorf("get broadcaster IDs: %v", err)
+		}
+	}
+	allowed := make(map[string]bool, len(users))
+	for _, u := range users {
+		allowed[u.ID] = true
+	}
+	if len(users) == 0 && len(logins) > 0 {
+		return fmt.Errorf("none of the configured broadcasters %v found, every event would be rejected", logins)
+	}
+	if len(users) != len(logins) {
+		log.Printf("Warning: only found %d of %d configured broadcasters %v", len(users), len(logins), logins)
+	}
+
+	subscriptionsMux.Lock()
+	allowedBroadcasters = allowed
+	subscriptionsMux.Unlock()
+
+	// everything we want to be subscribed to, mapped by type and broadcaster
+	wanted := make(map[string]helix.EventSubSubscription)
+	for _, t := range types {
+		st, ok := subscriptionTypes[t]
+		if !ok {
+			log.Printf("Warning: unsupported EventSub type '%s' in config", t)
+			continue
+		}
+		for id := range allowed {
+			wanted[t+"/"+id] = helix.EventSubSubscription{
+				Type:      t,
+				Version:   st.version,
+				Condition: st.condition(id),
+				Transport: transport,
+			}
+		}
+	}
+
+	existing, err := hx.GetEventSubSubscriptions()
+	if err != nil {
+		return fmt.Errorf("list subscriptions: %v", err)
+	}
+
+	var active []helix.EventSubSubscription
+	for _, sub := range existing {
+		key := sub.Type + "/" + conditionBroadcaster(sub.Condition)
+		want, ok := wanted[key]
+		if ok && sub.Status == "enabled" && sub.Version == want.Version && sameTransport(sub.Transport, transport) {
+			delete(wanted, key)
+			active = append(active, sub)
+			continue
+		}
+
+		if sub.Transport.Method != transport.Method {
+			// managed by another transport
+			active = append(active, sub)
+			continue
+		}
+		log.Printf("Deleting stale subscription '%s v%s' for broadcaster '%s' (%s)", sub.Type, sub.Version, conditionBroadcaster(sub.Condition), sub.Status)
+		if err = hx.DeleteEventSubSubscription(sub.ID); err != nil {
+			log.Printf("Error on deleting subscription '%s': %v", sub.ID, err)
+		}
+	}
+
+	var failed int
+	for _, sub := range wanted {
+		created, err := hx.CreateEventSubSubscription(sub)
+		if err != nil {
+			log.Printf("Error on creating subscription '%s' for broadcaster '%s': %v", sub.Type, conditionBroadcaster(sub.Condition), err)
+			failed++
+			continue
+		}
+		log.Printf("Created subscription '%s v%s' for broadcaster '%s'", created.Type, created.Version, conditionBroadcaster(created.Condition))
+		active = append(active, *created)
+	}
+
+	subscriptionsMux.Lock()
+	subscriptions = active
+	subscriptionsMux.Unlock()
+
+	if failed > 0 {
+		return fmt.Errorf("failed to create %d of %d subscriptions", failed, len(wanted))
+	}
+	return nil
+}
+
+// subscriptionStatus is a single entry of the status endpoint.
+type subscriptionStatus struct {
+	Type        string `json:"type"`
+	Version     string `json:"version"`
+	Broadcaster string `json:"broadcaster_id"`
+	Status      string `json:"status"`
+	Method      string `json:"method"`
+}
+
+// HandleStatus is the HTTP/GET handler that shows the status of the EventSub subscriptions, as
+// known from the last call of SetupSubscriptions.
+func HandleStatus(w http.ResponseWriter, r *http.Request) {
+	subscriptionsMux.RLock()
+	status := make([]subscriptionStatus, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		status = append(status, subscriptionStatus{
+			Type:        sub.Type,
+			Version:     sub.Version,
+			Broadcaster: conditionBroadcaster(sub.Condition),
+			Status:      sub.Status,
+			Method:      sub.Transport.Method,
+		})
+	}
+	subscriptionsMux.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Printf("Error on encoding subscription status: %v", err)
+	}
+}
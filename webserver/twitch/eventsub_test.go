@@ -0,0 +1,128 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/tools/helix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// helixStub is a minimal Helix API for the EventSub endpoints.
+type helixStub struct {
+	mu            sync.Mutex
+	subscriptions []helix.EventSubSubscription
+	deleted       []string
+}
+
+func (s *helixStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeData := func(status int, data any) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}
+
+	switch r.Method + " " + r.URL.Path {
+	case "POST /oauth2/token":
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "token", "expires_in": 3600})
+	case "GET /helix/users":
+		var users []helix.User
+		for i, login := range r.URL.Query()["login"] {
+			users = append(users, helix.User{ID: []string{"100", "200"}[i], Login: login})
+		}
+		writeData(http.StatusOK, users)
+	case "GET /helix/eventsub/subscriptions":
+		writeData(http.StatusOK, s.subscriptions)
+	case "POST /helix/eventsub/subscriptions":
+		var sub helix.EventSubSubscription
+		json.NewDecoder(r.Body).Decode(&sub)
+		sub.ID = "new-" + sub.Type + "-" + conditionBroadcaster(sub.Condition)
+		sub.Status = "webhook_callback_verification_pending"
+		sub.Transport.Secret = ""
+		s.subscriptions = append(s.subscriptions, sub)
+		writeData(http.StatusAccepted, []helix.EventSubSubscription{sub})
+	case "DELETE /helix/eventsub/subscriptions":
+		id := r.URL.Query().Get("id")
+		s.deleted = append(s.deleted, id)
+		s.subscriptions = slices.DeleteFunc(s.subscriptions, func(sub helix.EventSubSubscription) bool { return sub.ID == id })
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestSetupSubscriptions(t *testing.T) {
+	const callback = "https://example.com/api/twitch_pubsub"
+	viper.Set("twitch.eventsub.broadcasters", []string{"foo", "bar"})
+	viper.Set("twitch.eventsub.types", []string{TypeStreamOnline})
+	viper.Set("twitch.eventsub.callback", callback)
+
+	webhook := func(id, status, broadcaster, callback string) helix.EventSubSubscription {
+		return helix.EventSubSubscription{
+			ID: id, Status: status, Type: TypeStreamOnline, Version: "1",
+			Condition: map[string]string{"broadcaster_user_id": broadcaster},
+			Transport: helix.EventSubTransport{Method: "webhook", Callback: callback},
+		}
+	}
+	stub := &helixStub{subscriptions: []helix.EventSubSubscription{
+		webhook("keep", "enabled", "100", callback),
+		webhook("failed", "webhook_callback_verification_failed", "200", callback),
+		webhook("unknown", "enabled", "300", callback),
+		webhook("old-callback", "enabled", "100", "https://old.example.com"),
+	}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	hx := helix.New("id", "secret")
+	hx.SetBaseURL(server.URL+"/helix", server.URL+"/oauth2")
+
+	if err := SetupSubscriptions(hx, WebhookTransport()); err != nil {
+		t.Fatalf("SetupSubscriptions() error = %v", err)
+	}
+
+	slices.Sort(stub.deleted)
+	if want := []string{"failed", "old-callback", "unknown"}; !slices.Equal(stub.deleted, want) {
+		t.Errorf("deleted %v, want %v", stub.deleted, want)
+	}
+	var ids []string
+	for _, sub := range stub.subscriptions {
+		ids = append(ids, sub.ID)
+	}
+	slices.Sort(ids)
+	if want := []string{"keep", "new-stream.online-200"}; !slices.Equal(ids, want) {
+		t.Errorf("subscriptions %v, want %v", ids, want)
+	}
+
+	for id, want := range map[string]bool{"100": true, "200": true, "300": false} {
+		if got := isAllowedBroadcaster(id); got != want {
+			t.Errorf("isAllowedBroadcaster(%s) = %v, want %v", id, got, want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	HandleStatus(w, httptest.NewRequest(http.MethodGet, "/api/twitch_pubsub/status", nil))
+	var status []subscriptionStatus
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil || len(status) != 2 {
+		t.Errorf("HandleStatus() = %+v, %v, want 2 entries", status, err)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/tools/helix"
+	"cake4everybot/util"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/spf13/viper"
+)
+
+// DefaultWebSocketURL is the URL of the Twitch EventSub WebSocket server.
+const DefaultWebSocketURL = "wss://eventsub.wss.twitch.tv/ws"
+
+// errReconnect is returned by a session when Twitch asked to reconnect to a new URL.
+var errReconnect = errors.New("reconnect requested")
+
+// wsMessage is a message received over the EventSub WebSocket.
+type wsMessage struct {
+	Metadata struct {
+		MessageID        string    `json:"message_id"`
+		MessageType      string    `json:"message_type"`
+		MessageTimestamp time.Time `json:"message_timestamp"`
+	} `json:"metadata"`
+	Payload struct {
+		Session      *wsSession      `json:"session"`
+		Subscription Subscription    `json:"subscription"`
+		Event        json.RawMessage `json:"event"`
+	} `json:"payload"`
+}
+
+// wsSession is the session of a session_welcome or session_reconnect message.
+type wsSession struct {
+	ID                      string `json:"id"`
+	Status                  string `json:"status"`
+	KeepaliveTimeoutSeconds int    `json:"keepalive_timeout_seconds"`
+	ReconnectURL            string `json:"reconnect_url"`
+}
+
+// wsConn is a connection to the EventSub WebSocket server with its session.
+type wsConn struct {
+	conn    *websocket.Conn
+	session wsSession
+}
+
+// RunWebSocket connects to the EventSub WebSocket server and receives events until ctx is done. The
+// events are passed to Dispatch, just like the ones received by the webhook.
+//
+// After connecting, the configured subscriptions are created for the new session (see
+// SetupSubscriptions). hx must use a user access token, as Twitch requires it for websocket
+// subscriptions. When the connection is lost, a new session is started. When Twitch asks to
+// reconnect, the subscriptions are kept.
+func RunWebSocket(ctx context.Context, hx *helix.Helix) {
+	url := viper.GetString("twitch.eventsub.websocket_url")
+	if url == "" {
+		url = DefaultWebSocketURL
+	}
+
+	backoff := time.Second
+	for ctx.Err() == nil {
+		c, err := dialWebSocket(ctx, url)
+		if err != nil {
+			log.Printf("Error on connecting to EventSub WebSocket: %v", err)
+			if !util.Sleep(ctx, backoff) {
+				return
+			}
+			backoff = min(2*backoff, 2*time.Minute)
+			continue
+		}
+		backoff = time.Second
+		log.Printf("Connected to EventSub WebSocket with session '%s'", c.session.ID)
+
+		if err = SetupSubscriptions(hx, helix.EventSubTransport{Method: "websocket", SessionID: c.session.ID}); err != nil {
+			log.Printf("Error on setting up EventSub subscriptions: %v", err)
+		}
+
+		for {
+			err = c.receive(ctx)
+			if !errors.Is(err, errReconnect) {
+				break
+			}
+			// connect to the new URL before closing the old connection, so no events are lost
+			var newConn *wsConn
+			newConn, err = dialWebSocket(ctx, c.session.ReconnectURL)
+			if err != nil {
+				log.Printf("Error on reconnecting to EventSub WebSocket: %v", err)
+				c.conn.Close()
+				break
+			}
+			c.conn.Close()
+			c = newConn
+			log.Printf("Reconnected to EventSub WebSocket with session '%s'", c.session.ID)
+		}
+		c.conn.Close()
+
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("EventSub WebSocket closed, starting a new session: %v", err)
+	}
+}
+
+// dialWebSocket connects to url and waits for the welcome message.
+func dialWebSocket(ctx context.Context, url string) (*wsConn, error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// twitch closes the connection if we don't receive the welcome within 10 seconds anyway
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	var msg wsMessage
+	if err = conn.ReadJSON(&msg); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("read welcome message: %v", err)
+	}
+	if msg.Metadata.MessageType != "session_welcome" || msg.Payload.Session == nil {
+		conn.Close()
+		return nil, fmt.Errorf("expected welcome message, got '%s'", msg.Metadata.MessageType)
+	}
+	return &wsConn{conn: conn, session: *msg.Payload.Session}, nil
+}
+
+// receive reads and handles messages until the connection fails or ctx is done. It returns
+// errReconnect if Twitch asked to reconnect to c.session.ReconnectURL.
+func (c *wsConn) receive(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() { c.conn.Close() })
+	defer stop()
+
+	// no message (not even a keepalive) within the timeout means the connection is dead
+	timeout := time.Duration(c.session.KeepaliveTimeoutSeconds)*time.Second + 5*time.Second
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(timeout))
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return err
+		}
+		if seenMessage(msg.Metadata.MessageID) {
+			continue
+		}
+
+		switch msg.Metadata.MessageType {
+		case "session_keepalive":
+		case "notification":
+			if !isAllowedBroadcaster(conditionBroadcaster(msg.Payload.Subscription.Condition)) {
+				log.Printf("Ignoring '%s' event of unknown broadcaster", msg.Payload.Subscription.Type)
+				continue
+			}
+			goDispatch(msg.Payload.Subscription, msg.Payload.Event)
+		case "session_reconnect":
+			if msg.Payload.Session == nil || msg.Payload.Session.ReconnectURL == "" {
+				return fmt.Errorf("reconnect message without URL")
+			}
+			c.session.ReconnectURL = msg.Payload.Session.ReconnectURL
+			return errReconnect
+		case "revocation":
+			sub := msg.Payload.Subscription
+			log.Printf("Subscription '%s' for broadcaster '%s' was revoked: %s", sub.Type, conditionBroadcaster(sub.Condition), sub.Status)
+			removeSubscription(sub.ID)
+		default:
+			log.Printf("Unknown EventSub WebSocket message type '%s'", msg.Metadata.MessageType)
+		}
+	}
+}
+
+// removeSubscription removes the subscription with the given ID from the status list.
+func removeSubscription(id string) {
+	subscriptionsMux.Lock()
+	defer subscriptionsMux.Unlock()
+	subscriptions = slices.DeleteFunc(subscriptions, func(s helix.EventSubSubscription) bool { return s.ID == id })
+}
@@ -0,0 +1,132 @@
+// Copyright 2023 Kesuaheli
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package twitch
+
+import (
+	"cake4everybot/tools/helix"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/websocket"
+	"github.com/spf13/viper"
+)
+
+func wsTestMessage(id, messageType, payload string) string {
+	return fmt.Sprintf(`{"metadata":{"message_id":"%s","message_type":"%s","message_timestamp":"%s"},"payload":%s}`,
+		id, messageType, time.Now().Format(time.RFC3339), payload)
+}
+
+func wsTestCheer(id string, bits int) string {
+	return wsTestMessage(id, "notification", fmt.Sprintf(`{"subscription":{"id":"sub","type":"channel.cheer","version":"1","condition":{"broadcaster_user_id":"100"}},`+
+		`"event":{"broadcaster_user_id":"100","user_login":"cheerer","bits":%d}}`, bits))
+}
+
+func TestRunWebSocket(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	serve := func(messages ...string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				t.Errorf("upgrade: %v", err)
+				return
+			}
+			defer conn.Close()
+			for _, m := range messages {
+				conn.WriteMessage(websocket.TextMessage, []byte(m))
+			}
+			// wait until the client closes the connection
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	stub := &helixStub{}
+	mux := http.NewServeMux()
+	mux.Handle("/helix/", stub)
+	mux.Handle("/oauth2/", stub)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	mux.HandleFunc("/ws", serve(
+		wsTestMessage("w1", "session_welcome", `{"session":{"id":"s1","status":"connected","keepalive_timeout_seconds":10}}`),
+		wsTestCheer("n1", 100),
+		wsTestMessage("k1", "session_keepalive", `{}`),
+		wsTestMessage("r1", "session_reconnect", `{"session":{"id":"s1","status":"reconnecting","reconnect_url":"`+wsURL+`/reconnect"}}`),
+	))
+	mux.HandleFunc("/reconnect", serve(
+		wsTestMessage("w2", "session_welcome", `{"session":{"id":"s2","status":"connected","keepalive_timeout_seconds":10}}`),
+		// duplicate message is ignored
+		wsTestCheer("n1", 100),
+		wsTestCheer("n2", 200),
+	))
+
+	viper.Set("twitch.eventsub.websocket_url", wsURL+"/ws")
+	viper.Set("twitch.eventsub.broadcasters", []string{"foo"})
+	viper.Set("twitch.eventsub.types", []string{TypeChannelCheer})
+
+	resetDiscordHandlers(t)
+	cheers := make(chan int, 10)
+	AddDiscordHandler(func(s *discordgo.Session, e *ChannelCheerEvent) { cheers <- e.Bits })
+
+	hx := helix.New("id", "secret")
+	hx.SetBaseURL(server.URL+"/helix", server.URL+"/oauth2")
+	hx.SetUserToken("oauth:token")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		RunWebSocket(ctx, hx)
+		close(done)
+	}()
+
+	for _, want := range []int{100, 200} {
+		select {
+		case got := <-cheers:
+			if got != want {
+				t.Errorf("got cheer with %d bits, want %d", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("didn't receive cheer with %d bits", want)
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunWebSocket didn't return after cancel")
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if len(stub.subscriptions) != 1 || stub.subscriptions[0].Transport.SessionID != "s1" {
+		t.Errorf("subscriptions = %+v, want one for session s1", stub.subscriptions)
+	}
+	select {
+	case bits := <-cheers:
+		t.Errorf("got unexpected cheer with %d bits", bits)
+	default:
+	}
+}